@@ -0,0 +1,309 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package apply makes Grafana match desired state kept in files, ie. in git repository. Applier compares the desired
+// state with the live one fetched via the Grafana API and makes a plan of changes: dashboards and datasources to
+// create, update or delete. The plan is reviewed and then applied.
+//
+// Applied dashboards are marked with managed tag. Dashboards with the tag which are no longer in the desired state
+// are deleted if pruning is enabled. Datasources can't be tagged, so they are never deleted.
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spoof/go-grafana/client"
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/diff"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+// DefaultManagedTag is a tag of dashboards managed by Applier.
+const DefaultManagedTag = "managed-by:go-grafana"
+
+// searchLimit is a maximum number of live dashboards. Grafana returns 1000 dashboards by default.
+const searchLimit = 5000
+
+// Applier plans and applies changes of Grafana.
+type Applier struct {
+	client *client.Client
+
+	ManagedTag string // tag added to applied dashboards
+	Prune      bool   // delete dashboards with managed tag which are not in the desired state
+}
+
+// NewApplier creates new Applier which talks to Grafana with given client.
+func NewApplier(c *client.Client) *Applier {
+	return &Applier{
+		client:     c,
+		ManagedTag: DefaultManagedTag,
+	}
+}
+
+// Plan compares desired state with the live state of Grafana and returns changes which make them equal.
+// Datasources are changed before dashboards which may use them, deletions are the last.
+func (a *Applier) Plan(ctx context.Context, desired *State) (*Plan, error) {
+	plan := new(Plan)
+	if err := a.planDatasources(ctx, plan, desired.Datasources); err != nil {
+		return nil, err
+	}
+	if err := a.planDashboards(ctx, plan, desired.Dashboards); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (a *Applier) planDatasources(ctx context.Context, plan *Plan, desired []*Datasource) error {
+	live, err := a.client.Datasources.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching datasources: %s", err)
+	}
+	byName := make(map[string]*grafana.Datasource, len(live))
+	for _, d := range live {
+		byName[d.Name] = d
+	}
+
+	for _, d := range desired {
+		change := Change{Kind: DatasourceKind, Name: d.Name, datasource: d.Datasource}
+		hit, ok := byName[d.Name]
+		if !ok {
+			change.Action = Create
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+
+		// The list doesn't contain all fields of datasources, ie. basicAuthUser or withCredentials
+		liveDatasource, err := a.client.Datasources.GetByID(ctx, hit.ID())
+		if err != nil {
+			return fmt.Errorf("fetching datasource %q: %s", d.Name, err)
+		}
+		changes, err := diffDatasources(liveDatasource, d)
+		if err != nil {
+			return fmt.Errorf("datasource %q: %s", d.Name, err)
+		}
+		if len(changes) > 0 {
+			change.Action = Update
+			change.Diff = changes
+			change.id = hit.ID()
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+	return nil
+}
+
+func (a *Applier) planDashboards(ctx context.Context, plan *Plan, desired []*Dashboard) error {
+	hits, err := a.client.Dashboards.Search(ctx, &client.DashboardSearchOptions{Limit: searchLimit})
+	if err != nil {
+		return fmt.Errorf("searching dashboards: %s", err)
+	}
+	byUID := make(map[string]*client.DashboardHit, len(hits))
+	byTitle := make(map[string]*client.DashboardHit, len(hits))
+	for _, hit := range hits {
+		if !strings.HasPrefix(hit.URI, "db/") {
+			continue
+		}
+		if hit.UID != "" {
+			byUID[hit.UID] = hit
+		}
+		byTitle[hit.Title] = hit
+	}
+
+	matched := make(map[int64]bool, len(desired))
+	for _, d := range desired {
+		data, err := a.tagged(d)
+		if err != nil {
+			return fmt.Errorf("dashboard %q: %s", d.Title, err)
+		}
+		change := Change{Kind: DashboardKind, Name: d.Title, dashboard: data}
+
+		hit, ok := byUID[d.UID]
+		if !ok || d.UID == "" {
+			hit, ok = byTitle[d.Title]
+		}
+		if !ok {
+			change.Action = Create
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+		matched[hit.ID] = true
+		// Grafana doesn't save a dashboard with the title of another one, and overwriting it keeps the uid of
+		// the other dashboard, so the conflict should be resolved by hand
+		if d.UID != "" && hit.UID != "" && hit.UID != d.UID {
+			change.Action = Conflict
+			change.Diff = []diff.Change{{Op: diff.Changed, Path: "uid", Old: hit.UID, New: d.UID}}
+			plan.Changes = append(plan.Changes, change)
+			continue
+		}
+
+		change.slug = strings.TrimPrefix(hit.URI, "db/")
+		live, meta, err := a.client.Dashboards.GetJSON(ctx, change.slug)
+		if err != nil {
+			return fmt.Errorf("fetching dashboard %q: %s", d.Title, err)
+		}
+		if meta != nil {
+			change.folderID = meta.FolderID
+		}
+		// Dashboards differing only in noise are not saved, so their versions are not bumped
+		report, err := diffDashboards(live, data)
+		if err != nil {
+			return fmt.Errorf("dashboard %q: %s", d.Title, err)
+		}
+		if !report.Empty() {
+			change.Action = Update
+			change.Diff = report.Changes
+			plan.Changes = append(plan.Changes, change)
+		}
+	}
+
+	if !a.Prune || a.ManagedTag == "" {
+		return nil
+	}
+	for _, hit := range hits {
+		if !strings.HasPrefix(hit.URI, "db/") || matched[hit.ID] || !hasTag(hit.Tags, a.ManagedTag) {
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{
+			Action: Delete,
+			Kind:   DashboardKind,
+			Name:   hit.Title,
+			slug:   strings.TrimPrefix(hit.URI, "db/"),
+		})
+	}
+	return nil
+}
+
+// tagged returns JSON of the dashboard with managed tag. Desired dashboard is not changed.
+func (a *Applier) tagged(d *Dashboard) (json.RawMessage, error) {
+	if a.ManagedTag == "" || hasTag(d.Tags, a.ManagedTag) {
+		return d.JSON, nil
+	}
+
+	tags := append(append([]string{}, d.Tags...), a.ManagedTag)
+	return jsontools.SetFields(d.JSON, map[string]interface{}{"tags": tags})
+}
+
+// Apply applies changes of the plan in their order. It stops at the first failed change. Plans with conflicts are not
+// applied at all.
+func (a *Applier) Apply(ctx context.Context, plan *Plan) error {
+	for _, c := range plan.Changes {
+		if c.Action == Conflict {
+			return fmt.Errorf("%s %q conflicts with live one, plan can't be applied", c.Kind, c.Name)
+		}
+	}
+	for _, c := range plan.Changes {
+		if err := a.apply(ctx, c); err != nil {
+			return fmt.Errorf("%s %s %q: %s", c.Action, c.Kind, c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (a *Applier) apply(ctx context.Context, c Change) error {
+	switch c.Kind {
+	case DatasourceKind:
+		switch c.Action {
+		case Create:
+			return a.client.Datasources.Create(ctx, c.datasource)
+		case Update:
+			return a.client.Datasources.Update(ctx, c.id, c.datasource)
+		}
+	case DashboardKind:
+		switch c.Action {
+		case Create:
+			_, err := a.client.Dashboards.SaveJSON(ctx, c.dashboard, 0, false)
+			return err
+		case Update:
+			_, err := a.client.Dashboards.SaveJSON(ctx, c.dashboard, c.folderID, true)
+			return err
+		case Delete:
+			return a.client.Dashboards.Delete(ctx, c.slug)
+		}
+	}
+	return fmt.Errorf("unsupported change")
+}
+
+// diffDashboards compares canonical forms of dashboards' JSON, so differences in noise are not reported.
+func diffDashboards(old, new []byte) (*diff.Report, error) {
+	var canonical [2][]byte
+	for i, data := range [][]byte{old, new} {
+		var err error
+		if canonical[i], err = grafana.NormalizeJSON(data); err != nil {
+			return nil, err
+		}
+	}
+	return diff.JSON(canonical[0], canonical[1])
+}
+
+// diffDatasources compares live datasource with the desired one. Only fields set in the file of the desired
+// datasource are compared, so fields assigned by Grafana, ie. uid, are ignored unless they are set. Ids are never
+// compared. Passwords are write-only since Grafana keeps them in secure settings, so they are compared only if the
+// live datasource has them.
+func diffDatasources(live *grafana.Datasource, desired *Datasource) ([]diff.Change, error) {
+	var values [2]map[string]interface{}
+	for i, d := range []*grafana.Datasource{live, desired.Datasource} {
+		data, err := json.Marshal(d)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &values[i]); err != nil {
+			return nil, err
+		}
+	}
+	var set map[string]interface{}
+	if err := json.Unmarshal(desired.JSON, &set); err != nil {
+		return nil, err
+	}
+
+	old, new := values[0], values[1]
+	for k := range new {
+		if _, ok := set[k]; !ok || k == "id" || k == "orgId" {
+			delete(new, k)
+		}
+	}
+	for _, k := range sensitiveFields {
+		if v, ok := old[k]; !ok || v == "" {
+			delete(new, k)
+		}
+	}
+	onlyFields(old, new)
+	return diff.Values(old, new), nil
+}
+
+// onlyFields deletes fields of the object which are missing in other object, nested objects are handled the same
+// way.
+func onlyFields(object, other map[string]interface{}) {
+	for k, v := range object {
+		otherValue, ok := other[k]
+		if !ok {
+			delete(object, k)
+			continue
+		}
+		nested, ok := v.(map[string]interface{})
+		otherNested, otherOK := otherValue.(map[string]interface{})
+		if ok && otherOK {
+			onlyFields(nested, otherNested)
+		}
+	}
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,415 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spoof/go-grafana/client"
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// writeJSON writes JSON of given value into file of the directory.
+func writeJSON(t *testing.T, dir, name string, v interface{}) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeFile writes given data into file of the directory.
+func writeFile(t *testing.T, dir, name, data string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dashboardsDir := filepath.Join(dir, DashboardsDir)
+	writeFile(t, dashboardsDir, "api.json", `{"uid": "api", "title": "API", "schemaVersion": 16, "panels": [
+		{"type": "graph", "title": "Requests", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}},
+		{"type": "grafana-worldmap-panel", "title": "Regions", "locationData": "states"}
+	]}`)
+	writeFile(t, dashboardsDir, "new.json", `{"title": "New", "schemaVersion": 16}`)
+	writeFile(t, dashboardsDir, "same.json", `{"title": "Same", "schemaVersion": 14, "rows": [
+		{"height": "250px", "panels": [{"span": 4, "title": "Requests", "type": "graph"}]}
+	]}`)
+	writeJSON(t, filepath.Join(dir, DatasourcesDir), "loki.json", &grafana.Datasource{Name: "Loki", Type: "loki"})
+	writeJSON(t, filepath.Join(dir, DatasourcesDir), "prometheus.json", &grafana.Datasource{
+		Name: "Prometheus", Type: "prometheus", URL: "http://prometheus:9090", Password: "new-secret"})
+
+	var requests []string
+	mux := http.NewServeMux()
+	record := func(r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+	}
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `[{"id": 1, "name": "Prometheus", "type": "prometheus", "url": "http://prometheus"}]`)
+			return
+		}
+		record(r)
+		fmt.Fprint(w, `{"id": 2, "name": "Loki"}`)
+	})
+	mux.HandleFunc("/api/datasources/1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `{"id": 1, "name": "Prometheus", "type": "prometheus", "url": "http://prometheus", "password": "secret"}`)
+			return
+		}
+		record(r)
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[
+			{"id": 1, "uid": "api", "title": "API (renamed)", "uri": "db/api-renamed", "tags": [%[1]q]},
+			{"id": 2, "title": "Old", "uri": "db/old", "tags": [%[1]q]},
+			{"id": 3, "title": "Manual", "uri": "db/manual", "tags": []},
+			{"id": 7, "title": "Same", "uri": "db/same", "tags": [%[1]q]}
+		]`, DefaultManagedTag)
+	})
+	mux.HandleFunc("/api/dashboards/db/same", func(w http.ResponseWriter, r *http.Request) {
+		// The same dashboard saved from UI differs in noise only: order of keys, ids, heights and empty lists
+		fmt.Fprintf(w, `{"dashboard": {"id": 7, "version": 3, "tags": [%q], "title": "Same", "links": [],
+			"schemaVersion": 14, "rows": [{"panels": [{"span": 4, "title": "Requests", "type": "graph", "id": 9}],
+			"height": 250}]
+		}}`, DefaultManagedTag)
+	})
+	mux.HandleFunc("/api/dashboards/db/api-renamed", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"dashboard": {"id": 1, "uid": "api", "title": "API (renamed)", "tags": [%q], "schemaVersion": 16,
+			"panels": [
+				{"id": 1, "type": "graph", "title": "Requests", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}},
+				{"id": 2, "type": "grafana-worldmap-panel", "title": "Regions", "locationData": "countries"}
+			]
+		}, "meta": {"folderId": 3}}`, DefaultManagedTag)
+	})
+	mux.HandleFunc("/api/dashboards/db/old", func(w http.ResponseWriter, r *http.Request) {
+		record(r)
+	})
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Dashboard struct {
+				UID    string   `json:"uid"`
+				Title  string   `json:"title"`
+				Tags   []string `json:"tags"`
+				Panels []struct {
+					Type string `json:"type"`
+				} `json:"panels"`
+			} `json:"dashboard"`
+			Overwrite bool             `json:"overwrite"`
+			FolderID  grafana.FolderID `json:"folderId"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, fmt.Sprintf("POST %s %s %q %v %v %t %d", r.URL.Path, req.Dashboard.UID,
+			req.Dashboard.Title, req.Dashboard.Tags, req.Dashboard.Panels, req.Overwrite, req.FolderID))
+		fmt.Fprint(w, `{"status": "created"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	baseURL, _ := url.Parse(server.URL + "/")
+
+	state, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir returned error %s", err)
+	}
+
+	a := NewApplier(client.NewClient(baseURL, "", nil))
+	a.Prune = true
+	plan, err := a.Plan(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Applier.Plan returned error %s", err)
+	}
+
+	var out bytes.Buffer
+	if _, err := plan.WriteTo(&out); err != nil {
+		t.Fatalf("Plan.WriteTo returned error %s", err)
+	}
+	expected := `  + datasource "Loki"
+  ~ datasource "Prometheus"
+      ~ password: (sensitive)
+      ~ url: "http://prometheus" => "http://prometheus:9090"
+  ~ dashboard "API"
+      ~ panels[1].locationData: "countries" => "states"
+      ~ title: "API (renamed)" => "API"
+  + dashboard "New"
+  - dashboard "Old"
+
+Plan: 2 to create, 2 to update, 1 to delete.
+`
+	if out.String() != expected {
+		t.Errorf("Plan.WriteTo: expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	if err := a.Apply(context.Background(), plan); err != nil {
+		t.Fatalf("Applier.Apply returned error %s", err)
+	}
+	expectedRequests := []string{
+		"POST /api/datasources",
+		"PUT /api/datasources/1",
+		"POST /api/dashboards/db api \"API\" [" + DefaultManagedTag + "] [{graph} {grafana-worldmap-panel}] true 3",
+		"POST /api/dashboards/db  \"New\" [" + DefaultManagedTag + "] [] false 0",
+		"DELETE /api/dashboards/db/old",
+	}
+	if !reflect.DeepEqual(requests, expectedRequests) {
+		t.Errorf("Applier.Apply: expected requests %v, got %v", expectedRequests, requests)
+	}
+
+	// Desired state doesn't change on apply
+	if tags := state.Dashboards[0].Tags; len(tags) != 0 {
+		t.Errorf("Applier.Plan: desired dashboard is changed: %v", tags)
+	}
+}
+
+// fakeGrafana keeps datasources and dashboards saved via the API the way Grafana does it: the list of datasources
+// has no details, passwords are not returned and uids are assigned to datasources without them.
+type fakeGrafana struct {
+	datasources []map[string]interface{}
+	dashboards  []map[string]interface{}
+}
+
+func (g *fakeGrafana) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			var list []map[string]interface{}
+			for _, d := range g.datasources {
+				list = append(list, map[string]interface{}{"id": d["id"], "uid": d["uid"], "name": d["name"],
+					"type": d["type"], "url": d["url"]})
+			}
+			json.NewEncoder(w).Encode(list)
+			return
+		}
+		var d map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&d)
+		d["id"] = len(g.datasources) + 1
+		if d["uid"] == nil {
+			d["uid"] = fmt.Sprintf("generated-%d", d["id"])
+		}
+		delete(d, "password")
+		g.datasources = append(g.datasources, d)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": d["id"], "name": d["name"]})
+	})
+	mux.HandleFunc("/api/datasources/", func(w http.ResponseWriter, r *http.Request) {
+		for _, d := range g.datasources {
+			if fmt.Sprint(d["id"]) == strings.TrimPrefix(r.URL.Path, "/api/datasources/") {
+				json.NewEncoder(w).Encode(d)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		var hits []map[string]interface{}
+		for _, d := range g.dashboards {
+			hits = append(hits, map[string]interface{}{"id": d["id"], "uid": d["uid"], "title": d["title"],
+				"uri": "db/" + slug(d), "tags": d["tags"]})
+		}
+		json.NewEncoder(w).Encode(hits)
+	})
+	mux.HandleFunc("/api/dashboards/db/", func(w http.ResponseWriter, r *http.Request) {
+		for _, d := range g.dashboards {
+			if slug(d) == strings.TrimPrefix(r.URL.Path, "/api/dashboards/db/") {
+				json.NewEncoder(w).Encode(map[string]interface{}{"dashboard": d, "meta": map[string]interface{}{}})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Dashboard map[string]interface{} `json:"dashboard"`
+			Overwrite bool                   `json:"overwrite"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		d := req.Dashboard
+		for i, live := range g.dashboards {
+			if live["uid"] != d["uid"] && live["title"] != d["title"] {
+				continue
+			}
+			if live["uid"] != d["uid"] && !req.Overwrite {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				fmt.Fprint(w, `{"message": "A dashboard with the same name already exists", "status": "name-exists"}`)
+				return
+			}
+			d["id"], d["uid"], d["version"] = live["id"], live["uid"], live["version"].(float64)+1
+			g.dashboards[i] = d
+			fmt.Fprint(w, `{"status": "success"}`)
+			return
+		}
+		d["id"], d["version"] = len(g.dashboards)+1, float64(1)
+		g.dashboards = append(g.dashboards, d)
+		fmt.Fprint(w, `{"status": "success"}`)
+	})
+	return mux
+}
+
+func slug(d map[string]interface{}) string {
+	return strings.ToLower(strings.Replace(fmt.Sprint(d["title"]), " ", "-", -1))
+}
+
+func TestApplier_Idempotent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, DashboardsDir), "api.json", `{"uid": "api", "title": "API", "schemaVersion": 16,
+		"panels": [{"type": "graph", "title": "Requests", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}}]}`)
+	writeFile(t, filepath.Join(dir, DatasourcesDir), "prometheus.json", `{"name": "Prometheus", "type": "prometheus",
+		"url": "http://prometheus:9090", "basicAuth": true, "basicAuthUser": "admin", "basicAuthPassword": "secret",
+		"withCredentials": true, "jsonData": {"httpMethod": "POST"}}`)
+
+	g := new(fakeGrafana)
+	server := httptest.NewServer(g.handler())
+	defer server.Close()
+	baseURL, _ := url.Parse(server.URL + "/")
+	a := NewApplier(client.NewClient(baseURL, "", nil))
+
+	for i := 0; i < 2; i++ {
+		state, err := LoadDir(dir)
+		if err != nil {
+			t.Fatalf("LoadDir returned error %s", err)
+		}
+		plan, err := a.Plan(context.Background(), state)
+		if err != nil {
+			t.Fatalf("Applier.Plan returned error %s", err)
+		}
+		if i == 1 && !plan.Empty() {
+			var out bytes.Buffer
+			plan.WriteTo(&out)
+			t.Errorf("Applier.Plan: expected no changes for applied state, got\n%s", out.String())
+		}
+		if err := a.Apply(context.Background(), plan); err != nil {
+			t.Fatalf("Applier.Apply returned error %s", err)
+		}
+	}
+
+	// jsonData of live datasource has defaults which are not set in the file
+	g.datasources[0]["jsonData"].(map[string]interface{})["keepCookies"] = []interface{}{}
+	state, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir returned error %s", err)
+	}
+	plan, err := a.Plan(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Applier.Plan returned error %s", err)
+	}
+	if !plan.Empty() {
+		t.Errorf("Applier.Plan: expected no changes for datasource with defaults, got %v", plan.Changes)
+	}
+}
+
+func TestApplier_TitleConflict(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, DashboardsDir), "api.json", `{"uid": "api", "title": "API", "schemaVersion": 16}`)
+	writeFile(t, filepath.Join(dir, DashboardsDir), "old.json", `{"uid": "old", "title": "Old", "schemaVersion": 16}`)
+	g := &fakeGrafana{dashboards: []map[string]interface{}{
+		{"id": 1, "uid": "other", "title": "API", "version": float64(1)},
+		// Dashboards saved before uids were introduced are matched by titles
+		{"id": 2, "title": "Old", "version": float64(1)},
+	}}
+	server := httptest.NewServer(g.handler())
+	defer server.Close()
+	baseURL, _ := url.Parse(server.URL + "/")
+
+	state, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir returned error %s", err)
+	}
+	a := NewApplier(client.NewClient(baseURL, "", nil))
+	plan, err := a.Plan(context.Background(), state)
+	if err != nil {
+		t.Fatalf("Applier.Plan returned error %s", err)
+	}
+
+	var out bytes.Buffer
+	if _, err := plan.WriteTo(&out); err != nil {
+		t.Fatalf("Plan.WriteTo returned error %s", err)
+	}
+	expected := `  ! dashboard "API"
+      ~ uid: "other" => "api"
+  ~ dashboard "Old"
+      + schemaVersion: 16
+      + tags: ["` + DefaultManagedTag + `"]
+      + uid: "old"
+
+Plan: 0 to create, 1 to update, 0 to delete.
+Conflicts: 1, they should be resolved before apply.
+`
+	if out.String() != expected {
+		t.Errorf("Plan.WriteTo: expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	if err := a.Apply(context.Background(), plan); err == nil {
+		t.Errorf("Applier.Apply: expected error for plan with conflict")
+	}
+	if g.dashboards[1]["version"] != float64(1) {
+		t.Errorf("Applier.Apply: dashboard is saved despite conflict")
+	}
+}
+
+func TestLoadDir_Duplicates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFile(t, filepath.Join(dir, DashboardsDir), "a.json", `{"title": "API"}`)
+	writeFile(t, filepath.Join(dir, DashboardsDir), "b.json", `{"title": "API"}`)
+	if _, err := LoadDir(dir); err == nil {
+		t.Errorf("LoadDir: expected error for dashboards with the same title")
+	}
+
+	writeFile(t, filepath.Join(dir, DashboardsDir), "b.json", `{"uid": "api", "title": "API v2"}`)
+	writeFile(t, filepath.Join(dir, DashboardsDir), "c.json", `{"uid": "api", "title": "API v3"}`)
+	if _, err := LoadDir(dir); err == nil {
+		t.Errorf("LoadDir: expected error for dashboards with the same uid")
+	}
+
+	if _, err := LoadDir(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("LoadDir: expected error for missing directory")
+	}
+}
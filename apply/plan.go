@@ -0,0 +1,123 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/diff"
+)
+
+type action string
+
+// Actions of changes
+const (
+	Create action = "create"
+	Update action = "update"
+	Delete action = "delete"
+
+	// Conflict is a desired resource which can't be created, ie. a dashboard with the title of a live dashboard
+	// with another uid
+	Conflict action = "conflict"
+)
+
+type kind string
+
+// Kinds of changed resources
+const (
+	DashboardKind  kind = "dashboard"
+	DatasourceKind kind = "datasource"
+)
+
+// Change is a single change of Grafana's resource.
+type Change struct {
+	Action action
+	Kind   kind
+	Name   string        // title of dashboard or name of datasource
+	Diff   []diff.Change // differences of live and desired resource, only for updates and conflicts
+
+	dashboard  json.RawMessage
+	datasource *grafana.Datasource
+	id         grafana.DatasourceID // id of updated datasource
+	slug       string               // slug of updated or deleted dashboard
+	folderID   grafana.FolderID     // folder of updated dashboard
+}
+
+// Plan is a list of changes which make live state of Grafana match the desired one.
+type Plan struct {
+	Changes []Change
+}
+
+// Empty returns true if there are no changes.
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// sensitiveFields are fields of datasources whose values are not shown in diffs
+var sensitiveFields = []string{"password", "basicAuthPassword"}
+
+// WriteTo writes human-readable plan into w, the same way as Terraform does it: changes are marked with "+", "~" or
+// "-" for creates, updates and deletes, differences are listed under updates and counts of changes are at the end.
+// Conflicts are marked with "!".
+func (p *Plan) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	if p.Empty() {
+		b.WriteString("No changes.\n")
+		return writeString(w, b.String())
+	}
+
+	counts := make(map[action]int)
+	for _, c := range p.Changes {
+		counts[c.Action]++
+		fmt.Fprintf(&b, "  %s %s %q\n", actionSymbol(c.Action), c.Kind, c.Name)
+		for _, d := range c.Diff {
+			b.WriteString("      " + formatChange(d) + "\n")
+		}
+	}
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to delete.\n", counts[Create], counts[Update], counts[Delete])
+	if counts[Conflict] > 0 {
+		fmt.Fprintf(&b, "Conflicts: %d, they should be resolved before apply.\n", counts[Conflict])
+	}
+	return writeString(w, b.String())
+}
+
+func writeString(w io.Writer, s string) (int64, error) {
+	n, err := io.WriteString(w, s)
+	return int64(n), err
+}
+
+func actionSymbol(a action) string {
+	switch a {
+	case Create:
+		return "+"
+	case Delete:
+		return "-"
+	case Conflict:
+		return "!"
+	}
+	return "~"
+}
+
+func formatChange(c diff.Change) string {
+	for _, f := range sensitiveFields {
+		if c.Path == f {
+			return "~ " + c.Path + ": (sensitive)"
+		}
+	}
+	return c.String()
+}
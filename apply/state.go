@@ -0,0 +1,137 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// Subdirectories of state directory
+const (
+	DashboardsDir  = "dashboards"
+	DatasourcesDir = "datasources"
+)
+
+// State is a desired state of Grafana: dashboards and datasources which should exist.
+type State struct {
+	Dashboards  []*Dashboard
+	Datasources []*Datasource
+}
+
+// Dashboard is a desired dashboard. It's kept as JSON of its file, so fields which are not supported by
+// grafana.Dashboard, ie. panels of unregistered types, are applied as is.
+type Dashboard struct {
+	UID   string
+	Title string
+	Tags  []string
+	JSON  json.RawMessage
+}
+
+// Datasource is a desired datasource. JSON of its file is kept, so only fields set in the file are compared with
+// the live datasource.
+type Datasource struct {
+	*grafana.Datasource
+	JSON json.RawMessage
+}
+
+// LoadDir loads desired state from given directory. Dashboards are read from JSON files of "dashboards"
+// subdirectory, datasources are read from JSON files of "datasources" subdirectory. Files are read in the order of
+// their names, missing subdirectories are treated as empty. Dashboards are identified by their uids, or by their
+// titles if they have no uid, and datasources by their names, so they should be unique.
+func LoadDir(dir string) (*State, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+
+	s := new(State)
+	uids := make(map[string]string)
+	titles := make(map[string]string)
+	err := readJSONFiles(filepath.Join(dir, DashboardsDir), func(path string, data []byte) error {
+		d := &Dashboard{JSON: data}
+		if err := json.Unmarshal(data, &struct {
+			UID   *string   `json:"uid"`
+			Title *string   `json:"title"`
+			Tags  *[]string `json:"tags"`
+		}{&d.UID, &d.Title, &d.Tags}); err != nil {
+			return err
+		}
+		if d.Title == "" {
+			return fmt.Errorf("dashboard has no title")
+		}
+		if other, ok := titles[d.Title]; ok {
+			return fmt.Errorf("dashboard %q is already defined in %s", d.Title, other)
+		}
+		titles[d.Title] = path
+		if d.UID != "" {
+			if other, ok := uids[d.UID]; ok {
+				return fmt.Errorf("dashboard with uid %q is already defined in %s", d.UID, other)
+			}
+			uids[d.UID] = path
+		}
+		s.Dashboards = append(s.Dashboards, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+	err = readJSONFiles(filepath.Join(dir, DatasourcesDir), func(path string, data []byte) error {
+		d := &Datasource{Datasource: new(grafana.Datasource), JSON: data}
+		if err := json.Unmarshal(data, d.Datasource); err != nil {
+			return err
+		}
+		if d.Name == "" {
+			return fmt.Errorf("datasource has no name")
+		}
+		if other, ok := names[d.Name]; ok {
+			return fmt.Errorf("datasource %q is already defined in %s", d.Name, other)
+		}
+		names[d.Name] = path
+		s.Datasources = append(s.Datasources, d)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// readJSONFiles calls fn for every JSON file of given directory in the order of file names.
+func readJSONFiles(dir string, fn func(path string, data []byte) error) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := fn(path, data); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	return nil
+}
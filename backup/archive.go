@@ -0,0 +1,337 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// FormatVersion is a version of backup layout written into the manifest.
+const FormatVersion = 1
+
+const manifestFile = "manifest.json"
+
+// manifest describes content of backup
+type manifest struct {
+	Version int            `json:"version"`
+	Created time.Time      `json:"created"`
+	Source  string         `json:"source"`
+	Orgs    []*manifestOrg `json:"orgs"`
+}
+
+// manifestOrg lists files of organization's objects relative to root of backup
+type manifestOrg struct {
+	ID                 grafana.OrgID `json:"id"`
+	Name               string        `json:"name"`
+	Datasources        []string      `json:"datasources"`
+	Folders            []string      `json:"folders"`
+	Dashboards         []string      `json:"dashboards"`
+	AlertNotifications []string      `json:"alertNotifications"`
+	Annotations        string        `json:"annotations"`
+}
+
+// Save writes the backup into given path. Path ending with ".tar" is written as tar archive, path ending with
+// ".tar.gz" or ".tgz" as gzipped tar archive, otherwise the backup is written into directory.
+func (b *Backup) Save(path string) error {
+	if !isTar(path) {
+		return b.WriteDir(path)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var w io.WriteCloser = f
+	if isGzip(path) {
+		w = gzip.NewWriter(f)
+	}
+	if err := b.WriteTar(w); err != nil {
+		f.Close()
+		return err
+	}
+	if w != f {
+		if err := w.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// Load reads backup from given path written by Backup.Save.
+func Load(path string) (*Backup, error) {
+	if !isTar(path) {
+		return ReadDir(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if isGzip(path) {
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	return ReadTar(r)
+}
+
+func isTar(path string) bool {
+	return strings.HasSuffix(path, ".tar") || isGzip(path)
+}
+
+func isGzip(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// WriteDir writes the backup into given directory. The directory is created if it doesn't exist.
+func (b *Backup) WriteDir(dir string) error {
+	return b.write(func(name string, data []byte) error {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(p, data, 0644)
+	})
+}
+
+// WriteTar writes the backup as tar archive into given writer.
+func (b *Backup) WriteTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := b.write(func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: b.Created}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// write writes files of the backup with given function. Manifest is written the last, so backup without manifest is
+// incomplete.
+func (b *Backup) write(writeFile func(name string, data []byte) error) error {
+	writeJSON := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		return writeFile(name, append(data, '\n'))
+	}
+
+	m := &manifest{Version: FormatVersion, Created: b.Created, Source: b.Source}
+	for _, o := range b.Orgs {
+		dir := fmt.Sprintf("orgs/%d/", o.Org.ID)
+		mo := &manifestOrg{ID: o.Org.ID, Name: o.Org.Name}
+		m.Orgs = append(m.Orgs, mo)
+
+		names := make(map[string]bool)
+		for _, d := range o.Datasources {
+			name := uniqueName(names, dir+"datasources/", d.Name)
+			if err := writeJSON(name, d); err != nil {
+				return err
+			}
+			mo.Datasources = append(mo.Datasources, name)
+		}
+		for _, f := range o.Folders {
+			name := uniqueName(names, dir+"folders/", f.Folder.UID)
+			if err := writeJSON(name, f); err != nil {
+				return err
+			}
+			mo.Folders = append(mo.Folders, name)
+		}
+		for _, d := range o.Dashboards {
+			name := uniqueName(names, dir+"dashboards/", d.Title)
+			if err := writeJSON(name, d); err != nil {
+				return err
+			}
+			mo.Dashboards = append(mo.Dashboards, name)
+		}
+		for _, n := range o.AlertNotifications {
+			name := uniqueName(names, dir+"alert-notifications/", n.Name)
+			if err := writeJSON(name, n); err != nil {
+				return err
+			}
+			mo.AlertNotifications = append(mo.AlertNotifications, name)
+		}
+
+		annotations := o.Annotations
+		if annotations == nil {
+			annotations = []*grafana.Annotation{}
+		}
+		mo.Annotations = dir + "annotations.json"
+		if err := writeJSON(mo.Annotations, annotations); err != nil {
+			return err
+		}
+	}
+	return writeJSON(manifestFile, m)
+}
+
+// uniqueName returns name of JSON file of given directory for an object with given name. Names of objects are
+// turned into lowercase slugs, so a number is appended to names used already.
+func uniqueName(used map[string]bool, dir, objectName string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9':
+			return r
+		case 'A' <= r && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '-'
+	}, objectName)
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		slug = "unnamed"
+	}
+
+	name := dir + slug + ".json"
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s%s-%d.json", dir, slug, i)
+	}
+	used[name] = true
+	return name
+}
+
+// ReadDir reads backup from given directory written by Backup.WriteDir.
+func ReadDir(dir string) (*Backup, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	return read(func(name string) ([]byte, error) {
+		return ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	})
+}
+
+// ReadTar reads backup from tar archive written by Backup.WriteTar.
+func ReadTar(r io.Reader) (*Backup, error) {
+	files := make(map[string][]byte)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg && hdr.Typeflag != tar.TypeRegA {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(hdr.Name)] = data
+	}
+
+	return read(func(name string) ([]byte, error) {
+		data, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("%s: file not found in archive", name)
+		}
+		return data, nil
+	})
+}
+
+// read reads backup with files listed by its manifest using given function.
+func read(readFile func(name string) ([]byte, error)) (*Backup, error) {
+	readJSON := func(name string, v interface{}) error {
+		data, err := readFile(name)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+		return nil
+	}
+
+	var m manifest
+	if err := readJSON(manifestFile, &m); err != nil {
+		return nil, err
+	}
+	if m.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", m.Version)
+	}
+
+	b := &Backup{Created: m.Created, Source: m.Source}
+	for _, mo := range m.Orgs {
+		o := &Org{Org: &grafana.Org{ID: mo.ID, Name: mo.Name}}
+		for _, name := range mo.Datasources {
+			d := new(grafana.Datasource)
+			if err := readJSON(name, d); err != nil {
+				return nil, err
+			}
+			o.Datasources = append(o.Datasources, d)
+		}
+		for _, name := range mo.Folders {
+			f := new(Folder)
+			if err := readJSON(name, f); err != nil {
+				return nil, err
+			}
+			if f.Folder == nil {
+				return nil, fmt.Errorf("%s: folder is missing", name)
+			}
+			o.Folders = append(o.Folders, f)
+		}
+
+		for _, name := range mo.Dashboards {
+			d := new(Dashboard)
+			if err := readJSON(name, d); err != nil {
+				return nil, err
+			}
+			if len(d.Dashboard) == 0 {
+				return nil, fmt.Errorf("%s: dashboard is missing", name)
+			}
+			o.Dashboards = append(o.Dashboards, d)
+		}
+
+		for _, name := range mo.AlertNotifications {
+			n := new(grafana.AlertNotification)
+			if err := readJSON(name, n); err != nil {
+				return nil, err
+			}
+			o.AlertNotifications = append(o.AlertNotifications, n)
+		}
+		if mo.Annotations != "" {
+			if err := readJSON(mo.Annotations, &o.Annotations); err != nil {
+				return nil, err
+			}
+		}
+		b.Orgs = append(b.Orgs, o)
+	}
+	return b, nil
+}
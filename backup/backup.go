@@ -0,0 +1,205 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package backup backs up a Grafana instance via the Grafana API and restores it, ie. on a fresh instance.
+//
+// Backup contains every organization reachable by the client with its datasources, folders, dashboards,
+// permissions, alert notification channels and annotations. API tokens are bound to a single organization, so
+// a client of a server admin with basic authentication is needed to back up all organizations, see
+// client.NewBasicAuthClient.
+//
+// Backup is written into a directory or a tar archive with the layout:
+//
+//	manifest.json
+//	orgs/<org id>/datasources/<name>.json
+//	orgs/<org id>/folders/<uid>.json
+//	orgs/<org id>/dashboards/<title>.json
+//	orgs/<org id>/alert-notifications/<name>.json
+//	orgs/<org id>/annotations.json
+//
+// The manifest lists organizations of the backup with files of their objects.
+//
+// Restore recreates objects in dependency order: organizations, datasources, alert notification channels, folders,
+// dashboards, permissions and annotations. Objects get new ids on the restored instance, so references to them are
+// remapped: dashboards are saved into restored folders and annotations are attached to restored dashboards.
+// Dashboards are restored from their JSON as is, so they keep their uids and ids of their panels referenced by
+// annotations.
+//
+// Grafana API doesn't return secrets, ie. passwords of datasources kept in secure settings, so they should be set
+// after restore. Restore reports secure settings which are not restored. Permissions granted to users and teams are not restored since users and teams are not backed up.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spoof/go-grafana/client"
+	"github.com/spoof/go-grafana/grafana"
+)
+
+const (
+	// searchLimit is a maximum number of dashboards of an organization. Grafana returns 1000 dashboards by default.
+	searchLimit = 5000
+	// annotationsLimit is a maximum number of annotations of an organization. Grafana returns 100 by default.
+	annotationsLimit = 100000
+)
+
+// Backup is a backup of Grafana instance.
+type Backup struct {
+	Created time.Time
+	Source  string // URL of the backed up Grafana
+	Orgs    []*Org
+}
+
+// Org is a backup of an organization.
+type Org struct {
+	Org                *grafana.Org
+	Datasources        []*grafana.Datasource
+	Folders            []*Folder
+	Dashboards         []*Dashboard
+	AlertNotifications []*grafana.AlertNotification
+	Annotations        []*grafana.Annotation
+}
+
+// Folder is a backup of a folder with its permissions.
+type Folder struct {
+	Folder      *grafana.Folder       `json:"folder"`
+	Permissions []*grafana.Permission `json:"permissions,omitempty"`
+}
+
+// Dashboard is a backup of a dashboard with its folder and permissions. JSON of the dashboard is kept as it's returned
+// by Grafana, so fields which are not supported by grafana.Dashboard, ie. panels of unregistered types, are restored
+// too.
+type Dashboard struct {
+	ID          grafana.DashboardID   `json:"id"` // id in the backed up Grafana
+	UID         string                `json:"uid,omitempty"`
+	Title       string                `json:"title"`
+	FolderUID   string                `json:"folderUid,omitempty"` // empty for the General folder
+	Permissions []*grafana.Permission `json:"permissions,omitempty"`
+	Dashboard   json.RawMessage       `json:"dashboard"`
+}
+
+// Take backs up all organizations reachable by given client. Only the organization of the client is backed up if
+// the client can't list organizations, ie. it uses an API token.
+func Take(ctx context.Context, c *client.Client) (*Backup, error) {
+	orgs, err := c.Orgs.GetAll(ctx)
+	if isForbidden(err) {
+		var org *grafana.Org
+		if org, err = c.Orgs.Current(ctx); err == nil {
+			orgs = []*grafana.Org{org}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fetching organizations: %s", err)
+	}
+
+	b := &Backup{Created: time.Now().UTC(), Source: c.BaseURL.String()}
+	for _, org := range orgs {
+		o, err := takeOrg(ctx, c.WithOrg(org.ID), org)
+		if err != nil {
+			return nil, fmt.Errorf("organization %q: %s", org.Name, err)
+		}
+		b.Orgs = append(b.Orgs, o)
+	}
+	return b, nil
+}
+
+func takeOrg(ctx context.Context, c *client.Client, org *grafana.Org) (*Org, error) {
+	o := &Org{Org: org}
+
+	datasources, err := c.Datasources.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching datasources: %s", err)
+	}
+	// The list doesn't contain all fields of datasources, ie. basicAuthUser or withCredentials
+	for _, d := range datasources {
+		datasource, err := c.Datasources.GetByID(ctx, d.ID())
+		if err != nil {
+			return nil, fmt.Errorf("fetching datasource %q: %s", d.Name, err)
+		}
+		o.Datasources = append(o.Datasources, datasource)
+	}
+
+	folders, err := c.Folders.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching folders: %s", err)
+	}
+	folderUIDs := make(map[grafana.FolderID]string, len(folders))
+	for _, f := range folders {
+		permissions, err := c.Folders.GetPermissions(ctx, f.UID)
+		if err != nil {
+			return nil, fmt.Errorf("fetching permissions of folder %q: %s", f.Title, err)
+		}
+		folderUIDs[f.ID] = f.UID
+		o.Folders = append(o.Folders, &Folder{Folder: f, Permissions: permissions})
+	}
+
+	hits, err := c.Dashboards.Search(ctx, &client.DashboardSearchOptions{Limit: searchLimit})
+	if err != nil {
+		return nil, fmt.Errorf("searching dashboards: %s", err)
+	}
+	for _, hit := range hits {
+		if hit.Type == "dash-folder" || !strings.HasPrefix(hit.URI, "db/") {
+			continue
+		}
+		data, meta, err := c.Dashboards.GetJSON(ctx, strings.TrimPrefix(hit.URI, "db/"))
+		if err != nil {
+			return nil, fmt.Errorf("fetching dashboard %q: %s", hit.Title, err)
+		}
+		bd := &Dashboard{Dashboard: data}
+		if err := json.Unmarshal(data, &struct {
+			ID    *grafana.DashboardID `json:"id"`
+			UID   *string              `json:"uid"`
+			Title *string              `json:"title"`
+		}{&bd.ID, &bd.UID, &bd.Title}); err != nil {
+			return nil, fmt.Errorf("dashboard %q: %s", hit.Title, err)
+		}
+		if meta != nil {
+			bd.FolderUID = folderUIDs[meta.FolderID]
+		}
+		permissions, err := c.Dashboards.GetPermissions(ctx, bd.ID)
+		if err != nil {
+			return nil, fmt.Errorf("fetching permissions of dashboard %q: %s", hit.Title, err)
+		}
+		for _, p := range permissions {
+			if !p.Inherited {
+				bd.Permissions = append(bd.Permissions, p)
+			}
+		}
+		o.Dashboards = append(o.Dashboards, bd)
+	}
+
+	if o.AlertNotifications, err = c.AlertNotifications.GetAll(ctx); err != nil {
+		return nil, fmt.Errorf("fetching alert notifications: %s", err)
+	}
+
+	// Annotations of alerts are made by alerting, so only annotations made by users are backed up
+	opt := &client.AnnotationFindOptions{Type: "annotation", Limit: annotationsLimit}
+	if o.Annotations, err = c.Annotations.Find(ctx, opt); err != nil {
+		return nil, fmt.Errorf("fetching annotations: %s", err)
+	}
+	return o, nil
+}
+
+// isForbidden returns true if the error is a response of Grafana denying access.
+func isForbidden(err error) bool {
+	if errResp, ok := err.(*client.ErrorResponse); ok {
+		code := errResp.Response.StatusCode
+		return code == http.StatusUnauthorized || code == http.StatusForbidden
+	}
+	return false
+}
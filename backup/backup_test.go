@@ -0,0 +1,378 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spoof/go-grafana/client"
+	"github.com/spoof/go-grafana/grafana"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+// serveOrgs serves responses of organizations selected by X-Grafana-Org-Id header. Organizations without response
+// get an empty list.
+func serveOrgs(responses map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if resp, ok := responses[r.Header.Get("X-Grafana-Org-Id")]; ok {
+			fmt.Fprint(w, resp)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}
+}
+
+func newClient(t *testing.T, mux *http.ServeMux) (*client.Client, func()) {
+	server := httptest.NewServer(mux)
+	baseURL, _ := url.Parse(server.URL + "/")
+	return client.NewBasicAuthClient(baseURL, "admin", "admin", nil), server.Close
+}
+
+func takeBackup(t *testing.T) *Backup {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/orgs", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": 1, "name": "Main Org."}, {"id": 2, "name": "Ops"}]`)
+	})
+	mux.HandleFunc("/api/datasources", serveOrgs(map[string]string{
+		"1": `[{"id": 1, "name": "Prometheus", "type": "prometheus", "isDefault": true}]`,
+	}))
+	mux.HandleFunc("/api/datasources/1", serveOrgs(map[string]string{
+		"1": `{"id": 1, "name": "Prometheus", "type": "prometheus", "isDefault": true, "basicAuth": true,
+			"basicAuthUser": "admin", "jsonData": {"timeInterval": "15s"}, "secureJsonFields": {"basicAuthPassword": true}}`,
+	}))
+	mux.HandleFunc("/api/folders", serveOrgs(map[string]string{"1": `[{"id": 3, "uid": "ops", "title": "Ops"}]`}))
+	mux.HandleFunc("/api/folders/ops/permissions", serveOrgs(map[string]string{
+		"1": `[{"role": "Viewer", "permission": 1}, {"userId": 5, "userLogin": "alice", "permission": 4}]`,
+	}))
+	mux.HandleFunc("/api/search", serveOrgs(map[string]string{
+		"1": `[{"id": 3, "type": "dash-folder", "title": "Ops", "uri": "db/ops"},
+			{"id": 7, "type": "dash-db", "title": "API", "uri": "db/api", "folderId": 3}]`,
+	}))
+	mux.HandleFunc("/api/dashboards/db/api", serveOrgs(map[string]string{
+		"1": `{"dashboard": {"id": 7, "uid": "api", "title": "API", "schemaVersion": 16, "panels": [
+			{"id": 1, "type": "graph", "title": "Requests", "targets": [{"refId": "A", "expr": "sum(rate(requests_total[$__interval]))"}]},
+			{"id": 2, "type": "grafana-worldmap-panel", "title": "Regions", "locationData": "countries"}
+			], "annotations": {"list": [{"name": "Deploys", "datasource": "Prometheus", "expr": "changes(build_info[1m])"}]}
+			}, "meta": {"slug": "api", "folderId": 3, "folderTitle": "Ops"}}`,
+	}))
+	mux.HandleFunc("/api/dashboards/id/7/permissions", serveOrgs(map[string]string{
+		"1": `[{"role": "Editor", "permission": 2, "inherited": true}, {"teamId": 2, "team": "SRE", "permission": 2}]`,
+	}))
+	mux.HandleFunc("/api/alert-notifications", serveOrgs(map[string]string{
+		"1": `[{"id": 1, "uid": "slack", "name": "Slack", "type": "slack", "settings": {"url": "http://slack"}}]`,
+	}))
+	mux.HandleFunc("/api/annotations", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("type"); got != "annotation" {
+			t.Errorf("annotations: unexpected type %q", got)
+		}
+		serveOrgs(map[string]string{
+			"1": `[{"id": 10, "dashboardId": 7, "panelId": 1, "time": 1500000000000, "tags": ["deploy"], "text": "v1"},
+				{"id": 11, "time": 1500000000000, "tags": ["outage"], "text": "global"}]`,
+		})(w, r)
+	})
+
+	c, closeServer := newClient(t, mux)
+	defer closeServer()
+	b, err := Take(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Take returned error: %v", err)
+	}
+	return b
+}
+
+func TestTake(t *testing.T) {
+	b := takeBackup(t)
+	if len(b.Orgs) != 2 {
+		t.Fatalf("Take: expected 2 organizations, got %d", len(b.Orgs))
+	}
+
+	o := b.Orgs[0]
+	if len(o.Datasources) != 1 || o.Datasources[0].JSONData["timeInterval"] != "15s" ||
+		o.Datasources[0].BasicAuthUser != "admin" {
+		t.Errorf("Take: unexpected datasources %+v", o.Datasources)
+	}
+	if len(o.Folders) != 1 || len(o.Folders[0].Permissions) != 2 {
+		t.Errorf("Take: unexpected folders %+v", o.Folders)
+	}
+	if len(o.Dashboards) != 1 {
+		t.Fatalf("Take: expected 1 dashboard, got %d", len(o.Dashboards))
+	}
+	d := o.Dashboards[0]
+	if d.ID != 7 || d.UID != "api" || d.Title != "API" || d.FolderUID != "ops" || len(d.Permissions) != 1 ||
+		d.Permissions[0].Team != "SRE" {
+		t.Errorf("Take: unexpected dashboard %+v", d)
+	}
+	if !strings.Contains(string(d.Dashboard), `"grafana-worldmap-panel"`) {
+		t.Errorf("Take: panel of unregistered type is lost: %s", d.Dashboard)
+	}
+	if len(o.AlertNotifications) != 1 || len(o.Annotations) != 2 {
+		t.Errorf("Take: unexpected alert notifications %+v and annotations %+v", o.AlertNotifications, o.Annotations)
+	}
+
+	if o := b.Orgs[1]; len(o.Datasources)+len(o.Folders)+len(o.Dashboards)+len(o.Annotations) != 0 {
+		t.Errorf("Take: unexpected objects of organization %q", o.Org.Name)
+	}
+}
+
+func TestTake_SingleOrg(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/orgs", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "Permission denied"}`, http.StatusForbidden)
+	})
+	mux.HandleFunc("/api/org", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 2, "name": "Ops"}`)
+	})
+	mux.HandleFunc("/api/", serveOrgs(nil))
+
+	c, closeServer := newClient(t, mux)
+	defer closeServer()
+	b, err := Take(context.Background(), c)
+	if err != nil {
+		t.Fatalf("Take returned error: %v", err)
+	}
+	if len(b.Orgs) != 1 || b.Orgs[0].Org.Name != "Ops" {
+		t.Errorf("Take: expected the organization of the client, got %+v", b.Orgs)
+	}
+}
+
+func TestBackup_SaveLoad(t *testing.T) {
+	b := takeBackup(t)
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Empty lists of objects are loaded as nil, so only the first organization is compared with the taken one
+	want, err := json.Marshal(b.Orgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"dir", "backup.tar", "backup.tar.gz"} {
+		path := filepath.Join(dir, name)
+		if err := b.Save(path); err != nil {
+			t.Fatalf("%s: Save returned error: %v", name, err)
+		}
+		loaded, err := Load(path)
+		if err != nil {
+			t.Fatalf("%s: Load returned error: %v", name, err)
+		}
+		if !loaded.Created.Equal(b.Created) || loaded.Source != b.Source || len(loaded.Orgs) != 2 {
+			t.Fatalf("%s: loaded backup %+v differs from %+v", name, loaded, b)
+		}
+		got, err := json.Marshal(loaded.Orgs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s: loaded organization differs\n got %s\nwant %s", name, got, want)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "dir", "manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	wantOrg := &manifestOrg{
+		ID:                 1,
+		Name:               "Main Org.",
+		Datasources:        []string{"orgs/1/datasources/prometheus.json"},
+		Folders:            []string{"orgs/1/folders/ops.json"},
+		Dashboards:         []string{"orgs/1/dashboards/api.json"},
+		AlertNotifications: []string{"orgs/1/alert-notifications/slack.json"},
+		Annotations:        "orgs/1/annotations.json",
+	}
+	if len(m.Orgs) != 2 || !reflect.DeepEqual(m.Orgs[0], wantOrg) {
+		t.Errorf("manifest: unexpected organizations %+v", m.Orgs)
+	}
+
+	if _, err := ReadDir(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("ReadDir: expected error for missing directory")
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	used := make(map[string]bool)
+	names := []string{
+		uniqueName(used, "d/", "API Overview"),
+		uniqueName(used, "d/", "api overview"),
+		uniqueName(used, "d/", "¿?"),
+	}
+	want := []string{"d/api-overview.json", "d/api-overview-2.json", "d/unnamed.json"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("uniqueName returned %v, want %v", names, want)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	record := func(r *http.Request, body interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, fmt.Sprintf("%s %s %s", r.Method, r.URL.Path, r.Header.Get("X-Grafana-Org-Id")))
+		if body != nil {
+			if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+				t.Errorf("%s %s: invalid body: %v", r.Method, r.URL.Path, err)
+			}
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/orgs/name/Main Org.", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "name": "Main Org."}`)
+	})
+	mux.HandleFunc("/api/orgs", func(w http.ResponseWriter, r *http.Request) {
+		record(r, nil)
+		fmt.Fprint(w, `{"orgId": 4}`)
+	})
+	var datasource grafana.Datasource
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			record(r, &datasource)
+			fmt.Fprint(w, `{"id": 21}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/api/alert-notifications", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			record(r, nil)
+			fmt.Fprint(w, `{"id": 31}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	})
+	mux.HandleFunc("/api/folders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			record(r, nil)
+			fmt.Fprint(w, `{"id": 41, "uid": "ops", "title": "Ops"}`)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	})
+	var saved struct {
+		Dashboard json.RawMessage  `json:"dashboard"`
+		FolderID  grafana.FolderID `json:"folderId"`
+	}
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		record(r, &saved)
+		fmt.Fprint(w, `{"id": 51, "uid": "api", "slug": "api", "status": "success", "version": 1}`)
+	})
+	var permissions struct {
+		Items []*grafana.Permission `json:"items"`
+	}
+	mux.HandleFunc("/api/folders/ops/permissions", func(w http.ResponseWriter, r *http.Request) {
+		record(r, &permissions)
+	})
+	var annotations []grafana.Annotation
+	mux.HandleFunc("/api/annotations", func(w http.ResponseWriter, r *http.Request) {
+		var a grafana.Annotation
+		record(r, &a)
+		annotations = append(annotations, a)
+		fmt.Fprintf(w, `{"id": %d}`, 60+len(annotations))
+	})
+
+	// Backup is restored after save and load, like backups of grafanactl
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := takeBackup(t).Save(dir); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+	b, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	c, closeServer := newClient(t, mux)
+	defer closeServer()
+	report, err := Restore(context.Background(), c, b)
+	if err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+
+	wantRequests := []string{
+		"POST /api/orgs ",
+		"POST /api/datasources 1",
+		"POST /api/alert-notifications 1",
+		"POST /api/folders 1",
+		"POST /api/dashboards/db 1",
+		"POST /api/folders/ops/permissions 1",
+		"POST /api/annotations 1",
+		"POST /api/annotations 1",
+	}
+	if !reflect.DeepEqual(requests, wantRequests) {
+		t.Errorf("Restore: unexpected requests\n%s\nwant\n%s", strings.Join(requests, "\n"), strings.Join(wantRequests, "\n"))
+	}
+
+	if datasource.Name != "Prometheus" || datasource.JSONData["timeInterval"] != "15s" {
+		t.Errorf("Restore: unexpected datasource %+v", datasource)
+	}
+	if saved.FolderID != 41 {
+		t.Errorf("Restore: dashboard is saved into folder %d, want 41", saved.FolderID)
+	}
+	// Dashboard is restored as it's backed up, except for id and version of the backed up Grafana
+	wantDashboard := `{"uid": "api", "title": "API", "schemaVersion": 16, "panels": [
+		{"id": 1, "type": "graph", "title": "Requests", "targets": [{"refId": "A", "expr": "sum(rate(requests_total[$__interval]))"}]},
+		{"id": 2, "type": "grafana-worldmap-panel", "title": "Regions", "locationData": "countries"}
+		], "annotations": {"list": [{"name": "Deploys", "datasource": "Prometheus", "expr": "changes(build_info[1m])"}]}}`
+	if eq, err := jsontools.BytesEqual(saved.Dashboard, []byte(wantDashboard)); err != nil || !eq {
+		t.Errorf("Restore: unexpected dashboard %s", saved.Dashboard)
+	}
+	if want := []*grafana.Permission{{Role: "Viewer", Permission: grafana.ViewPermission}}; !reflect.DeepEqual(permissions.Items, want) {
+		t.Errorf("Restore: unexpected folder permissions %+v", permissions.Items)
+	}
+	if len(annotations) != 2 || annotations[0].DashboardID != 51 || annotations[1].DashboardID != 0 {
+		t.Errorf("Restore: unexpected annotations %+v", annotations)
+	}
+
+	wantReport := &Report{
+		Orgs:               2,
+		Datasources:        1,
+		AlertNotifications: 1,
+		Folders:            1,
+		Dashboards:         1,
+		Permissions:        1,
+		Annotations:        2,
+		Skipped: []string{
+			`secure field "basicAuthPassword" of datasource "Prometheus"`,
+			`permission of user "alice" for folder "Ops"`,
+			`permission of team "SRE" for dashboard "API"`,
+		},
+	}
+	if !reflect.DeepEqual(report, wantReport) {
+		t.Errorf("Restore returned report %+v, want %+v", report, wantReport)
+	}
+}
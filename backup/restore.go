@@ -0,0 +1,221 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/spoof/go-grafana/client"
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// Report summarizes restored objects.
+type Report struct {
+	Orgs               int
+	Datasources        int
+	AlertNotifications int
+	Folders            int
+	Dashboards         int
+	Permissions        int // folders and dashboards with restored permissions
+	Annotations        int
+
+	// Skipped describes objects which can't be restored, ie. permissions granted to users.
+	Skipped []string
+}
+
+// Restore restores the backup with given client. Organizations are matched by their names, datasources and alert
+// notification channels by their names, folders by their uids and dashboards by their uids or titles. Existing
+// objects are updated and missing ones are created, dashboards are overwritten. Restoring of several organizations
+// requires a client of a server admin, while backup of a single organization is restored into the organization of
+// the client if it can't manage organizations.
+func Restore(ctx context.Context, c *client.Client, b *Backup) (*Report, error) {
+	r := new(Report)
+	orgIDs := make([]grafana.OrgID, len(b.Orgs))
+	for i, o := range b.Orgs {
+		org, err := restoreOrg(ctx, c, o.Org, len(b.Orgs) == 1)
+		if err != nil {
+			return r, fmt.Errorf("organization %q: %s", o.Org.Name, err)
+		}
+		orgIDs[i] = org.ID
+		r.Orgs++
+	}
+
+	for i, o := range b.Orgs {
+		if err := restoreOrgObjects(ctx, c.WithOrg(orgIDs[i]), o, r); err != nil {
+			return r, fmt.Errorf("organization %q: %s", o.Org.Name, err)
+		}
+	}
+	return r, nil
+}
+
+// restoreOrg returns organization with the name of given one creating it if it's missing.
+func restoreOrg(ctx context.Context, c *client.Client, o *grafana.Org, single bool) (*grafana.Org, error) {
+	org, err := c.Orgs.GetByName(ctx, o.Name)
+	if err == client.ErrOrgNotFound {
+		org = &grafana.Org{Name: o.Name}
+		err = c.Orgs.Create(ctx, org)
+	}
+	if isForbidden(err) && single {
+		return c.Orgs.Current(ctx)
+	}
+	return org, err
+}
+
+func restoreOrgObjects(ctx context.Context, c *client.Client, o *Org, r *Report) error {
+	datasources, err := c.Datasources.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching datasources: %s", err)
+	}
+	datasourceIDs := make(map[string]grafana.DatasourceID, len(datasources))
+	for _, d := range datasources {
+		datasourceIDs[d.Name] = d.ID()
+	}
+	for _, d := range o.Datasources {
+		restored := *d
+		restored.SecureJSONFields = nil
+		if id, ok := datasourceIDs[d.Name]; ok {
+			err = c.Datasources.Update(ctx, id, &restored)
+		} else {
+			err = c.Datasources.Create(ctx, &restored)
+		}
+		if err != nil {
+			return fmt.Errorf("datasource %q: %s", d.Name, err)
+		}
+		r.Datasources++
+		fields := make([]string, 0, len(d.SecureJSONFields))
+		for f, set := range d.SecureJSONFields {
+			if set {
+				fields = append(fields, f)
+			}
+		}
+		sort.Strings(fields)
+		for _, f := range fields {
+			r.Skipped = append(r.Skipped, fmt.Sprintf("secure field %q of datasource %q", f, d.Name))
+		}
+	}
+
+	notifications, err := c.AlertNotifications.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching alert notifications: %s", err)
+	}
+	notificationIDs := make(map[string]grafana.AlertNotificationID, len(notifications))
+	for _, n := range notifications {
+		notificationIDs[n.Name] = n.ID
+	}
+	for _, n := range o.AlertNotifications {
+		restored := *n
+		if id, ok := notificationIDs[n.Name]; ok {
+			err = c.AlertNotifications.Update(ctx, id, &restored)
+		} else {
+			restored.ID = 0
+			err = c.AlertNotifications.Create(ctx, &restored)
+		}
+		if err != nil {
+			return fmt.Errorf("alert notification %q: %s", n.Name, err)
+		}
+		r.AlertNotifications++
+	}
+
+	folders, err := c.Folders.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching folders: %s", err)
+	}
+	folderIDs := make(map[string]grafana.FolderID, len(folders))
+	for _, f := range folders {
+		folderIDs[f.UID] = f.ID
+	}
+	for _, f := range o.Folders {
+		if _, ok := folderIDs[f.Folder.UID]; !ok {
+			restored := &grafana.Folder{UID: f.Folder.UID, Title: f.Folder.Title}
+			if err := c.Folders.Create(ctx, restored); err != nil {
+				return fmt.Errorf("folder %q: %s", f.Folder.Title, err)
+			}
+			folderIDs[restored.UID] = restored.ID
+		}
+		r.Folders++
+	}
+
+	dashboardIDs := make(map[grafana.DashboardID]grafana.DashboardID, len(o.Dashboards))
+	for _, d := range o.Dashboards {
+		folderID, ok := folderIDs[d.FolderUID]
+		if !ok && d.FolderUID != "" {
+			return fmt.Errorf("dashboard %q: folder %q is not in the backup", d.Title, d.FolderUID)
+		}
+		result, err := c.Dashboards.SaveJSON(ctx, d.Dashboard, folderID, true)
+		if err != nil {
+			return fmt.Errorf("dashboard %q: %s", d.Title, err)
+		}
+		dashboardIDs[d.ID] = result.ID
+		r.Dashboards++
+	}
+
+	for _, f := range o.Folders {
+		permissions := restorablePermissions(f.Permissions, "folder", f.Folder.Title, r)
+		if len(permissions) == 0 {
+			continue
+		}
+		if err := c.Folders.UpdatePermissions(ctx, f.Folder.UID, permissions); err != nil {
+			return fmt.Errorf("permissions of folder %q: %s", f.Folder.Title, err)
+		}
+		r.Permissions++
+	}
+	for _, d := range o.Dashboards {
+		permissions := restorablePermissions(d.Permissions, "dashboard", d.Title, r)
+		if len(permissions) == 0 {
+			continue
+		}
+		if err := c.Dashboards.UpdatePermissions(ctx, dashboardIDs[d.ID], permissions); err != nil {
+			return fmt.Errorf("permissions of dashboard %q: %s", d.Title, err)
+		}
+		r.Permissions++
+	}
+
+	for _, a := range o.Annotations {
+		restored := *a
+		restored.ID = 0
+		if a.DashboardID != 0 {
+			id, ok := dashboardIDs[a.DashboardID]
+			if !ok {
+				r.Skipped = append(r.Skipped, fmt.Sprintf("annotation %q of unknown dashboard %d", a.Text, a.DashboardID))
+				continue
+			}
+			restored.DashboardID = id
+		}
+		if err := c.Annotations.Create(ctx, &restored); err != nil {
+			return fmt.Errorf("annotation %q: %s", a.Text, err)
+		}
+		r.Annotations++
+	}
+	return nil
+}
+
+// restorablePermissions returns permissions granted to roles. Other permissions are added into skipped objects of
+// the report since users and teams are not backed up.
+func restorablePermissions(permissions []*grafana.Permission, kind, title string, r *Report) []*grafana.Permission {
+	var restorable []*grafana.Permission
+	for _, p := range permissions {
+		switch {
+		case p.Inherited:
+		case p.Role != "":
+			restorable = append(restorable, p)
+		case p.UserID != 0:
+			r.Skipped = append(r.Skipped, fmt.Sprintf("permission of user %q for %s %q", p.UserLogin, kind, title))
+		case p.TeamID != 0:
+			r.Skipped = append(r.Skipped, fmt.Sprintf("permission of team %q for %s %q", p.Team, kind, title))
+		}
+	}
+	return restorable
+}
@@ -0,0 +1,93 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// AlertNotificationsService communicates with alert notification channel methods of the Grafana API.
+type AlertNotificationsService struct {
+	client *Client
+}
+
+// NewAlertNotificationsService returns a new AlertNotificationsService.
+func NewAlertNotificationsService(client *Client) *AlertNotificationsService {
+	return &AlertNotificationsService{
+		client: client,
+	}
+}
+
+// ErrAlertNotificationNotFound represents an error if alert notification channel not found.
+var ErrAlertNotificationNotFound = errors.New("Alert notification not found")
+
+// GetAll fetches all alert notification channels.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/alerting/#get-alert-notifications
+func (s *AlertNotificationsService) GetAll(ctx context.Context) ([]*grafana.AlertNotification, error) {
+	u := "/api/alert-notifications"
+	req, err := s.client.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifications []*grafana.AlertNotification
+	if _, err := s.client.Do(req, &notifications); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// Create creates a new alert notification channel. ID of the created channel is set to given one.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/alerting/#create-alert-notification
+func (s *AlertNotificationsService) Create(ctx context.Context, n *grafana.AlertNotification) error {
+	u := "/api/alert-notifications"
+	req, err := s.client.NewRequest(ctx, "POST", u, n)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.client.Do(req, n); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Update updates alert notification channel with given id.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/alerting/#update-alert-notification
+func (s *AlertNotificationsService) Update(ctx context.Context, id grafana.AlertNotificationID, n *grafana.AlertNotification) error {
+	u := fmt.Sprintf("/api/alert-notifications/%d", id)
+	body := *n
+	body.ID = id
+	req, err := s.client.NewRequest(ctx, "PUT", u, &body)
+	if err != nil {
+		return err
+	}
+
+	if resp, err := s.client.Do(req, nil); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return ErrAlertNotificationNotFound
+		}
+		return err
+	}
+	return nil
+}
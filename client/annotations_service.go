@@ -0,0 +1,85 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// AnnotationsService communicates with annotation methods of the Grafana API.
+type AnnotationsService struct {
+	client *Client
+}
+
+// NewAnnotationsService returns a new AnnotationsService.
+func NewAnnotationsService(client *Client) *AnnotationsService {
+	return &AnnotationsService{
+		client: client,
+	}
+}
+
+// AnnotationFindOptions specifies the optional parameters to the AnnotationsService.Find method.
+type AnnotationFindOptions struct {
+	From        int64               `url:"from,omitempty"` // epoch in milliseconds
+	To          int64               `url:"to,omitempty"`
+	DashboardID grafana.DashboardID `url:"dashboardId,omitempty"`
+	PanelID     uint                `url:"panelId,omitempty"`
+	Tags        []string            `url:"tags,omitempty"`
+	Type        string              `url:"type,omitempty"`  // "annotation" or "alert"
+	Limit       int                 `url:"limit,omitempty"` // Grafana returns 100 annotations by default
+}
+
+// Find finds annotations with given criteria.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/annotations/#find-annotations
+func (s *AnnotationsService) Find(ctx context.Context, opt *AnnotationFindOptions) ([]*grafana.Annotation, error) {
+	u, err := addOptions("/api/annotations", opt)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.client.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var annotations []*grafana.Annotation
+	if _, err := s.client.Do(req, &annotations); err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
+
+// Create creates a new annotation. ID of the created annotation is set to given one.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/annotations/#create-annotation
+func (s *AnnotationsService) Create(ctx context.Context, a *grafana.Annotation) error {
+	u := "/api/annotations"
+	req, err := s.client.NewRequest(ctx, "POST", u, a)
+	if err != nil {
+		return err
+	}
+
+	var respBody struct {
+		ID grafana.AnnotationID `json:"id"`
+	}
+	if _, err := s.client.Do(req, &respBody); err != nil {
+		return err
+	}
+	a.ID = respBody.ID
+	return nil
+}
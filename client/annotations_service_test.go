@@ -0,0 +1,61 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+func TestAnnotationsService_FindAndCreate(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient(baseURL, "", nil)
+
+	mux.HandleFunc("/api/annotations", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			if got := r.URL.RawQuery; got != "limit=10&type=annotation" {
+				t.Errorf("Annotations.Find: unexpected query %s", got)
+			}
+			fmt.Fprint(w, `[{"id": 1, "dashboardId": 3, "panelId": 2, "time": 1500000000000, "tags": ["deploy"], "text": "v1"}]`)
+		case "POST":
+			fmt.Fprint(w, `{"id": 7, "message": "Annotation added"}`)
+		}
+	})
+
+	annotations, err := client.Annotations.Find(context.Background(), &AnnotationFindOptions{Type: "annotation", Limit: 10})
+	if err != nil {
+		t.Fatalf("Annotations.Find returned error: %v", err)
+	}
+	if len(annotations) != 1 || annotations[0].DashboardID != 3 || annotations[0].Text != "v1" {
+		t.Errorf("Annotations.Find returned unexpected annotations %+v", annotations)
+	}
+
+	a := &grafana.Annotation{Time: 1500000000000, Text: "v2"}
+	if err := client.Annotations.Create(context.Background(), a); err != nil {
+		t.Fatalf("Annotations.Create returned error: %v", err)
+	}
+	if a.ID != 7 {
+		t.Errorf("Annotations.Create: unexpected id %d, want 7", a.ID)
+	}
+}
@@ -25,21 +25,32 @@ import (
 	"reflect"
 
 	"github.com/google/go-querystring/query"
+	"github.com/spoof/go-grafana/grafana"
 )
 
 const (
 	mediaTypeJSON = "application/json"
+
+	// headerOrgID selects organization of the request for users which are members of several organizations.
+	headerOrgID = "X-Grafana-Org-Id"
 )
 
 // A Client manages communication with the Grafana API.
 type Client struct {
 	client    *http.Client // HTTP client used to communicate with the API.
 	token     string
+	username  string // basic authentication is used instead of the token if it's set
+	password  string
+	orgID     grafana.OrgID
 	BaseURL   *url.URL // Base URL for API requests.
 	UserAgent string   // User agent used when communicating with the GitHub API.
 
-	Dashboards  *DashboardsService
-	Datasources *DatasourcesService
+	AlertNotifications *AlertNotificationsService
+	Annotations        *AnnotationsService
+	Dashboards         *DashboardsService
+	Datasources        *DatasourcesService
+	Folders            *FoldersService
+	Orgs               *OrgsService
 }
 
 // NewClient returns a new Grafana API client. If a nil httpClient is
@@ -52,12 +63,40 @@ func NewClient(baseURL *url.URL, token string, httpClient *http.Client) *Client
 	}
 
 	c := &Client{client: httpClient, BaseURL: baseURL, token: token}
-	c.Dashboards = NewDashboardsService(c)
-	c.Datasources = NewDatasourcesService(c)
+	c.initServices()
+
+	return c
+}
+
+// NewBasicAuthClient returns a new Grafana API client which authenticates as given user. API tokens are bound to
+// a single organization, so basic authentication of a server admin is needed to manage all organizations.
+func NewBasicAuthClient(baseURL *url.URL, username, password string, httpClient *http.Client) *Client {
+	c := NewClient(baseURL, "", httpClient)
+	c.username = username
+	c.password = password
 
 	return c
 }
 
+// WithOrg returns a copy of the client which makes requests in the organization with given id. The user of the
+// client should be a member of the organization.
+func (c *Client) WithOrg(id grafana.OrgID) *Client {
+	oc := *c
+	oc.orgID = id
+	oc.initServices()
+
+	return &oc
+}
+
+func (c *Client) initServices() {
+	c.AlertNotifications = NewAlertNotificationsService(c)
+	c.Annotations = NewAnnotationsService(c)
+	c.Dashboards = NewDashboardsService(c)
+	c.Datasources = NewDatasourcesService(c)
+	c.Folders = NewFoldersService(c)
+	c.Orgs = NewOrgsService(c)
+}
+
 // NewRequest creates an API request.
 func (c *Client) NewRequest(ctx context.Context, method string, urlStr string, body interface{}) (*http.Request, error) {
 	relURL, err := url.Parse(urlStr)
@@ -81,7 +120,14 @@ func (c *Client) NewRequest(ctx context.Context, method string, urlStr string, b
 	}
 
 	req = req.WithContext(ctx)
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	} else {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	}
+	if c.orgID != 0 {
+		req.Header.Set(headerOrgID, fmt.Sprint(c.orgID))
+	}
 
 	if c.UserAgent != "" {
 		req.Header.Set("User-Agent", c.UserAgent)
@@ -61,3 +61,30 @@ func TestClient_NewRequest_ContentType(t *testing.T) {
 		}
 	}
 }
+
+func TestClient_NewRequest_BasicAuthAndOrg(t *testing.T) {
+	baseURL, _ := url.Parse("http://localhost/")
+	c := NewBasicAuthClient(baseURL, "admin", "secret", nil)
+	r, err := c.NewRequest(context.Background(), "GET", "path", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+	if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "secret" {
+		t.Errorf("Basic authentication is invalid. Got %s:%s", username, password)
+	}
+	if got := r.Header.Get("X-Grafana-Org-Id"); got != "" {
+		t.Errorf("X-Grafana-Org-Id header is set to %s without organization", got)
+	}
+
+	oc := c.WithOrg(2)
+	r, err = oc.Dashboards.client.NewRequest(context.Background(), "GET", "path", nil)
+	if err != nil {
+		t.Fatalf("NewRequest returned error: %v", err)
+	}
+	if got := r.Header.Get("X-Grafana-Org-Id"); got != "2" {
+		t.Errorf("X-Grafana-Org-Id header is invalid. Got %s, want 2", got)
+	}
+	if _, _, ok := r.BasicAuth(); !ok {
+		t.Errorf("Basic authentication is lost by WithOrg")
+	}
+}
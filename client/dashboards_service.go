@@ -15,16 +15,22 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/spoof/go-grafana/grafana"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
 )
 
 // DashboardsService communicates with dashboard methods of the Grafana API.
 type DashboardsService struct {
 	client *Client
+
+	// Datasources resolves types of queries of fetched dashboards by their datasources. Types of queries are
+	// guessed by their fields if it's nil. Use DatasourcesService.Registry to get datasources of Grafana.
+	Datasources *grafana.DatasourceRegistry
 }
 
 // NewDashboardsService returns a new DashboardsService.
@@ -57,23 +63,50 @@ func (ds *DashboardsService) Get(ctx context.Context, slug string) (*grafana.Das
 		return nil, err
 	}
 
-	d := dResp.Dashboard
+	d, err := grafana.UnmarshalDashboard(dResp.Dashboard, ds.Datasources)
+	if err != nil {
+		return nil, err
+	}
 	d.Meta = dResp.Meta
-	return &d, nil
+	return d, nil
+}
+
+// GetJSON fetches JSON of a dashboard by given slug as it's returned by Grafana. Unlike Get, it keeps all fields of
+// the dashboard including ones which are not supported by grafana.Dashboard, ie. panels of unregistered types.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/dashboard/#get-dashboard
+func (ds *DashboardsService) GetJSON(ctx context.Context, slug string) (json.RawMessage, *grafana.DashboardMeta, error) {
+	u := fmt.Sprintf("/api/dashboards/db/%s", slug)
+	req, err := ds.client.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var dResp dashboardGetResponse
+	if resp, err := ds.client.Do(req, &dResp); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, nil, ErrDashboardNotFound
+		}
+		return nil, nil, err
+	}
+	return dResp.Dashboard, dResp.Meta, nil
 }
 
 type dashboardGetResponse struct {
-	Dashboard grafana.Dashboard      `json:"dashboard"`
+	Dashboard json.RawMessage        `json:"dashboard"`
 	Meta      *grafana.DashboardMeta `json:"meta"`
 }
 
-// Save creates a new dashboard or updates existing one.
+// Save creates a new dashboard or updates existing one. The dashboard is saved into folder of its meta if it's set.
 //
 // Grafana API docs: http://docs.grafana.org/http_api/dashboard/#create-update-dashboard
 func (ds *DashboardsService) Save(ctx context.Context, dashboard *grafana.Dashboard, overwrite bool) error {
 	u := "/api/dashboards/db"
 
-	dReq := dashboardCreateRequest{dashboard, overwrite}
+	dReq := dashboardCreateRequest{Dashboard: dashboard, Overwrite: overwrite}
+	if dashboard.Meta != nil {
+		dReq.FolderID = dashboard.Meta.FolderID
+	}
 	req, err := ds.client.NewRequest(ctx, "POST", u, dReq)
 	if err != nil {
 		return err
@@ -112,6 +145,160 @@ func (ds *DashboardsService) Save(ctx context.Context, dashboard *grafana.Dashbo
 type dashboardCreateRequest struct {
 	Dashboard *grafana.Dashboard `json:"dashboard"`
 	Overwrite bool               `json:"overwrite"`
+	FolderID  grafana.FolderID   `json:"folderId,omitempty"`
+}
+
+// DashboardSaveResult represents a dashboard saved by DashboardsService.SaveJSON.
+type DashboardSaveResult struct {
+	ID      grafana.DashboardID `json:"id"`
+	UID     string              `json:"uid"`
+	Slug    string              `json:"slug"`
+	Version int                 `json:"version"`
+}
+
+// SaveJSON creates a new dashboard or updates existing one from given JSON as is, so fields which are not supported
+// by grafana.Dashboard are saved too. Id and version of the dashboard are removed, so it's matched with existing
+// dashboard by its uid or title the same way as imported one. The dashboard is saved into folder with given id.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/dashboard/#create-update-dashboard
+func (ds *DashboardsService) SaveJSON(ctx context.Context, data json.RawMessage, folderID grafana.FolderID, overwrite bool) (*DashboardSaveResult, error) {
+	u := "/api/dashboards/db"
+
+	data, err := jsontools.SetFields(data, map[string]interface{}{"id": nil, "version": nil})
+	if err != nil {
+		return nil, err
+	}
+	dReq := dashboardCreateJSONRequest{Dashboard: data, Overwrite: overwrite, FolderID: folderID}
+	req, err := ds.client.NewRequest(ctx, "POST", u, dReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var result DashboardSaveResult
+	if _, err := ds.client.Do(req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type dashboardCreateJSONRequest struct {
+	Dashboard json.RawMessage  `json:"dashboard"`
+	Overwrite bool             `json:"overwrite"`
+	FolderID  grafana.FolderID `json:"folderId,omitempty"`
+}
+
+// Import imports a dashboard shared on grafana.com or exported for sharing. Inputs of the dashboard are bound to
+// given values by their names, ie. "DS_PROMETHEUS" to name of a datasource. Constant inputs without values use
+// their default ones. The dashboard is imported into folder of its meta if it's set.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/dashboard/#import-dashboard
+func (ds *DashboardsService) Import(ctx context.Context, dashboard *grafana.Dashboard, inputs map[string]string, overwrite bool) error {
+	data, err := json.Marshal(dashboard)
+	if err != nil {
+		return err
+	}
+	var folderID grafana.FolderID
+	if dashboard.Meta != nil {
+		folderID = dashboard.Meta.FolderID
+	}
+	result, err := ds.ImportJSON(ctx, data, inputs, folderID, overwrite)
+	if err != nil {
+		return err
+	}
+
+	// Inputs are replaced by Grafana, so imported dashboard is refetched like saved one.
+	if result.Imported && result.Slug != "" {
+		d, err := ds.Get(ctx, result.Slug)
+		if err != nil {
+			return err
+		}
+		*dashboard = *d
+	}
+	return nil
+}
+
+// DashboardImportResult represents a dashboard imported by DashboardsService.ImportJSON.
+type DashboardImportResult struct {
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Imported bool   `json:"imported"`
+}
+
+// ImportJSON imports a dashboard from given JSON as is, so fields which are not supported by grafana.Dashboard are
+// imported too. Inputs are bound the same way as by Import. The dashboard is imported into folder with given id.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/dashboard/#import-dashboard
+func (ds *DashboardsService) ImportJSON(ctx context.Context, data json.RawMessage, inputs map[string]string, folderID grafana.FolderID, overwrite bool) (*DashboardImportResult, error) {
+	u := "/api/dashboards/import"
+
+	var shared struct {
+		Inputs []grafana.DashboardInput `json:"__inputs"`
+	}
+	if err := json.Unmarshal(data, &shared); err != nil {
+		return nil, err
+	}
+	dReq := dashboardImportRequest{Dashboard: data, Overwrite: overwrite, Inputs: []dashboardImportInput{}, FolderID: folderID}
+	for _, in := range shared.Inputs {
+		value, ok := inputs[in.Name]
+		if !ok && in.Type == grafana.ConstantInput {
+			// Default value of constant input may be empty
+			value, ok = in.Value, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("Value of input %q is not given", in.Name)
+		}
+		dReq.Inputs = append(dReq.Inputs, dashboardImportInput{
+			Name:     in.Name,
+			Type:     string(in.Type),
+			PluginID: in.PluginID,
+			Value:    value,
+		})
+	}
+
+	req, err := ds.client.NewRequest(ctx, "POST", u, dReq)
+	if err != nil {
+		return nil, err
+	}
+
+	var result DashboardImportResult
+	if _, err := ds.client.Do(req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type dashboardImportRequest struct {
+	Dashboard json.RawMessage        `json:"dashboard"`
+	Overwrite bool                   `json:"overwrite"`
+	Inputs    []dashboardImportInput `json:"inputs"`
+	FolderID  grafana.FolderID       `json:"folderId,omitempty"`
+}
+
+type dashboardImportInput struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	PluginID string `json:"pluginId,omitempty"`
+	Value    string `json:"value"`
+}
+
+// Delete deletes a dashboard by given slug.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/dashboard/#delete-dashboard
+func (ds *DashboardsService) Delete(ctx context.Context, slug string) error {
+	u := fmt.Sprintf("/api/dashboards/db/%s", slug)
+	req, err := ds.client.NewRequest(ctx, "DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+
+	if resp, err := ds.client.Do(req, nil); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return ErrDashboardNotFound
+		}
+		return err
+	}
+
+	return nil
 }
 
 // DashboardSearchOptions specifies the optional parameters to the
@@ -148,11 +335,39 @@ func (ds *DashboardsService) Search(ctx context.Context, opt *DashboardSearchOpt
 	return hits, nil
 }
 
-// DashboardHit represents a found by DashboardsService.Search dashboard
+// DashboardHit represents a found by DashboardsService.Search dashboard or folder
 type DashboardHit struct {
-	ID        int64    `json:"id"`
-	Title     string   `json:"title"`
-	URI       string   `json:"uri"`
-	Tags      []string `json:"tags"`
-	IsStarred bool     `json:"isStarred"`
+	ID        int64            `json:"id"`
+	UID       string           `json:"uid"`
+	Type      string           `json:"type"` // "dash-db" or "dash-folder"
+	Title     string           `json:"title"`
+	URI       string           `json:"uri"`
+	Tags      []string         `json:"tags"`
+	IsStarred bool             `json:"isStarred"`
+	FolderID  grafana.FolderID `json:"folderId"`
+}
+
+// GetPermissions fetches permissions of dashboard with given id. Permissions of dashboards in folders include ones
+// inherited from the folder.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/dashboard_permissions/#get-permissions-for-a-dashboard
+func (ds *DashboardsService) GetPermissions(ctx context.Context, id grafana.DashboardID) ([]*grafana.Permission, error) {
+	u := fmt.Sprintf("/api/dashboards/id/%d/permissions", id)
+	permissions, err := getPermissions(ctx, ds.client, u)
+	if err == errPermissionsNotFound {
+		return nil, ErrDashboardNotFound
+	}
+	return permissions, err
+}
+
+// UpdatePermissions replaces permissions of dashboard with given id. Inherited permissions are skipped.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/dashboard_permissions/#update-permissions-for-a-dashboard
+func (ds *DashboardsService) UpdatePermissions(ctx context.Context, id grafana.DashboardID, permissions []*grafana.Permission) error {
+	u := fmt.Sprintf("/api/dashboards/id/%d/permissions", id)
+	err := updatePermissions(ctx, ds.client, u, permissions)
+	if err == errPermissionsNotFound {
+		return ErrDashboardNotFound
+	}
+	return err
 }
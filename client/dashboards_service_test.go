@@ -15,6 +15,7 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -23,6 +24,7 @@ import (
 	"testing"
 
 	"github.com/spoof/go-grafana/grafana"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
 )
 
 func TestDashboardsService_Get(t *testing.T) {
@@ -91,6 +93,76 @@ func TestDashboardsService_Save_New(t *testing.T) {
 
 }
 
+func TestDashboardsService_GetAndSaveJSON(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient(baseURL, "", nil)
+
+	slug := "slug"
+	dashboard := `{"id": 1, "uid": "abc", "title": "title", "version": 2, "panels": [{"id": 1, "type": "worldmap-panel"}]}`
+	mux.HandleFunc("/api/dashboards/db/"+slug, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"dashboard": `+dashboard+`, "meta": {"slug": "`+slug+`"}}`)
+	})
+	var saved dashboardCreateJSONRequest
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if err := json.NewDecoder(r.Body).Decode(&saved); err != nil {
+			t.Errorf("Dashboards.SaveJSON sent invalid request: %v", err)
+		}
+		fmt.Fprint(w, `{"id": 2, "uid": "abc", "slug": "`+slug+`", "version": 3, "status": "success"}`)
+	})
+
+	data, _, err := client.Dashboards.GetJSON(context.Background(), slug)
+	if err != nil {
+		t.Fatalf("Dashboards.GetJSON returned error: %v", err)
+	}
+	if eq, err := jsontools.BytesEqual(data, []byte(dashboard)); err != nil || !eq {
+		t.Errorf("Dashboards.GetJSON\nreturned: %s\nwant: %s", data, dashboard)
+	}
+
+	result, err := client.Dashboards.SaveJSON(context.Background(), data, grafana.FolderID(5), true)
+	if err != nil {
+		t.Fatalf("Dashboards.SaveJSON returned error: %v", err)
+	}
+	want := &DashboardSaveResult{ID: 2, UID: "abc", Slug: slug, Version: 3}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Dashboards.SaveJSON\nreturned: %+v\nwant: %+v", result, want)
+	}
+	expected := `{"uid": "abc", "title": "title", "panels": [{"id": 1, "type": "worldmap-panel"}]}`
+	if eq, err := jsontools.BytesEqual(saved.Dashboard, []byte(expected)); err != nil || !eq {
+		t.Errorf("Dashboards.SaveJSON\nsent: %s\nwant: %s", saved.Dashboard, expected)
+	}
+	if !saved.Overwrite || saved.FolderID != 5 {
+		t.Errorf("Dashboards.SaveJSON sent unexpected options: %+v", saved)
+	}
+}
+
+func TestDashboardsService_Delete(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient(baseURL, "", nil)
+
+	slug := "slug"
+	mux.HandleFunc("/api/dashboards/db/"+slug, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		fmt.Fprint(w, `{"title": "title"}`)
+	})
+	mux.HandleFunc("/api/dashboards/db/unknown", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Dashboard not found"}`)
+	})
+
+	if err := client.Dashboards.Delete(context.Background(), slug); err != nil {
+		t.Fatalf("Dashboards.Delete returned error: %v", err)
+	}
+	if err := client.Dashboards.Delete(context.Background(), "unknown"); err != ErrDashboardNotFound {
+		t.Errorf("Dashboards.Delete returned %v, want %v", err, ErrDashboardNotFound)
+	}
+}
+
 func TestDashboardsService_Search(t *testing.T) {
 	mux := http.NewServeMux()
 	server := httptest.NewServer(mux)
@@ -125,3 +197,52 @@ func testMethod(t *testing.T, r *http.Request, want string) {
 		t.Errorf("Request method: %v, want %v", got, want)
 	}
 }
+
+func TestDashboardsService_Import(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient(baseURL, "", nil)
+
+	var body struct {
+		Overwrite bool                   `json:"overwrite"`
+		Inputs    []dashboardImportInput `json:"inputs"`
+	}
+	mux.HandleFunc("/api/dashboards/import", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Dashboards.Import: invalid request body: %v", err)
+		}
+		fmt.Fprint(w, `{"pluginId": "", "title": "Node Exporter", "imported": true, "slug": "node-exporter"}`)
+	})
+	mux.HandleFunc("/api/dashboards/db/node-exporter", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"dashboard": {"id": 3, "title": "Node Exporter", "schemaVersion": 16}}`)
+	})
+
+	d := grafana.NewDashboard("Node Exporter")
+	d.Inputs = []grafana.DashboardInput{
+		{Name: "DS_PROMETHEUS", Type: grafana.DatasourceInput, PluginID: "prometheus"},
+		{Name: "VAR_JOB", Type: grafana.ConstantInput, Value: "node"},
+		{Name: "VAR_PREFIX", Type: grafana.ConstantInput},
+	}
+	if err := client.Dashboards.Import(context.Background(), d, map[string]string{}, true); err == nil {
+		t.Errorf("Dashboards.Import: expected error of unbound input")
+	}
+
+	err := client.Dashboards.Import(context.Background(), d, map[string]string{"DS_PROMETHEUS": "Prometheus"}, true)
+	if err != nil {
+		t.Fatalf("Dashboards.Import returned error: %v", err)
+	}
+	want := []dashboardImportInput{
+		{Name: "DS_PROMETHEUS", Type: "datasource", PluginID: "prometheus", Value: "Prometheus"},
+		{Name: "VAR_JOB", Type: "constant", Value: "node"},
+		{Name: "VAR_PREFIX", Type: "constant", Value: ""},
+	}
+	if !body.Overwrite || !reflect.DeepEqual(body.Inputs, want) {
+		t.Errorf("Dashboards.Import sent overwrite %t and inputs %+v, want %+v", body.Overwrite, body.Inputs, want)
+	}
+	if d.ID != 3 {
+		t.Errorf("Dashboards.Import: dashboard is not refetched, id %d", d.ID)
+	}
+}
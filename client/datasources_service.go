@@ -52,6 +52,16 @@ func (s *DatasourcesService) GetAll(ctx context.Context) ([]*grafana.Datasource,
 	return datasources, nil
 }
 
+// Registry fetches all datasources and returns registry of them. The registry is used to decode dashboards' queries
+// into types of their datasources, see DashboardsService.Datasources.
+func (s *DatasourcesService) Registry(ctx context.Context) (*grafana.DatasourceRegistry, error) {
+	datasources, err := s.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return grafana.NewDatasourceRegistry(datasources...), nil
+}
+
 // ErrDatasourceNotFound represents an error if datasource not found.
 var ErrDatasourceNotFound = errors.New("Datasource not found")
 
@@ -106,3 +116,58 @@ func (s *DatasourcesService) GetByName(ctx context.Context, name string) (*grafa
 
 	return &d, nil
 }
+
+// Create creates a new datasource. ID of the created datasource is set to given datasource.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/data_source/#create-data-source
+func (s *DatasourcesService) Create(ctx context.Context, d *grafana.Datasource) error {
+	u := "/api/datasources"
+	req, err := s.client.NewRequest(ctx, "POST", u, d)
+	if err != nil {
+		return err
+	}
+
+	// Response contains id and name of the datasource
+	if _, err := s.client.Do(req, d); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Update updates datasource with given id.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/data_source/#update-an-existing-data-source
+func (s *DatasourcesService) Update(ctx context.Context, id grafana.DatasourceID, d *grafana.Datasource) error {
+	u := fmt.Sprintf("/api/datasources/%d", id)
+	req, err := s.client.NewRequest(ctx, "PUT", u, d)
+	if err != nil {
+		return err
+	}
+
+	if resp, err := s.client.Do(req, nil); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return ErrDatasourceNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes datasource with given id.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/data_source/#delete-an-existing-data-source-by-id
+func (s *DatasourcesService) Delete(ctx context.Context, id grafana.DatasourceID) error {
+	u := fmt.Sprintf("/api/datasources/%d", id)
+	req, err := s.client.NewRequest(ctx, "DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+
+	if resp, err := s.client.Do(req, nil); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return ErrDatasourceNotFound
+		}
+		return err
+	}
+	return nil
+}
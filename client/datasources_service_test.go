@@ -0,0 +1,66 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+func TestDatasourcesService_CreateUpdate(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient(baseURL, "", nil)
+
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		var d grafana.Datasource
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil || d.Name != "Prometheus" {
+			t.Errorf("Datasources.Create: unexpected request body %+v (%v)", d, err)
+		}
+		fmt.Fprint(w, `{"id": 5, "message": "Datasource added", "name": "Prometheus"}`)
+	})
+	var updated bool
+	mux.HandleFunc("/api/datasources/5", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		updated = true
+		fmt.Fprint(w, `{"message": "Datasource updated"}`)
+	})
+
+	d := &grafana.Datasource{Name: "Prometheus", Type: grafana.PrometheusDatasource, URL: "http://prometheus:9090"}
+	if err := client.Datasources.Create(context.Background(), d); err != nil {
+		t.Fatalf("Datasources.Create returned error: %v", err)
+	}
+	if d.ID() != 5 || d.URL != "http://prometheus:9090" {
+		t.Errorf("Datasources.Create: unexpected datasource %+v with id %d", d, d.ID())
+	}
+
+	if err := client.Datasources.Update(context.Background(), d.ID(), d); err != nil {
+		t.Fatalf("Datasources.Update returned error: %v", err)
+	}
+	if !updated {
+		t.Errorf("Datasources.Update: datasource is not updated")
+	}
+	if err := client.Datasources.Update(context.Background(), 6, d); err != ErrDatasourceNotFound {
+		t.Errorf("Datasources.Update returned %v, want %v", err, ErrDatasourceNotFound)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// FoldersService communicates with folder methods of the Grafana API.
+type FoldersService struct {
+	client *Client
+}
+
+// NewFoldersService returns a new FoldersService.
+func NewFoldersService(client *Client) *FoldersService {
+	return &FoldersService{
+		client: client,
+	}
+}
+
+// ErrFolderNotFound represents an error if folder not found.
+var ErrFolderNotFound = errors.New("Folder not found")
+
+// GetAll fetches all folders.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/folder/#get-all-folders
+func (s *FoldersService) GetAll(ctx context.Context) ([]*grafana.Folder, error) {
+	u := "/api/folders"
+	req, err := s.client.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var folders []*grafana.Folder
+	if _, err := s.client.Do(req, &folders); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// GetByUID fetches folder by given uid.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/folder/#get-folder-by-uid
+func (s *FoldersService) GetByUID(ctx context.Context, uid string) (*grafana.Folder, error) {
+	u := fmt.Sprintf("/api/folders/%s", uid)
+	req, err := s.client.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var f grafana.Folder
+	if resp, err := s.client.Do(req, &f); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrFolderNotFound
+		}
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// Create creates a new folder. Grafana generates uid of the folder if it's empty. ID and uid of the created folder
+// are set to given folder.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/folder/#create-folder
+func (s *FoldersService) Create(ctx context.Context, f *grafana.Folder) error {
+	u := "/api/folders"
+	body := struct {
+		UID   string `json:"uid,omitempty"`
+		Title string `json:"title"`
+	}{f.UID, f.Title}
+	req, err := s.client.NewRequest(ctx, "POST", u, body)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.client.Do(req, f); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetPermissions fetches permissions of folder with given uid.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/folder_permissions/#get-permissions-for-a-folder
+func (s *FoldersService) GetPermissions(ctx context.Context, uid string) ([]*grafana.Permission, error) {
+	u := fmt.Sprintf("/api/folders/%s/permissions", uid)
+	permissions, err := getPermissions(ctx, s.client, u)
+	if err == errPermissionsNotFound {
+		return nil, ErrFolderNotFound
+	}
+	return permissions, err
+}
+
+// UpdatePermissions replaces permissions of folder with given uid. Inherited permissions are skipped.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/folder_permissions/#update-permissions-for-a-folder
+func (s *FoldersService) UpdatePermissions(ctx context.Context, uid string, permissions []*grafana.Permission) error {
+	u := fmt.Sprintf("/api/folders/%s/permissions", uid)
+	err := updatePermissions(ctx, s.client, u, permissions)
+	if err == errPermissionsNotFound {
+		return ErrFolderNotFound
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+func TestFoldersService_CreateAndPermissions(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient(baseURL, "", nil)
+
+	mux.HandleFunc("/api/folders", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		fmt.Fprint(w, `{"id": 4, "uid": "ops", "title": "Ops"}`)
+	})
+	var items []map[string]interface{}
+	mux.HandleFunc("/api/folders/ops/permissions", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			fmt.Fprint(w, `[{"role": "Viewer", "permission": 1}, {"userId": 2, "userLogin": "ops", "permission": 4}]`)
+		case "POST":
+			var body struct {
+				Items []map[string]interface{} `json:"items"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("Folders.UpdatePermissions: unexpected request body (%v)", err)
+			}
+			items = body.Items
+			fmt.Fprint(w, `{"message": "Folder permissions updated"}`)
+		}
+	})
+
+	f := &grafana.Folder{UID: "ops", Title: "Ops"}
+	if err := client.Folders.Create(context.Background(), f); err != nil {
+		t.Fatalf("Folders.Create returned error: %v", err)
+	}
+	if f.ID != 4 {
+		t.Errorf("Folders.Create: unexpected id %d, want 4", f.ID)
+	}
+
+	permissions, err := client.Folders.GetPermissions(context.Background(), "ops")
+	if err != nil {
+		t.Fatalf("Folders.GetPermissions returned error: %v", err)
+	}
+	want := []*grafana.Permission{
+		{Role: "Viewer", Permission: grafana.ViewPermission},
+		{UserID: 2, UserLogin: "ops", Permission: grafana.AdminPermission},
+	}
+	if !reflect.DeepEqual(permissions, want) {
+		t.Errorf("Folders.GetPermissions returned %+v, want %+v", permissions, want)
+	}
+	if _, err := client.Folders.GetPermissions(context.Background(), "unknown"); err != ErrFolderNotFound {
+		t.Errorf("Folders.GetPermissions returned %v, want %v", err, ErrFolderNotFound)
+	}
+
+	permissions = append(permissions, &grafana.Permission{Role: "Editor", Permission: grafana.EditPermission, Inherited: true})
+	if err := client.Folders.UpdatePermissions(context.Background(), "ops", permissions); err != nil {
+		t.Fatalf("Folders.UpdatePermissions returned error: %v", err)
+	}
+	wantItems := []map[string]interface{}{
+		{"role": "Viewer", "permission": float64(1)},
+		{"userId": float64(2), "permission": float64(4)},
+	}
+	if !reflect.DeepEqual(items, wantItems) {
+		t.Errorf("Folders.UpdatePermissions sent %v, want %v", items, wantItems)
+	}
+}
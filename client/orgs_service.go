@@ -0,0 +1,120 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// OrgsService communicates with organization methods of the Grafana API.
+type OrgsService struct {
+	client *Client
+}
+
+// NewOrgsService returns a new OrgsService.
+func NewOrgsService(client *Client) *OrgsService {
+	return &OrgsService{
+		client: client,
+	}
+}
+
+// ErrOrgNotFound represents an error if organization not found.
+var ErrOrgNotFound = errors.New("Organization not found")
+
+// Current fetches organization of the client.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/org/#get-current-organization
+func (s *OrgsService) Current(ctx context.Context) (*grafana.Org, error) {
+	u := "/api/org"
+	req, err := s.client.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var o grafana.Org
+	if _, err := s.client.Do(req, &o); err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// GetAll fetches all organizations. It requires basic authentication of a server admin.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/org/#search-all-organizations
+func (s *OrgsService) GetAll(ctx context.Context) ([]*grafana.Org, error) {
+	u := "/api/orgs"
+	req, err := s.client.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var orgs []*grafana.Org
+	if _, err := s.client.Do(req, &orgs); err != nil {
+		return nil, err
+	}
+
+	return orgs, nil
+}
+
+// GetByName fetches organization with given name.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/org/#get-organization-by-name
+func (s *OrgsService) GetByName(ctx context.Context, name string) (*grafana.Org, error) {
+	if name == "" {
+		return nil, errors.New("Name cannot be empty")
+	}
+
+	u := fmt.Sprintf("/api/orgs/name/%s", url.PathEscape(name))
+	req, err := s.client.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var o grafana.Org
+	if resp, err := s.client.Do(req, &o); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, ErrOrgNotFound
+		}
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// Create creates a new organization. ID of the created organization is set to given organization.
+//
+// Grafana API docs: http://docs.grafana.org/http_api/org/#create-organization
+func (s *OrgsService) Create(ctx context.Context, o *grafana.Org) error {
+	u := "/api/orgs"
+	req, err := s.client.NewRequest(ctx, "POST", u, o)
+	if err != nil {
+		return err
+	}
+
+	var respBody struct {
+		OrgID grafana.OrgID `json:"orgId"`
+	}
+	if _, err := s.client.Do(req, &respBody); err != nil {
+		return err
+	}
+	o.ID = respBody.OrgID
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+func TestOrgsService(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	baseURL, _ := url.Parse(server.URL + "/")
+	client := NewClient(baseURL, "", nil)
+
+	mux.HandleFunc("/api/orgs", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			fmt.Fprint(w, `[{"id": 1, "name": "Main Org."}, {"id": 2, "name": "Ops"}]`)
+		case "POST":
+			var o grafana.Org
+			if err := json.NewDecoder(r.Body).Decode(&o); err != nil || o.Name != "Dev" {
+				t.Errorf("Orgs.Create: unexpected request body %+v (%v)", o, err)
+			}
+			fmt.Fprint(w, `{"orgId": 3, "message": "Organization created"}`)
+		}
+	})
+	mux.HandleFunc("/api/orgs/name/Main Org.", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		fmt.Fprint(w, `{"id": 1, "name": "Main Org."}`)
+	})
+
+	orgs, err := client.Orgs.GetAll(context.Background())
+	if err != nil {
+		t.Fatalf("Orgs.GetAll returned error: %v", err)
+	}
+	want := []*grafana.Org{{ID: 1, Name: "Main Org."}, {ID: 2, Name: "Ops"}}
+	if !reflect.DeepEqual(orgs, want) {
+		t.Errorf("Orgs.GetAll returned %+v, want %+v", orgs, want)
+	}
+
+	o, err := client.Orgs.GetByName(context.Background(), "Main Org.")
+	if err != nil || o.ID != 1 {
+		t.Errorf("Orgs.GetByName returned %+v, %v", o, err)
+	}
+	if _, err := client.Orgs.GetByName(context.Background(), "Unknown"); err != ErrOrgNotFound {
+		t.Errorf("Orgs.GetByName returned %v, want %v", err, ErrOrgNotFound)
+	}
+
+	o = &grafana.Org{Name: "Dev"}
+	if err := client.Orgs.Create(context.Background(), o); err != nil {
+		t.Fatalf("Orgs.Create returned error: %v", err)
+	}
+	if o.ID != 3 {
+		t.Errorf("Orgs.Create: unexpected id %d, want 3", o.ID)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// errPermissionsNotFound is returned if the dashboard or the folder of permissions not found.
+var errPermissionsNotFound = errors.New("permissions not found")
+
+func getPermissions(ctx context.Context, c *Client, u string) ([]*grafana.Permission, error) {
+	req, err := c.NewRequest(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions []*grafana.Permission
+	if resp, err := c.Do(req, &permissions); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, errPermissionsNotFound
+		}
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func updatePermissions(ctx context.Context, c *Client, u string, permissions []*grafana.Permission) error {
+	type item struct {
+		Role       string `json:"role,omitempty"`
+		UserID     uint   `json:"userId,omitempty"`
+		TeamID     uint   `json:"teamId,omitempty"`
+		Permission uint8  `json:"permission"`
+	}
+	body := struct {
+		Items []item `json:"items"`
+	}{Items: make([]item, 0, len(permissions))}
+	for _, p := range permissions {
+		if p.Inherited {
+			continue // inherited permissions are managed by the folder
+		}
+		body.Items = append(body.Items, item{p.Role, p.UserID, p.TeamID, uint8(p.Permission)})
+	}
+
+	req, err := c.NewRequest(ctx, "POST", u, body)
+	if err != nil {
+		return err
+	}
+
+	if resp, err := c.Do(req, nil); err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return errPermissionsNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/spoof/go-grafana/apply"
+)
+
+func planDirectory(a *app, args []string) error {
+	flags := a.newFlagSet("plan")
+	applier := applierFlags(a, flags)
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	_, err := a.plan(applier, flags.Arg(0))
+	return err
+}
+
+func applyDirectory(a *app, args []string) error {
+	flags := a.newFlagSet("apply")
+	applier := applierFlags(a, flags)
+	autoApprove := flags.Bool("auto-approve", false, "apply changes without confirmation")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	plan, err := a.plan(applier, flags.Arg(0))
+	if err != nil || plan.Empty() {
+		return err
+	}
+
+	if !*autoApprove {
+		fmt.Fprint(a.stdout, "\nApply these changes? Only 'yes' will be accepted: ")
+		answer, _ := bufio.NewReader(a.stdin).ReadString('\n')
+		if strings.TrimSpace(answer) != "yes" {
+			fmt.Fprintln(a.stdout, "Apply cancelled.")
+			return nil
+		}
+	}
+
+	if err := applier.Apply(a.ctx, plan); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Apply complete: %d changes.\n", len(plan.Changes))
+	return nil
+}
+
+// applierFlags adds flags of applier into given flag set.
+func applierFlags(a *app, flags *flag.FlagSet) *apply.Applier {
+	applier := apply.NewApplier(a.client)
+	flags.BoolVar(&applier.Prune, "prune", false, "delete dashboards with managed tag which are not in the directory")
+	flags.StringVar(&applier.ManagedTag, "managed-tag", apply.DefaultManagedTag, "tag of managed dashboards")
+	return applier
+}
+
+// plan loads desired state from the directory and prints plan of its changes.
+func (a *app) plan(applier *apply.Applier, dir string) (*apply.Plan, error) {
+	state, err := apply.LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := applier.Plan(a.ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plan.WriteTo(a.stdout); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/spoof/go-grafana/backup"
+)
+
+func backupGrafana(a *app, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	b, err := backup.Take(a.ctx, a.client)
+	if err != nil {
+		return err
+	}
+	if err := b.Save(args[0]); err != nil {
+		return err
+	}
+	for _, o := range b.Orgs {
+		fmt.Fprintf(a.stdout, "%s: %d datasources, %d folders, %d dashboards, %d alert notifications, %d annotations\n",
+			o.Org.Name, len(o.Datasources), len(o.Folders), len(o.Dashboards), len(o.AlertNotifications), len(o.Annotations))
+	}
+	return nil
+}
+
+func restoreGrafana(a *app, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	b, err := backup.Load(args[0])
+	if err != nil {
+		return err
+	}
+	r, err := backup.Restore(a.ctx, a.client, b)
+	if r != nil {
+		fmt.Fprintf(a.stdout, "Restored %d organizations, %d datasources, %d alert notifications, %d folders, "+
+			"%d dashboards, %d permissions, %d annotations.\n",
+			r.Orgs, r.Datasources, r.AlertNotifications, r.Folders, r.Dashboards, r.Permissions, r.Annotations)
+		for _, skipped := range r.Skipped {
+			fmt.Fprintf(a.stdout, "Skipped %s.\n", skipped)
+		}
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spoof/go-grafana/client"
+)
+
+// Environment variables configuring grafanactl
+const (
+	envURL     = "GRAFANA_URL"
+	envToken   = "GRAFANA_TOKEN"
+	envConfig  = "GRAFANACTL_CONFIG"
+	configName = ".grafanactl.json" // default contexts file in home directory
+)
+
+// Config is a contexts file with named Grafana instances.
+type Config struct {
+	Current  string              `json:"current"`
+	Contexts map[string]*Context `json:"contexts"`
+}
+
+// Context is a Grafana instance of the contexts file.
+type Context struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// loadConfig reads contexts file from given path. Empty config is returned if the file doesn't exist.
+func loadConfig(path string) (*Config, error) {
+	config := &Config{Contexts: make(map[string]*Context)}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return config, nil
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid contexts file %s: %s", path, err)
+	}
+	if config.Contexts == nil {
+		config.Contexts = make(map[string]*Context)
+	}
+	return config, nil
+}
+
+// Names returns sorted names of contexts.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options are global options of grafanactl given by flags.
+type Options struct {
+	URL        string
+	Token      string
+	Context    string
+	ConfigPath string
+}
+
+// configPath returns path of the contexts file: given by flag, environment variable or the default one.
+func (o Options) configPath() string {
+	if o.ConfigPath != "" {
+		return o.ConfigPath
+	}
+	if path := os.Getenv(envConfig); path != "" {
+		return path
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, configName)
+	}
+	return ""
+}
+
+// resolve returns URL and token of Grafana instance. Flags override environment variables which override context of
+// the contexts file.
+func (o Options) resolve(config *Config) (*Context, error) {
+	var resolved Context
+	name := o.Context
+	if name == "" {
+		name = config.Current
+	}
+	if name != "" {
+		c, ok := config.Contexts[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown context %q", name)
+		}
+		resolved = *c
+	}
+
+	if u := os.Getenv(envURL); u != "" && o.Context == "" {
+		resolved.URL = u
+	}
+	if token := os.Getenv(envToken); token != "" && o.Context == "" {
+		resolved.Token = token
+	}
+	if o.URL != "" {
+		resolved.URL = o.URL
+	}
+	if o.Token != "" {
+		resolved.Token = o.Token
+	}
+
+	if resolved.URL == "" {
+		return nil, errors.New("URL of Grafana is not set, use -url flag, " + envURL + " or contexts file")
+	}
+	return &resolved, nil
+}
+
+// client creates Grafana API client for resolved Grafana instance. User info of the URL is used for basic
+// authentication instead of the token.
+func (o Options) client(config *Config) (*client.Client, error) {
+	c, err := o.resolve(config)
+	if err != nil {
+		return nil, err
+	}
+
+	u := c.URL
+	if !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	baseURL, err := url.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL of Grafana: %s", err)
+	}
+	if user := baseURL.User; user != nil {
+		baseURL.User = nil
+		password, _ := user.Password()
+		return client.NewBasicAuthClient(baseURL, user.Username(), password, nil), nil
+	}
+	return client.NewClient(baseURL, c.Token, nil), nil
+}
@@ -0,0 +1,236 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spoof/go-grafana/client"
+	"github.com/spoof/go-grafana/grafana"
+)
+
+func getDashboard(a *app, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	data, _, err := a.client.Dashboards.GetJSON(a.ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return a.printRawJSON(data)
+}
+
+func saveDashboard(a *app, args []string) error {
+	flags := a.newFlagSet("dashboard save")
+	overwrite := flags.Bool("overwrite", false, "overwrite existing dashboard with the same title")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	return a.saveDashboardFile(flags.Arg(0), *overwrite, nil)
+}
+
+func searchDashboards(a *app, args []string) error {
+	flags := a.newFlagSet("dashboard search")
+	opt := searchFlags(flags)
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	hits, err := a.client.Dashboards.Search(a.ctx, opt)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSLUG\tTITLE\tTAGS")
+	for _, hit := range hits {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", hit.ID, hitSlug(hit), hit.Title, strings.Join(hit.Tags, ","))
+	}
+	return w.Flush()
+}
+
+func deleteDashboard(a *app, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	if err := a.client.Dashboards.Delete(a.ctx, args[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "deleted %s\n", args[0])
+	return nil
+}
+
+func exportDashboards(a *app, args []string) error {
+	flags := a.newFlagSet("dashboard export")
+	dir := flags.String("dir", ".", "directory to write dashboards into")
+	opt := searchFlags(flags)
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	hits, err := a.client.Dashboards.Search(a.ctx, opt)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+
+	for _, hit := range hits {
+		slug := hitSlug(hit)
+		if slug == "" {
+			continue // folders and other non-dashboard hits
+		}
+
+		data, _, err := a.client.Dashboards.GetJSON(a.ctx, slug)
+		if err != nil {
+			return fmt.Errorf("%s: %s", slug, err)
+		}
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return fmt.Errorf("%s: %s", slug, err)
+		}
+
+		path := filepath.Join(*dir, slug+".json")
+		if err := ioutil.WriteFile(path, append(buf.Bytes(), '\n'), 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "exported %s\n", path)
+	}
+	return nil
+}
+
+func importDashboards(a *app, args []string) error {
+	flags := a.newFlagSet("dashboard import")
+	overwrite := flags.Bool("overwrite", false, "overwrite existing dashboards with the same titles")
+	inputs := make(mappingFlag)
+	flags.Var(inputs, "input", "bind input of shared dashboards, ie. DS_PROMETHEUS=Prometheus")
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		return errUsage
+	}
+
+	for _, path := range flags.Args() {
+		if err := a.saveDashboardFile(path, *overwrite, inputs); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	return nil
+}
+
+// saveDashboardFile saves dashboard from JSON file with given path. Stdin is read if path is "-". Dashboards with
+// inputs are imported with given values of the inputs.
+func (a *app) saveDashboardFile(path string, overwrite bool, inputs map[string]string) error {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = ioutil.ReadAll(a.stdin)
+	} else {
+		data, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	var header struct {
+		Title  string            `json:"title"`
+		Inputs []json.RawMessage `json:"__inputs"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return err
+	}
+
+	// File is saved as is, so fields which aren't supported by grafana.Dashboard are not lost
+	if len(header.Inputs) > 0 {
+		if _, err := a.client.Dashboards.ImportJSON(a.ctx, data, inputs, 0, overwrite); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "imported %q\n", header.Title)
+		return nil
+	}
+	result, err := a.client.Dashboards.SaveJSON(a.ctx, data, 0, overwrite)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "saved %q version %d\n", header.Title, result.Version)
+	return nil
+}
+
+func shareDashboard(a *app, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	registry, err := a.client.Datasources.Registry(a.ctx)
+	if err != nil {
+		return err
+	}
+	data, _, err := a.client.Dashboards.GetJSON(a.ctx, args[0])
+	if err != nil {
+		return err
+	}
+	shared, err := grafana.ExportForSharing(data, registry)
+	if err != nil {
+		return err
+	}
+	return a.printRawJSON(shared)
+}
+
+// searchFlags adds flags of dashboard search into given flag set.
+func searchFlags(flags *flag.FlagSet) *client.DashboardSearchOptions {
+	opt := new(client.DashboardSearchOptions)
+	flags.StringVar(&opt.Query, "query", "", "search by title")
+	flags.Var((*stringsFlag)(&opt.Tags), "tag", "search by tag, may be given several times")
+	flags.BoolVar(&opt.IsStarred, "starred", false, "search only starred dashboards")
+	flags.IntVar(&opt.Limit, "limit", 0, "maximum number of found dashboards")
+	return opt
+}
+
+// hitSlug returns slug of found dashboard, ie. "my-dashboard" of "db/my-dashboard" URI.
+func hitSlug(hit *client.DashboardHit) string {
+	if !strings.HasPrefix(hit.URI, "db/") {
+		return ""
+	}
+	return strings.TrimPrefix(hit.URI, "db/")
+}
+
+// printRawJSON prints given JSON indented into stdout.
+func (a *app) printRawJSON(data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(a.stdout, "%s\n", buf.Bytes())
+	return err
+}
+
+// printJSON prints indented JSON of given value into stdout.
+func (a *app) printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(a.stdout, "%s\n", data)
+	return err
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+func listDatasources(a *app, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	datasources, err := a.client.Datasources.GetAll(a.ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tTYPE\tURL\tDEFAULT")
+	for _, ds := range datasources {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\n", ds.ID(), ds.Name, ds.Type, ds.URL, ds.IsDefault)
+	}
+	return w.Flush()
+}
+
+func getDatasource(a *app, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	var ds *grafana.Datasource
+	var err error
+	if id, parseErr := strconv.ParseUint(args[0], 10, 64); parseErr == nil {
+		ds, err = a.client.Datasources.GetByID(a.ctx, grafana.DatasourceID(id))
+	} else {
+		ds, err = a.client.Datasources.GetByName(a.ctx, args[0])
+	}
+	if err != nil {
+		return err
+	}
+	return a.printJSON(ds)
+}
+
+func listContexts(a *app, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CURRENT\tNAME\tURL")
+	for _, name := range a.config.Names() {
+		current := ""
+		if name == a.config.Current {
+			current = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", current, name, a.config.Contexts[name].URL)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/diff"
+)
+
+// livePrefix is a prefix of diff arguments which refer to dashboards of Grafana instead of files
+const livePrefix = "grafana:"
+
+func diffDashboards(a *app, args []string) error {
+	flags := a.newFlagSet("diff")
+	formatName := flags.String("format", string(diff.TextFormat), "format of the report: text, unified or json")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		return errUsage
+	}
+	format, err := diff.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	var docs [2][]byte
+	for i, arg := range flags.Args() {
+		if docs[i], err = a.readDashboard(arg); err != nil {
+			return err
+		}
+	}
+
+	report, err := diff.JSON(docs[0], docs[1])
+	if err != nil {
+		return err
+	}
+	report.OldName, report.NewName = flags.Arg(0), flags.Arg(1)
+	return report.Write(a.stdout, format)
+}
+
+// readDashboard reads JSON of dashboard from file or from Grafana if the argument is grafana:<slug>. Dashboards are
+// decoded and encoded back, so both sides of diff have the same form.
+func (a *app) readDashboard(arg string) ([]byte, error) {
+	if !strings.HasPrefix(arg, livePrefix) {
+		data, err := ioutil.ReadFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		d, err := grafana.UnmarshalDashboard(data, nil)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(d)
+	}
+
+	if err := a.connect(); err != nil {
+		return nil, err
+	}
+	d, err := a.client.Dashboards.Get(a.ctx, strings.TrimPrefix(arg, livePrefix))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(d)
+}
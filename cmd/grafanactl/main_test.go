@@ -0,0 +1,276 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runWithServer runs grafanactl with given arguments against test server and returns its exit code and output.
+func runWithServer(t *testing.T, mux *http.ServeMux, stdin string, args ...string) (int, string, string) {
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-config", os.DevNull, "-url", server.URL, "-token", "token"}, args...)
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_DashboardSearch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization header is invalid: %s", r.Header.Get("Authorization"))
+		}
+		if got := r.URL.Query()["tags"]; len(got) != 2 || got[0] != "prod" || got[1] != "api" {
+			t.Errorf("dashboard search: unexpected tags %v", got)
+		}
+		fmt.Fprint(w, `[{"id": 1, "title": "API", "uri": "db/api", "tags": ["prod", "api"]}]`)
+	})
+
+	code, stdout, stderr := runWithServer(t, mux, "", "dashboard", "search", "-tag", "prod", "-tag", "api")
+	if code != 0 {
+		t.Fatalf("dashboard search exited with %d: %s", code, stderr)
+	}
+	expected := "ID  SLUG  TITLE  TAGS\n1   api   API    prod,api\n"
+	if stdout != expected {
+		t.Errorf("dashboard search: expected output\n%s\ngot\n%s", expected, stdout)
+	}
+}
+
+func TestRun_DashboardGetAndDelete(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/db/api", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			fmt.Fprint(w, `{"dashboard": {"id": 1, "title": "API", "schemaVersion": 16}}`)
+		case "DELETE":
+			fmt.Fprint(w, `{"title": "API"}`)
+		}
+	})
+
+	code, stdout, stderr := runWithServer(t, mux, "", "dashboard", "get", "api")
+	if code != 0 {
+		t.Fatalf("dashboard get exited with %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `"title": "API"`) {
+		t.Errorf("dashboard get: unexpected output %s", stdout)
+	}
+
+	code, stdout, stderr = runWithServer(t, mux, "", "dashboard", "delete", "api")
+	if code != 0 || stdout != "deleted api\n" {
+		t.Errorf("dashboard delete exited with %d: %s%s", code, stdout, stderr)
+	}
+
+	code, _, stderr = runWithServer(t, mux, "", "dashboard", "delete", "missing")
+	if code != 1 || !strings.Contains(stderr, "Dashboard not found") {
+		t.Errorf("dashboard delete of missing dashboard exited with %d: %s", code, stderr)
+	}
+}
+
+func TestRun_DashboardExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grafanactl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var saved []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": 1, "title": "API", "uri": "db/api"}, {"id": 2, "title": "Folder", "uri": ""}]`)
+	})
+	mux.HandleFunc("/api/dashboards/db/api", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"dashboard": {"id": 1, "uid": "api", "title": "API", "version": 3, "schemaVersion": 16, `+
+			`"panels": [{"id": 2, "type": "worldmap-panel"}]}}`)
+	})
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		saved = append(saved, string(data))
+		fmt.Fprint(w, `{"slug": "api", "version": 3, "status": "success"}`)
+	})
+
+	code, stdout, stderr := runWithServer(t, mux, "", "dashboard", "export", "-dir", dir)
+	if code != 0 {
+		t.Fatalf("dashboard export exited with %d: %s", code, stderr)
+	}
+	path := filepath.Join(dir, "api.json")
+	if stdout != "exported "+path+"\n" {
+		t.Errorf("dashboard export: unexpected output %s", stdout)
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || !strings.Contains(string(data), `"type": "worldmap-panel"`) {
+		t.Errorf("dashboard export: unexpected file %s: %v", data, err)
+	}
+
+	code, stdout, stderr = runWithServer(t, mux, "", "dashboard", "import", "-overwrite", path)
+	if code != 0 {
+		t.Fatalf("dashboard import exited with %d: %s", code, stderr)
+	}
+	if stdout != "saved \"API\" version 3\n" {
+		t.Errorf("dashboard import: unexpected output %s", stdout)
+	}
+	if len(saved) != 1 || !strings.Contains(saved[0], `"overwrite":true`) || !strings.Contains(saved[0], `"title":"API"`) ||
+		!strings.Contains(saved[0], `"uid":"api"`) || !strings.Contains(saved[0], `"type":"worldmap-panel"`) {
+		t.Errorf("dashboard import: unexpected request %v", saved)
+	}
+
+	code, _, stderr = runWithServer(t, mux, `{"title": "API"}`, "dashboard", "save", "-")
+	if code != 0 || len(saved) != 2 || !strings.Contains(saved[1], `"overwrite":false`) {
+		t.Errorf("dashboard save from stdin exited with %d: %s", code, stderr)
+	}
+}
+
+func TestRun_DashboardImportShared(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grafanactl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "node.json")
+	data := `{"title": "Node", "schemaVersion": 16, "__inputs": [{"name": "DS_PROM", "type": "datasource", "pluginId": "prometheus"}]}`
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var imported string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/import", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		imported = string(data)
+		fmt.Fprint(w, `{"title": "Node", "imported": true, "slug": "node"}`)
+	})
+	mux.HandleFunc("/api/dashboards/db/node", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"dashboard": {"id": 1, "title": "Node", "schemaVersion": 16}}`)
+	})
+
+	code, _, stderr := runWithServer(t, mux, "", "dashboard", "import", path)
+	if code != 1 || !strings.Contains(stderr, `"DS_PROM" is not given`) {
+		t.Errorf("dashboard import without inputs exited with %d: %s", code, stderr)
+	}
+
+	code, stdout, stderr := runWithServer(t, mux, "", "dashboard", "import", "-input", "DS_PROM=Prometheus", path)
+	if code != 0 {
+		t.Fatalf("dashboard import exited with %d: %s", code, stderr)
+	}
+	if stdout != "imported \"Node\"\n" || !strings.Contains(imported, `"name":"DS_PROM","type":"datasource","pluginId":"prometheus","value":"Prometheus"`) {
+		t.Errorf("dashboard import: unexpected output %q and request %s", stdout, imported)
+	}
+}
+
+func TestRun_Datasources(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": 1, "name": "Prometheus", "type": "prometheus", "url": "http://prometheus:9090", "isDefault": true}]`)
+	})
+	mux.HandleFunc("/api/datasources/1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "name": "Prometheus", "type": "prometheus"}`)
+	})
+	mux.HandleFunc("/api/datasources/name/Prometheus", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "name": "Prometheus", "type": "prometheus"}`)
+	})
+
+	code, stdout, stderr := runWithServer(t, mux, "", "datasource", "list")
+	if code != 0 {
+		t.Fatalf("datasource list exited with %d: %s", code, stderr)
+	}
+	expected := "ID  NAME        TYPE        URL                     DEFAULT\n" +
+		"1   Prometheus  prometheus  http://prometheus:9090  true\n"
+	if stdout != expected {
+		t.Errorf("datasource list: expected output\n%s\ngot\n%s", expected, stdout)
+	}
+
+	for _, arg := range []string{"1", "Prometheus"} {
+		code, stdout, stderr = runWithServer(t, mux, "", "datasource", "get", arg)
+		if code != 0 || !strings.Contains(stdout, `"name": "Prometheus"`) {
+			t.Errorf("datasource get %s exited with %d: %s%s", arg, code, stdout, stderr)
+		}
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	ts := []struct {
+		args []string
+		code int
+	}{
+		{nil, 2},
+		{[]string{"dashboard", "rename"}, 2},
+		{[]string{"-url", "http://localhost/", "dashboard", "get"}, 2},
+		{[]string{"contexts"}, 0},
+		{[]string{"dashboard", "get", "api"}, 1}, // URL is not set
+	}
+
+	os.Unsetenv(envURL)
+	for _, tt := range ts {
+		var stdout, stderr bytes.Buffer
+		if code := run(append([]string{"-config", os.DevNull}, tt.args...), nil, &stdout, &stderr); code != tt.code {
+			t.Errorf("run(%v): expected exit code %d, got %d: %s", tt.args, tt.code, code, stderr.String())
+		}
+	}
+}
+
+func TestRun_Diff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grafanactl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "api.json")
+	if err := ioutil.WriteFile(path, []byte(`{"title": "API", "schemaVersion": 16, "tags": ["prod"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/db/api", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"dashboard": {"id": 1, "title": "API", "schemaVersion": 16, "tags": []}}`)
+	})
+
+	code, stdout, stderr := runWithServer(t, mux, "", "diff", "grafana:api", path)
+	if code != 0 {
+		t.Fatalf("diff exited with %d: %s", code, stderr)
+	}
+	if stdout != "+ tags[0]: \"prod\"\n" {
+		t.Errorf("diff: unexpected output %q", stdout)
+	}
+
+	code, _, stderr = runWithServer(t, mux, "", "diff", "-format", "yaml", path, path)
+	if code != 1 || !strings.Contains(stderr, `unknown format "yaml"`) {
+		t.Errorf("diff with unknown format exited with %d: %s", code, stderr)
+	}
+}
+
+func TestMappingFlag(t *testing.T) {
+	f := make(mappingFlag)
+	if err := f.Set("Prometheus-staging=Prometheus"); err != nil {
+		t.Fatalf("Set returned error: %v", err)
+	}
+	if f["Prometheus-staging"] != "Prometheus" {
+		t.Errorf("Set: unexpected mapping %v", f)
+	}
+	for _, value := range []string{"Prometheus", "=Prometheus", "Prometheus="} {
+		if err := f.Set(value); err == nil {
+			t.Errorf("Set(%q): expected error", value)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spoof/go-grafana/transfer"
+)
+
+func migrateDashboards(a *app, args []string) error {
+	flags := a.newFlagSet("migrate")
+	to := flags.String("to", "", "context of the target Grafana")
+	mapping := make(mappingFlag)
+	flags.Var(mapping, "map", "map source datasource to target one, ie. Prometheus-staging=Prometheus")
+	folders := flags.Bool("folders", false, "copy folders of dashboards")
+	overwrite := flags.Bool("overwrite", false, "overwrite dashboards existing on the target")
+	opt := searchFlags(flags)
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 || *to == "" {
+		return errUsage
+	}
+
+	target, err := Options{Context: *to}.client(a.config)
+	if err != nil {
+		return err
+	}
+	tr := transfer.NewTransfer(a.client, target)
+	tr.Datasources = mapping
+	tr.Folders = *folders
+	tr.Overwrite = *overwrite
+	copied, err := tr.Run(a.ctx, opt)
+	if err != nil {
+		return err
+	}
+	for _, d := range copied {
+		fmt.Fprintf(a.stdout, "Copied %q.\n", d.Title)
+	}
+	return nil
+}
+
+// mappingFlag is a flag of name=value pairs which may be given several times
+type mappingFlag map[string]string
+
+func (f mappingFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f mappingFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("%q should be in form source=target", value)
+	}
+	f[value[:i]] = value[i+1:]
+	return nil
+}
@@ -0,0 +1,30 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+// AnnotationID represents id type of annotation
+type AnnotationID uint
+
+// Annotation represents annotation of dashboards' graphs. Annotations without dashboard id are organization wide
+// and shown by dashboards querying them by tags.
+type Annotation struct {
+	ID          AnnotationID `json:"id"`
+	AlertID     uint         `json:"alertId,omitempty"` // set for annotations of alert state changes
+	DashboardID DashboardID  `json:"dashboardId"`
+	PanelID     uint         `json:"panelId"`
+	Time        int64        `json:"time"` // epoch in milliseconds
+	TimeEnd     int64        `json:"timeEnd,omitempty"`
+	Tags        []string     `json:"tags"`
+	Text        string       `json:"text"`
+}
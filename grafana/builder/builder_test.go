@@ -0,0 +1,193 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/builder"
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/grafana/query"
+)
+
+func TestDashboardBuilder_Build(t *testing.T) {
+	d, err := builder.Dashboard("API").
+		Tags("api", "prod").
+		Datasource("Prometheus").
+		Refresh("1m").
+		QueryVariable("instance", "label_values(up, instance)").
+		Row("Requests",
+			builder.Graph("Rate").Prom("sum(rate(requests[5m]))").Legend("rps").Prom("up").Unit("percent").Span(6),
+			builder.Singlestat("Errors").Datasource("$ds").Prom("errors").Thresholds(1, 5.5),
+		).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: unexpected error %s", err)
+	}
+
+	if got := d.Tags.Value(); !reflect.DeepEqual(got, []string{"api", "prod"}) {
+		t.Errorf("Build: tags %v", got)
+	}
+	if v := d.Variables[0].(*grafana.QueryVariable); v.Datasource.Name != "Prometheus" {
+		t.Errorf("Build: datasource of variable %q", v.Datasource.Name)
+	}
+	if len(d.Rows) != 1 || len(d.Rows[0].Panels) != 2 {
+		t.Fatalf("Build: unexpected rows %#v", d.Rows)
+	}
+
+	row := d.Rows[0]
+	if !row.ShowTitle || row.Title != "Requests" {
+		t.Errorf("Build: row title %q shown %v", row.Title, row.ShowTitle)
+	}
+
+	graph := row.Panels[0].(*panel.Graph)
+	if graph.GeneralOptions().Span != 6 || graph.YAxes.Left.Format != "percent" {
+		t.Errorf("Build: graph span %d and unit %q", graph.GeneralOptions().Span, graph.YAxes.Left.Format)
+	}
+	queries := *graph.Queries()
+	rate, up := queries[0].(*query.Prometheus), queries[1].(*query.Prometheus)
+	if rate.RefID != "A" || rate.LegendFormat != "rps" || rate.Datasource().Name != "Prometheus" {
+		t.Errorf("Build: unexpected first query %#v", rate)
+	}
+	if up.RefID != "B" || up.LegendFormat != "" {
+		t.Errorf("Build: unexpected second query %#v", up)
+	}
+
+	singlestat := row.Panels[1].(*panel.Singlestat)
+	if singlestat.GeneralOptions().Span != 6 || singlestat.Thresholds != "1,5.5" {
+		t.Errorf("Build: singlestat span %d and thresholds %q", singlestat.GeneralOptions().Span, singlestat.Thresholds)
+	}
+	want := panel.NewDatasourceVariableRef("ds")
+	if ds := (*singlestat.Queries())[0].Datasource(); ds != want {
+		t.Errorf("Build: singlestat datasource %#v, want %#v", ds, want)
+	}
+
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error %s", err)
+	}
+	for _, s := range []string{`"id":1`, `"id":2`, `"refId":"B"`, `"datasource":"$ds"`, `"refresh":"1m"`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("Marshal: %s is missing in %s", s, data)
+		}
+	}
+}
+
+func TestRowBuilder_spans(t *testing.T) {
+	tests := []struct {
+		spans []uint
+		want  []uint
+	}{
+		{[]uint{0}, []uint{12}},
+		{[]uint{0, 0, 0}, []uint{4, 4, 4}},
+		{[]uint{0, 0, 0, 0, 0}, []uint{3, 3, 2, 2, 2}},
+		{[]uint{8, 0}, []uint{8, 4}},
+		{[]uint{6, 0, 0, 0}, []uint{6, 2, 2, 2}},
+		{[]uint{10, 0, 0, 0}, []uint{10, 4, 4, 4}},
+		{[]uint{12, 0}, []uint{12, 12}},
+		{[]uint{4, 4, 6}, []uint{4, 4, 6}},
+		{make([]uint, 13), []uint{2, 2, 2, 2, 2, 1, 1, 2, 2, 2, 2, 2, 2}},
+		{append([]uint{6}, make([]uint, 25)...), []uint{6,
+			2, 2, 2, 1, 1, 1, 1, 1, 1,
+			2, 2, 2, 2, 1, 1, 1, 1,
+			2, 2, 2, 2, 1, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		panels := make([]*builder.PanelBuilder, len(tt.spans))
+		for i, span := range tt.spans {
+			panels[i] = builder.Text("", "").Span(span)
+		}
+		d, err := builder.Dashboard("Spans").Row("", panels...).Build()
+		if err != nil {
+			t.Fatalf("Build of %v: unexpected error %s", tt.spans, err)
+		}
+
+		got := make([]uint, len(tt.spans))
+		for i, p := range d.Rows[0].Panels {
+			got[i] = p.GeneralOptions().Span
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Build of %v: spans %v, want %v", tt.spans, got, tt.want)
+		}
+	}
+}
+
+func TestDashboardBuilder_Build_Reuse(t *testing.T) {
+	graph := builder.Graph("Rate").Prom("up")
+	b := builder.Dashboard("API").Row("Requests", graph)
+	if _, err := b.Build(); err != nil {
+		t.Fatalf("Build: unexpected error %s", err)
+	}
+	if _, err := b.Build(); err == nil || err.Error() != "already built, builders are not reusable" {
+		t.Errorf("Build of built dashboard: got error %v", err)
+	}
+
+	_, err := builder.Dashboard("Other").Row("Requests", graph).Build()
+	if err == nil || err.Error() != "rows[0].panels[0]: already built, builders are not reusable" {
+		t.Errorf("Build with built panel: got error %v", err)
+	}
+}
+
+func TestDashboardBuilder_Build_Errors(t *testing.T) {
+	_, err := builder.Dashboard("").
+		Row("Overview",
+			builder.Text("Readme", "Hello").Unit("percent").Prom("up"),
+			builder.Graph("Rate").Legend("rps").Stat("avg"),
+		).
+		Row("Details", builder.Singlestat("Errors").Span(13).Stat("median")).
+		Build()
+
+	want := "rows[0].panels[0]: unit is not supported by text panel; " +
+		"rows[0].panels[0]: query is not supported by text panel; " +
+		"rows[0].panels[1]: legend should follow a query; " +
+		"rows[0].panels[1]: stat is not supported by graph panel; " +
+		"title: should not be empty; " +
+		"rows[1].panels[0].span: should be between 1 and 12, got 13; " +
+		`rows[1].panels[0].valueName: unknown stat "median"`
+	if err == nil || err.Error() != want {
+		t.Errorf("Build: got error %v, want %s", err, want)
+	}
+}
+
+func TestDashboardBuilder_Grid(t *testing.T) {
+	d, err := builder.Dashboard("Grid").
+		Row("", builder.Graph("A").Span(8), builder.Graph("B")).
+		Rows(builder.Row("Details", builder.Text("C", "")).Height("300px").Collapse()).
+		Grid().
+		Build()
+	if err != nil {
+		t.Fatalf("Build: unexpected error %s", err)
+	}
+
+	var got []panel.GridPos
+	for _, p := range d.Panels {
+		got = append(got, *p.GeneralOptions().GridPos)
+	}
+	want := []panel.GridPos{
+		{X: 0, Y: 0, W: 24, H: 1},
+		{X: 0, Y: 1, W: 16, H: 7},
+		{X: 16, Y: 1, W: 8, H: 7},
+		{X: 0, Y: 8, W: 24, H: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build: grid positions %v, want %v", got, want)
+	}
+	if rp := d.Panels[3].(*grafana.RowPanel); len(rp.Panels) != 1 || !rp.Collapsed {
+		t.Errorf("Build: unexpected collapsed row %#v", rp)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package builder provides chainable API to build dashboards in Go:
+//
+//	d, err := builder.Dashboard("API").
+//		Tags("api", "prod").
+//		Datasource("Prometheus").
+//		QueryVariable("instance", "label_values(up{job=\"api\"}, instance)").
+//		Row("Requests",
+//			builder.Graph("Rate").Prom(`sum(rate(http_requests_total[5m]))`).Legend("rps").Unit("reqps").Span(8),
+//			builder.Singlestat("Errors").Prom(`sum(rate(http_errors_total[5m]))`).Unit("percentunit"),
+//		).
+//		Build()
+//
+// Builders fill the same grafana.Dashboard values as the rest of the library does, but take care of the details:
+// panels without span share the rest of the row, queries get refIds and use datasource of their panel or dashboard,
+// panel ids are assigned when dashboard is saved. Options not supported by the panel and invalid values are reported
+// by Build at once with paths of invalid fields. Builders fill their values in place, so they are not reusable: every
+// Build should use new ones and building the same dashboard, row or panel twice is reported as an error.
+package builder
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/field"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// DashboardBuilder builds a dashboard.
+type DashboardBuilder struct {
+	dashboard  *grafana.Dashboard
+	datasource string
+	rows       []*RowBuilder
+	grid       bool
+	built      bool
+	errs       validate.Errors
+}
+
+// Dashboard starts building a dashboard with given title.
+func Dashboard(title string) *DashboardBuilder {
+	return &DashboardBuilder{dashboard: grafana.NewDashboard(title)}
+}
+
+// Tags adds tags to the dashboard.
+func (b *DashboardBuilder) Tags(tags ...string) *DashboardBuilder {
+	b.dashboard.Tags.Add(tags...)
+	return b
+}
+
+// Datasource sets datasource of all panels and query variables which don't set their own ones. Datasource variable
+// is referenced by its name, ie. "$ds".
+func (b *DashboardBuilder) Datasource(name string) *DashboardBuilder {
+	b.datasource = name
+	return b
+}
+
+// Time sets default time range of the dashboard, ie. "now-6h" to "now".
+func (b *DashboardBuilder) Time(from, to string) *DashboardBuilder {
+	b.dashboard.Time = grafana.NewTimeRange(from, to)
+	return b
+}
+
+// Refresh sets auto-refresh interval of the dashboard, ie. "1m".
+func (b *DashboardBuilder) Refresh(interval string) *DashboardBuilder {
+	b.dashboard.Refresh = grafana.RefreshInterval(interval)
+	return b
+}
+
+// Timezone sets timezone of the dashboard: "browser", "utc" or empty for user's default.
+func (b *DashboardBuilder) Timezone(timezone string) *DashboardBuilder {
+	b.dashboard.Timezone = timezone
+	return b
+}
+
+// Link adds link to the dashboard's header.
+func (b *DashboardBuilder) Link(l *grafana.DashboardLink) *DashboardBuilder {
+	b.dashboard.Links = append(b.dashboard.Links, *l)
+	return b
+}
+
+// Variable adds given variable to the dashboard. Query and ad hoc variables referencing the default datasource get
+// datasource of the dashboard.
+func (b *DashboardBuilder) Variable(v grafana.Variable) *DashboardBuilder {
+	b.dashboard.Variables = append(b.dashboard.Variables, v)
+	return b
+}
+
+// QueryVariable adds variable with given name whose values are returned by the query to datasource of the
+// dashboard. Values are refreshed on dashboard load.
+func (b *DashboardBuilder) QueryVariable(name, query string) *DashboardBuilder {
+	v := grafana.NewQueryVar(name)
+	v.Query = query
+	v.Refresh = grafana.RefreshOnDashboardLoad
+	return b.Variable(v)
+}
+
+// CustomVariable adds variable with given name and values. The first value is selected.
+func (b *DashboardBuilder) CustomVariable(name string, values ...string) *DashboardBuilder {
+	v := &grafana.CustomVariable{Query: strings.Join(values, ",")}
+	v.Name = name
+	for i, value := range values {
+		v.Options = append(v.Options, grafana.VariableOption{Selected: i == 0, Text: value, Value: value})
+	}
+	if len(values) > 0 {
+		v.Current = grafana.NewVariableCurrent(values[0])
+	}
+	return b.Variable(v)
+}
+
+// DatasourceVariable adds variable with given name to select one of datasources of given plugin type, ie.
+// "prometheus". Set dashboard's datasource to "$name" to make panels use the selected one.
+func (b *DashboardBuilder) DatasourceVariable(name, datasourceType string) *DashboardBuilder {
+	v := &grafana.DatasourceVariable{Query: datasourceType, Refresh: grafana.RefreshOnDashboardLoad}
+	v.Name = name
+	return b.Variable(v)
+}
+
+// Row adds row with given title and panels. Empty title hides the row's title.
+func (b *DashboardBuilder) Row(title string, panels ...*PanelBuilder) *DashboardBuilder {
+	return b.Rows(Row(title, panels...))
+}
+
+// Rows adds given rows to the dashboard.
+func (b *DashboardBuilder) Rows(rows ...*RowBuilder) *DashboardBuilder {
+	b.rows = append(b.rows, rows...)
+	return b
+}
+
+// Panel returns builder of already added panel with given title, ie. to override its options for a single
+// dashboard built by Template. Missing panel is reported by Build.
+func (b *DashboardBuilder) Panel(title string) *PanelBuilder {
+	for _, r := range b.rows {
+		for _, p := range r.panels {
+			if p.panel.GeneralOptions().Title == title {
+				return p
+			}
+		}
+	}
+	b.errs.Addf("", "panel %q is not found", title)
+	return Text(title, "")
+}
+
+// Grid makes the dashboard use grid layout (schema version 16+). Rows are converted to grid the same way as
+// Grafana does it, see grafana.Dashboard.ConvertRowsToGrid.
+func (b *DashboardBuilder) Grid() *DashboardBuilder {
+	b.grid = true
+	return b
+}
+
+// Build returns built dashboard. All options not supported by panels and invalid values are returned as
+// validate.Errors.
+func (b *DashboardBuilder) Build() (*grafana.Dashboard, error) {
+	if b.built {
+		return nil, errAlreadyBuilt
+	}
+	b.built = true
+
+	d := b.dashboard
+	errs := b.errs
+	for i, r := range b.rows {
+		row, err := r.build(b.datasource)
+		errs.Add(validate.Index("rows", i), err)
+		if row != nil {
+			d.Rows = append(d.Rows, row)
+		}
+	}
+
+	datasource := datasourceRef(b.datasource)
+	for _, v := range d.Variables {
+		switch v := v.(type) {
+		case *grafana.QueryVariable:
+			if v.Datasource.IsDefault() {
+				v.Datasource = datasource
+			}
+		case *grafana.AdHocVariable:
+			if v.Datasource.IsDefault() {
+				v.Datasource = datasource
+			}
+		}
+	}
+
+	errs.Add("", d.Validate())
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	if b.grid {
+		d.ConvertRowsToGrid()
+	}
+	return d, nil
+}
+
+// RowBuilder builds a row of dashboard.
+type RowBuilder struct {
+	row    *grafana.Row
+	panels []*PanelBuilder
+	built  bool
+}
+
+// Row starts building a row with given title and panels. Empty title hides the row's title.
+func Row(title string, panels ...*PanelBuilder) *RowBuilder {
+	row := grafana.NewRow()
+	row.Title = title
+	row.ShowTitle = title != ""
+	row.Height = "250px"
+	return &RowBuilder{row: row, panels: panels}
+}
+
+// Panels adds given panels to the row.
+func (b *RowBuilder) Panels(panels ...*PanelBuilder) *RowBuilder {
+	b.panels = append(b.panels, panels...)
+	return b
+}
+
+// Height sets height of the row's panels, ie. "300px".
+func (b *RowBuilder) Height(height string) *RowBuilder {
+	b.row.Height = field.ForceString(height)
+	return b
+}
+
+// Collapse makes the row collapsed.
+func (b *RowBuilder) Collapse() *RowBuilder {
+	b.row.Collapsed = true
+	return b
+}
+
+// Repeat repeats the row for every selected value of variable with given name.
+func (b *RowBuilder) Repeat(variable string) *RowBuilder {
+	b.row.RepeatFor = variable
+	return b
+}
+
+// rowSpans is width of row in spans.
+const rowSpans = 12
+
+// build builds the row with panels using given datasource by default. Panels without span share the rest of the
+// last line of the row equally or the whole new lines if there is not enough space left, see autoSpans.
+func (b *RowBuilder) build(datasource string) (*grafana.Row, error) {
+	if b.built {
+		return nil, errAlreadyBuilt
+	}
+	b.built = true
+
+	var used, auto uint
+	for _, p := range b.panels {
+		if p.span == 0 {
+			auto++
+		}
+		used += p.span
+	}
+	spans := autoSpans(rowSpans-used%rowSpans, auto)
+
+	var errs validate.Errors
+	for i, p := range b.panels {
+		span := p.span
+		if span == 0 {
+			span, spans = spans[0], spans[1:]
+		}
+		panel, err := p.build(datasource, span)
+		errs.Add(validate.Index("panels", i), err)
+		if panel != nil {
+			b.row.Panels = append(b.row.Panels, panel)
+		}
+	}
+	return b.row, errs.Err()
+}
+
+// autoSpans returns spans of given number of panels without span. The panels share free spans of the last line
+// equally. If there are more panels than free spans, they are wrapped to new lines with the same number of panels
+// up to 12 each, so every panel gets at least one span.
+func autoSpans(free, panels uint) []uint {
+	if panels == 0 {
+		return nil
+	}
+	lines := uint(1)
+	if panels > free {
+		free = rowSpans
+		lines = (panels + rowSpans - 1) / rowSpans
+	}
+
+	spans := make([]uint, 0, panels)
+	for line := uint(0); line < lines; line++ {
+		n := panels / lines
+		if line < panels%lines {
+			n++
+		}
+		for i := uint(0); i < n; i++ {
+			span := free / n
+			if i < free%n {
+				span++
+			}
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// errAlreadyBuilt is returned by Build of builders used twice.
+var errAlreadyBuilt = errors.New("already built, builders are not reusable")
+
+// datasourceRef returns reference to datasource with given name. Names starting with "$" reference datasource
+// variables.
+func datasourceRef(name string) panel.DatasourceRef {
+	ref := panel.NewDatasourceRef(name)
+	if variable, ok := ref.Variable(); ok {
+		return panel.NewDatasourceVariableRef(variable)
+	}
+	return ref
+}
@@ -0,0 +1,320 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"strconv"
+
+	"github.com/guregu/null"
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/grafana/query"
+	"github.com/spoof/go-grafana/pkg/field"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// PanelBuilder builds a panel of dashboard. Options are applied to the panels supporting them, the rest of panels
+// report errors on Build.
+type PanelBuilder struct {
+	panel         grafana.Panel
+	span          uint
+	datasource    string
+	hasDatasource bool
+	built         bool
+	errs          validate.Errors
+}
+
+// Graph starts building Graph panel with given title. The panel draws lines and shows legend and both Y axes.
+func Graph(title string) *PanelBuilder {
+	p := panel.NewGraph()
+	p.Lines = true
+	p.LineWidth = 1
+	p.Fill = 1
+	p.NullValue = panel.NullNullPointMode
+	p.XAxis.Show = true
+	p.XAxis.Mode = "time"
+	p.XAxis.Values = []string{}
+	p.YAxes.Left = panel.GraphYAxis{Format: "short", LogBase: 1, Show: true}
+	p.YAxes.Right = panel.GraphYAxis{Format: "short", LogBase: 1, Show: true}
+	p.Legend.Show = true
+	p.Tooltip.Shared = true
+	p.Tooltip.StackedValue = panel.Individual
+	return Panel(p).Title(title)
+}
+
+// Singlestat starts building Singlestat panel with given title. The panel shows average value.
+func Singlestat(title string) *PanelBuilder {
+	p := panel.NewSinglestat()
+	p.ValueName = "avg"
+	p.Format = "none"
+	p.ValueFontSize = "80%"
+	p.PrefixFontSize = "50%"
+	p.PostfixFontSize = "50%"
+	p.Colors = []string{"#299c46", "rgba(237, 129, 40, 0.89)", "#d44a3a"}
+	p.Gauge.MaxValue = 100
+	p.Gauge.ThresholdMarkers = true
+	return Panel(p).Title(title)
+}
+
+// Text starts building Text panel with given title and markdown content.
+func Text(title, content string) *PanelBuilder {
+	p := panel.NewText(panel.TextPanelMarkdownMode)
+	p.Content = content
+	return Panel(p).Title(title)
+}
+
+// Panel starts building given panel, ie. panel of custom type.
+func Panel(p grafana.Panel) *PanelBuilder {
+	return &PanelBuilder{panel: p}
+}
+
+// Title sets title of the panel.
+func (b *PanelBuilder) Title(title string) *PanelBuilder {
+	b.panel.GeneralOptions().Title = title
+	return b
+}
+
+// Description sets description of the panel shown in its tooltip.
+func (b *PanelBuilder) Description(description string) *PanelBuilder {
+	b.panel.GeneralOptions().Description = description
+	return b
+}
+
+// Span sets width of the panel in spans from 1 to 12. Panels without span share the rest of their row.
+func (b *PanelBuilder) Span(span uint) *PanelBuilder {
+	b.span = span
+	return b
+}
+
+// Height sets height of the panel overriding height of its row, ie. "300px".
+func (b *PanelBuilder) Height(height string) *PanelBuilder {
+	b.panel.GeneralOptions().Height = field.ForceString(height)
+	return b
+}
+
+// Transparent makes background of the panel transparent.
+func (b *PanelBuilder) Transparent() *PanelBuilder {
+	b.panel.GeneralOptions().Transparent = true
+	return b
+}
+
+// Link adds link to given URL to the panel.
+func (b *PanelBuilder) Link(title, url string) *PanelBuilder {
+	l := panel.NewPanelLink(panel.PanelLinkAbsolute)
+	l.Title = title
+	l.URL = url
+	opts := b.panel.GeneralOptions()
+	opts.Links = append(opts.Links, *l)
+	return b
+}
+
+// Datasource sets datasource of the panel's queries overriding datasource of dashboard. Datasource variable is
+// referenced by its name, ie. "$ds".
+func (b *PanelBuilder) Datasource(name string) *PanelBuilder {
+	b.datasource = name
+	b.hasDatasource = true
+	return b
+}
+
+// Query adds given query to the panel. Query referencing the default datasource gets datasource of the panel.
+func (b *PanelBuilder) Query(q panel.Query) *PanelBuilder {
+	qp, ok := b.panel.(grafana.QueryablePanel)
+	if !ok {
+		b.unsupported("query")
+		return b
+	}
+	queries := qp.Queries()
+	*queries = append(*queries, q)
+	return b
+}
+
+// Prom adds Prometheus query with given PromQL expression to the panel.
+func (b *PanelBuilder) Prom(expr string) *PanelBuilder {
+	q := query.NewPrometheus("")
+	q.Expression = expr
+	return b.Query(q)
+}
+
+// Loki adds Loki query with given LogQL expression to the panel.
+func (b *PanelBuilder) Loki(expr string) *PanelBuilder {
+	return b.Query(query.NewLoki("", expr))
+}
+
+// Graphite adds Graphite query with given target to the panel.
+func (b *PanelBuilder) Graphite(target string) *PanelBuilder {
+	q := query.NewGraphite("")
+	q.Target = target
+	return b.Query(q)
+}
+
+// Legend sets legend format of the last added Prometheus or Loki query, ie. "{{ instance }}".
+func (b *PanelBuilder) Legend(format string) *PanelBuilder {
+	var last panel.Query
+	if qp, ok := b.panel.(grafana.QueryablePanel); ok {
+		if queries := *qp.Queries(); len(queries) > 0 {
+			last = queries[len(queries)-1]
+		}
+	}
+
+	switch q := last.(type) {
+	case *query.Prometheus:
+		q.LegendFormat = format
+	case *query.Loki:
+		q.LegendFormat = format
+	case nil:
+		b.errs.Addf("", "legend should follow a query")
+	default:
+		b.errs.Addf("", "legend is not supported by the query")
+	}
+	return b
+}
+
+// Unit sets unit of the panel's values, ie. "percent" or "bytes". Unit of Graph panel is set to its left Y axis.
+func (b *PanelBuilder) Unit(unit string) *PanelBuilder {
+	switch p := b.panel.(type) {
+	case *panel.Graph:
+		p.YAxes.Left.Format = unit
+	case *panel.Singlestat:
+		p.Format = unit
+	default:
+		b.unsupported("unit")
+	}
+	return b
+}
+
+// Decimals sets number of decimals of Graph panel's values.
+func (b *PanelBuilder) Decimals(decimals int) *PanelBuilder {
+	if p, ok := b.panel.(*panel.Graph); ok {
+		p.Decimals = null.IntFrom(int64(decimals))
+	} else {
+		b.unsupported("decimals")
+	}
+	return b
+}
+
+// Min sets minimum of left Y axis of Graph panel.
+func (b *PanelBuilder) Min(min float64) *PanelBuilder {
+	if p, ok := b.panel.(*panel.Graph); ok {
+		p.YAxes.Left.Min = formatFloat(min)
+	} else {
+		b.unsupported("min")
+	}
+	return b
+}
+
+// Max sets maximum of left Y axis of Graph panel.
+func (b *PanelBuilder) Max(max float64) *PanelBuilder {
+	if p, ok := b.panel.(*panel.Graph); ok {
+		p.YAxes.Left.Max = formatFloat(max)
+	} else {
+		b.unsupported("max")
+	}
+	return b
+}
+
+// Stack stacks series of Graph panel.
+func (b *PanelBuilder) Stack() *PanelBuilder {
+	if p, ok := b.panel.(*panel.Graph); ok {
+		p.Stack = true
+	} else {
+		b.unsupported("stack")
+	}
+	return b
+}
+
+// Bars makes Graph panel draw bars instead of lines.
+func (b *PanelBuilder) Bars() *PanelBuilder {
+	if p, ok := b.panel.(*panel.Graph); ok {
+		p.Bars = true
+		p.Lines = false
+	} else {
+		b.unsupported("bars")
+	}
+	return b
+}
+
+// Stat sets the value shown by Singlestat panel: "avg", "current", "max", "total", etc.
+func (b *PanelBuilder) Stat(valueName string) *PanelBuilder {
+	if p, ok := b.panel.(*panel.Singlestat); ok {
+		p.ValueName = valueName
+	} else {
+		b.unsupported("stat")
+	}
+	return b
+}
+
+// Thresholds sets warning and critical thresholds of Singlestat panel and colors its value by them.
+func (b *PanelBuilder) Thresholds(warning, critical float64) *PanelBuilder {
+	if p, ok := b.panel.(*panel.Singlestat); ok {
+		p.Thresholds = string(*formatFloat(warning)) + "," + string(*formatFloat(critical))
+		p.ColorValue = true
+	} else {
+		b.unsupported("thresholds")
+	}
+	return b
+}
+
+// Sparkline shows sparkline below value of Singlestat panel.
+func (b *PanelBuilder) Sparkline() *PanelBuilder {
+	if p, ok := b.panel.(*panel.Singlestat); ok {
+		p.SparkLine.Show = true
+		p.SparkLine.LineColor = "rgb(31, 120, 193)"
+		p.SparkLine.FillColor = "rgba(31, 118, 189, 0.18)"
+	} else {
+		b.unsupported("sparkline")
+	}
+	return b
+}
+
+// unsupported reports that given option is not supported by the panel.
+func (b *PanelBuilder) unsupported(option string) {
+	panelType, ok := panel.TypeOf(b.panel)
+	if !ok {
+		panelType = "custom"
+	}
+	b.errs.Addf("", "%s is not supported by %s panel", option, panelType)
+}
+
+// build builds the panel of given span. Queries referencing the default datasource get datasource of the panel or
+// given one if the panel doesn't set it.
+func (b *PanelBuilder) build(datasource string, span uint) (grafana.Panel, error) {
+	if b.built {
+		return nil, errAlreadyBuilt
+	}
+	b.built = true
+
+	b.panel.GeneralOptions().Span = span
+	if b.hasDatasource {
+		datasource = b.datasource
+	}
+
+	if qp, ok := b.panel.(grafana.QueryablePanel); ok {
+		queries := *qp.Queries()
+		ref := datasourceRef(datasource)
+		for _, q := range queries {
+			if qo, ok := q.(interface {
+				Options() *panel.QueryOptions
+			}); ok && q.Datasource().IsDefault() {
+				qo.Options().SetDatasource(ref)
+			}
+		}
+		grafana.AssignRefIDs(queries)
+	}
+	return b.panel, b.errs.Err()
+}
+
+func formatFloat(f float64) *field.ForceString {
+	s := field.ForceString(strconv.FormatFloat(f, 'f', -1, 64))
+	return &s
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"fmt"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+// Component is a reusable part of dashboards: rows, panels, variables, links or options added by the function to
+// the dashboard. Components are parameterised by arguments of functions returning them, ie.
+//
+//	func REDRow(s builder.Service) builder.Component {
+//		return func(b *builder.DashboardBuilder) {
+//			b.Row("RED", builder.Graph("Rate").Prom(`sum(rate(http_requests_total{job="`+s.JobLabel()+`"}[5m]))`))
+//		}
+//	}
+type Component func(b *DashboardBuilder)
+
+// With adds given components to the dashboard in order.
+func (b *DashboardBuilder) With(components ...Component) *DashboardBuilder {
+	for _, c := range components {
+		c(b)
+	}
+	return b
+}
+
+// Service is a service whose dashboard is built by Template.
+type Service struct {
+	Name       string            // ie. "checkout"
+	Job        string            // job label of the service's metrics, the same as name if empty
+	Datasource string            // datasource of the service's metrics overriding datasource of the template
+	Params     map[string]string // custom parameters of the template, see Param
+	Overrides  []Component       // service-specific rows or options added after the template
+}
+
+// JobLabel returns job label of the service's metrics.
+func (s Service) JobLabel() string {
+	if s.Job == "" {
+		return s.Name
+	}
+	return s.Job
+}
+
+// Param returns value of custom parameter with given name or default value if the service doesn't set it.
+func (s Service) Param(name, defaultValue string) string {
+	if v, ok := s.Params[name]; ok {
+		return v
+	}
+	return defaultValue
+}
+
+// Template returns builder of dashboard of given service. Template is called once per service, so it should
+// return new builder every time.
+type Template func(s Service) *DashboardBuilder
+
+// Build builds dashboards of given services. Overrides and datasource of every service are applied after the
+// template.
+func (t Template) Build(services ...Service) ([]*grafana.Dashboard, error) {
+	dashboards := make([]*grafana.Dashboard, len(services))
+	for i, s := range services {
+		b := t(s)
+		if s.Datasource != "" {
+			b.Datasource(s.Datasource)
+		}
+		b.With(s.Overrides...)
+
+		d, err := b.Build()
+		if err != nil {
+			return nil, fmt.Errorf("building dashboard of service %q: %s", s.Name, err)
+		}
+		dashboards[i] = d
+	}
+	return dashboards, nil
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/builder"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// header is a standard header of service dashboards.
+func header(s builder.Service) builder.Component {
+	return func(b *builder.DashboardBuilder) {
+		b.Tags("service", s.Name).
+			Link(grafana.NewTaggedDashboardsDropdown("Services", "service")).
+			QueryVariable("instance", fmt.Sprintf(`label_values(up{job=%q}, instance)`, s.JobLabel()))
+	}
+}
+
+// redRow is a row with rate, errors and duration of requests to the service.
+func redRow(s builder.Service) builder.Component {
+	selector := fmt.Sprintf(`job=%q, instance=~"$instance"`, s.JobLabel())
+	return func(b *builder.DashboardBuilder) {
+		b.Row("RED",
+			builder.Graph("Rate").
+				Prom(`sum(rate(http_requests_total{`+selector+`}[5m]))`).Legend("rps").
+				Unit("reqps"),
+			builder.Singlestat("Errors").
+				Prom(`sum(rate(http_requests_total{`+selector+`, code=~"5.."}[5m])) / sum(rate(http_requests_total{`+selector+`}[5m]))`).
+				Unit("percentunit").Stat("current").
+				Thresholds(param(s, "errors_warning", 0.01), param(s, "errors_critical", 0.05)),
+			builder.Graph("Duration").
+				Prom(`histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket{`+selector+`}[5m])) by (le))`).Legend("p99").
+				Prom(`histogram_quantile(0.5, sum(rate(http_request_duration_seconds_bucket{`+selector+`}[5m])) by (le))`).Legend("p50").
+				Unit("s"),
+		)
+	}
+}
+
+func param(s builder.Service, name string, defaultValue float64) float64 {
+	v, _ := strconv.ParseFloat(s.Param(name, strconv.FormatFloat(defaultValue, 'f', -1, 64)), 64)
+	return v
+}
+
+func serviceDashboard(s builder.Service) *builder.DashboardBuilder {
+	return builder.Dashboard("Service / "+s.Name).
+		Datasource("Prometheus").
+		Refresh("1m").
+		With(header(s), redRow(s))
+}
+
+func TestTemplate_Build(t *testing.T) {
+	services := []builder.Service{
+		{Name: "checkout"},
+		{
+			Name:       "payments",
+			Job:        "payments-api",
+			Datasource: "Prometheus-payments",
+			Params:     map[string]string{"errors_warning": "0.001", "errors_critical": "0.01"},
+			Overrides: []builder.Component{
+				func(b *builder.DashboardBuilder) {
+					b.Panel("Duration").Unit("ms")
+					b.Row("Database", builder.Graph("Connections").Prom(`sum(db_connections{job="payments-api"})`))
+				},
+			},
+		},
+	}
+
+	dashboards, err := builder.Template(serviceDashboard).Build(services...)
+	if err != nil {
+		t.Fatalf("Build: unexpected error %s", err)
+	}
+
+	for i, d := range dashboards {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			t.Fatalf("Marshal: unexpected error %s", err)
+		}
+		data = append(data, '\n')
+
+		golden := filepath.Join("testdata", services[i].Name+".json")
+		if *update {
+			if err := ioutil.WriteFile(golden, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("Build of %q: dashboard differs from %s, run go test -update and check the diff:\n%s",
+				services[i].Name, golden, data)
+		}
+	}
+}
+
+func TestTemplate_Build_Errors(t *testing.T) {
+	override := func(b *builder.DashboardBuilder) {
+		b.Panel("Latency").Unit("ms")
+	}
+	_, err := builder.Template(serviceDashboard).Build(
+		builder.Service{Name: "checkout"},
+		builder.Service{Name: "payments", Overrides: []builder.Component{override}},
+	)
+
+	want := `building dashboard of service "payments": panel "Latency" is not found`
+	if err == nil || err.Error() != want {
+		t.Errorf("Build: got error %v, want %s", err, want)
+	}
+}
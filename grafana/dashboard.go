@@ -15,11 +15,14 @@ package grafana
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/spoof/go-grafana/grafana/panel"
 	panelQuery "github.com/spoof/go-grafana/grafana/query"
 	"github.com/spoof/go-grafana/pkg/field"
+	"github.com/spoof/go-grafana/pkg/grid"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
 )
 
 type (
@@ -33,20 +36,34 @@ const (
 	dashboardLightStyle dashboardStyle = "light"
 )
 
+// gridLayoutSchemaVersion is the first schema version that uses grid layout instead of rows.
+const gridLayoutSchemaVersion = 16
+
 type Dashboard struct {
 	ID            DashboardID `json:"-"`
 	Version       uint64      `json:"-"`
 	SchemaVersion int         `json:"schemaVersion"`
 
-	Editable     bool           `json:"editable"`
-	GraphTooltip uint8          `json:"graphTooltip"`
-	HideControls bool           `json:"hideControls"`
-	Rows         []*Row         `json:"rows"`
-	Style        dashboardStyle `json:"style"`
-	Timezone     string         `json:"timezone"`
-	Title        string         `json:"title"`
-	Tags         *field.Tags    `json:"tags"`
-	Variables    Variables      `json:"templating"`
+	Editable             bool            `json:"editable"`
+	FiscalYearStartMonth uint8           `json:"fiscalYearStartMonth,omitempty"` // 0 is January
+	GraphTooltip         uint8           `json:"graphTooltip"`
+	HideControls         bool            `json:"hideControls"`
+	Links                []DashboardLink `json:"links"`
+	Panels               []Panel         `json:"panels"` // grid layout (schema version 16+)
+	Refresh              RefreshInterval `json:"refresh"`
+	Rows                 []*Row          `json:"rows"` // legacy layout (schema version < 16)
+	Style                dashboardStyle  `json:"style"`
+	Time                 *TimeRange      `json:"time,omitempty"`
+	TimePicker           *TimePicker     `json:"timepicker,omitempty"`
+	Timezone             string          `json:"timezone"`
+	Title                string          `json:"title"`
+	Tags                 *field.Tags     `json:"tags"`
+	Variables            Variables       `json:"templating"`
+	WeekStart            weekStart       `json:"weekStart,omitempty"`
+
+	// Inputs and requirements of dashboards shared on grafana.com or exported for sharing, see ExportForSharing.
+	Inputs   []DashboardInput       `json:"__inputs,omitempty"`
+	Requires []DashboardRequirement `json:"__requires,omitempty"`
 
 	Meta *DashboardMeta `json:"-"`
 }
@@ -59,51 +76,104 @@ func NewDashboard(title string) *Dashboard {
 		SchemaVersion: 14,
 		Style:         dashboardDarkStyle,
 		Tags:          field.NewTags(),
+		Time:          NewTimeRange("now-6h", "now"),
 	}
 }
 
 // MarshalJSON implements json.Marshaler interface
 func (d *Dashboard) MarshalJSON() ([]byte, error) {
-	panelID := 1
-	rows := make([]*Row, len(d.Rows))
-	for i, r := range d.Rows {
-		rr := *r
-
-		panels := make([]Panel, len(r.Panels))
-		for j, p := range r.Panels {
-			panels[j] = &probePanel{
-				ID:    uint(panelID),
-				panel: p,
-			}
-			panelID++
+	panelID := uint(1)
+	var rows *[]*Row
+	if len(d.Rows) > 0 || d.SchemaVersion < gridLayoutSchemaVersion {
+		rr := make([]*Row, len(d.Rows))
+		for i, r := range d.Rows {
+			row := *r
+			row.Panels = wrapPanels(r.Panels, &panelID, d.SchemaVersion)
+			rr[i] = &row
 		}
-		rr.Panels = panels
-		rows[i] = &rr
+		rows = &rr
 	}
 
 	type JSONDashboard Dashboard
 	jd := &struct {
 		JSONDashboard
-		Rows []*Row   `json:"rows"`
-		Tags []string `json:"tags"`
+		Panels    []Panel       `json:"panels,omitempty"`
+		Rows      *[]*Row       `json:"rows,omitempty"`
+		Tags      []string      `json:"tags"`
+		Variables variablesJSON `json:"templating"`
 	}{
 		JSONDashboard: (JSONDashboard)(*d),
+		Panels:        wrapPanels(d.Panels, &panelID, d.SchemaVersion),
 		Rows:          rows,
 		Tags:          d.Tags.Value(),
+		Variables:     variablesJSON{d.Variables, d.SchemaVersion},
 	}
 	return json.Marshal(jd)
 }
 
-// UnmarshalJSON implements json.Unmarshaler interface
+// variablesJSON marshals variables of dashboard with datasources in the form of dashboard's schema version.
+type variablesJSON struct {
+	variables     Variables
+	schemaVersion int
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (v variablesJSON) MarshalJSON() ([]byte, error) {
+	return v.variables.marshalJSON(v.schemaVersion)
+}
+
+// wrapPanels wraps given panels into probePanel and assigns them sequential ids starting with nextID. Datasources of
+// panels are marshaled in the form of given schema version.
+func wrapPanels(panels []Panel, nextID *uint, schemaVersion int) []Panel {
+	if panels == nil {
+		return nil
+	}
+
+	wrapped := make([]Panel, len(panels))
+	for i, p := range panels {
+		if rp, ok := p.(*RowPanel); ok {
+			row := *rp
+			p = &row
+		}
+		pp := &probePanel{ID: *nextID, panel: p, schemaVersion: schemaVersion}
+		*nextID++
+
+		if rp, ok := p.(*RowPanel); ok {
+			rp.Panels = wrapPanels(rp.Panels, nextID, schemaVersion)
+		}
+		wrapped[i] = pp
+	}
+	return wrapped
+}
+
+// UnmarshalDashboard decodes dashboard from given JSON data. Types of panels' queries are resolved by their
+// datasources using given registry. Queries of datasources unknown to the registry are decoded using heuristics
+// based on query fields, the same way as json.Unmarshal does it.
+func UnmarshalDashboard(data []byte, datasources *DatasourceRegistry) (*Dashboard, error) {
+	d := new(Dashboard)
+	if err := d.unmarshalJSON(data, datasources); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface. Only datasource variables are used to resolve types of
+// queries, use UnmarshalDashboard to resolve them by datasources of Grafana.
 func (d *Dashboard) UnmarshalJSON(data []byte) error {
+	return d.unmarshalJSON(data, nil)
+}
+
+func (d *Dashboard) unmarshalJSON(data []byte, datasources *DatasourceRegistry) error {
 	type JSONDashboard Dashboard
 	inDashboard := struct {
 		*JSONDashboard
 		ID      *DashboardID `json:"id"`
 		Version *uint64      `json:"version"`
 
-		Tags []string       `json:"tags"`
-		Meta *DashboardMeta `json:"meta"`
+		Panels []json.RawMessage `json:"panels"`
+		Rows   []json.RawMessage `json:"rows"`
+		Tags   []string          `json:"tags"`
+		Meta   *DashboardMeta    `json:"meta"`
 	}{
 		JSONDashboard: (*JSONDashboard)(d),
 		ID:            &d.ID,
@@ -116,6 +186,24 @@ func (d *Dashboard) UnmarshalJSON(data []byte) error {
 
 	d.Tags = field.NewTags(inDashboard.Tags...)
 
+	// Panels are decoded after variables because queries may use datasource variables
+	r := &queryResolver{datasources: datasources, variables: d.Variables, inputs: d.Inputs}
+	var err error
+	if d.Panels, err = unmarshalPanels(inDashboard.Panels, r); err != nil {
+		return err
+	}
+
+	if inDashboard.Rows != nil {
+		d.Rows = make([]*Row, len(inDashboard.Rows))
+		for i, data := range inDashboard.Rows {
+			row := new(Row)
+			if err := row.unmarshalJSON(data, r); err != nil {
+				return err
+			}
+			d.Rows[i] = row
+		}
+	}
+
 	return nil
 }
 
@@ -124,6 +212,9 @@ type DashboardMeta struct {
 	Type    string `json:"type"`
 	Version int    `json:"version"`
 
+	FolderID    FolderID `json:"folderId"`
+	FolderTitle string   `json:"folderTitle"`
+
 	CanEdit bool `json:"canEdit"`
 	CanSave bool `json:"canSave"`
 	CanStar bool `json:"canStar"`
@@ -154,10 +245,14 @@ func NewRow() *Row {
 
 // UnmarshalJSON implements json.Unmarshaler interface
 func (r *Row) UnmarshalJSON(data []byte) error {
+	return r.unmarshalJSON(data, nil)
+}
+
+func (r *Row) unmarshalJSON(data []byte, resolver *queryResolver) error {
 	type JSONRow Row
 	jr := struct {
 		*JSONRow
-		Panels []probePanel `json:"panels"`
+		Panels []json.RawMessage `json:"panels"`
 	}{
 		JSONRow: (*JSONRow)(r),
 	}
@@ -166,14 +261,107 @@ func (r *Row) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	panels := make([]Panel, len(jr.Panels))
-	for i, p := range jr.Panels {
-		panels[i] = p.panel
+	panels, err := unmarshalPanels(jr.Panels, resolver)
+	if err != nil {
+		return err
+	}
+	if panels == nil {
+		panels = []Panel{}
 	}
 	r.Panels = panels
 	return nil
 }
 
+// unmarshalPanels decodes concrete panels from given JSON objects. Panels of unknown types are skipped.
+func unmarshalPanels(objects []json.RawMessage, resolver *queryResolver) ([]Panel, error) {
+	if objects == nil {
+		return nil, nil
+	}
+
+	panels := make([]Panel, 0, len(objects))
+	for _, data := range objects {
+		var p probePanel
+		if err := p.unmarshalJSON(data, resolver); err != nil {
+			return nil, err
+		}
+		if p.panel == nil {
+			continue
+		}
+		panels = append(panels, p.panel)
+	}
+	return panels, nil
+}
+
+// RowPanel is a special panel of grid layout (schema version 16+) that groups panels placed below it. Panels of
+// collapsed row are stored inside the row itself. Title of the row is stored in its general options.
+type RowPanel struct {
+	Collapsed bool    `json:"collapsed"`
+	Panels    []Panel `json:"-"`
+	RepeatFor string  `json:"repeat,omitempty"` // repeat row for given variable
+
+	generalOptions panel.GeneralOptions
+}
+
+// NewRowPanel creates new RowPanel with given title.
+func NewRowPanel(title string) *RowPanel {
+	return &RowPanel{
+		generalOptions: panel.GeneralOptions{Title: title},
+	}
+}
+
+// GeneralOptions implements Panel interface
+func (p *RowPanel) GeneralOptions() *panel.GeneralOptions {
+	return &p.generalOptions
+}
+
+// ConvertRowsToGrid converts legacy rows layout of the dashboard into grid layout of schema version 16. Rows are
+// replaced with RowPanels if any of them has visible title, is collapsed or repeated; span and height of panels are
+// translated into their grid positions. Does nothing if dashboard already uses grid layout.
+func (d *Dashboard) ConvertRowsToGrid() {
+	if d.SchemaVersion >= gridLayoutSchemaVersion {
+		return
+	}
+
+	rows := make([]grid.Row, len(d.Rows))
+	for i, r := range d.Rows {
+		rows[i] = grid.Row{Height: string(r.Height), Collapsed: r.Collapsed, ShowTitle: r.ShowTitle, Repeat: r.RepeatFor}
+		for _, p := range r.Panels {
+			opts := p.GeneralOptions()
+			rows[i].Panels = append(rows[i].Panels, grid.Panel{Span: opts.Span, Height: string(opts.Height)})
+		}
+	}
+
+	var panels []Panel
+	var rowPanel *RowPanel
+	for _, pl := range grid.Convert(rows) {
+		pos := &panel.GridPos{X: pl.Pos.X, Y: pl.Pos.Y, W: pl.Pos.W, H: pl.Pos.H}
+		r := d.Rows[pl.Row]
+		if pl.Panel < 0 {
+			rowPanel = NewRowPanel(r.Title)
+			rowPanel.Collapsed = r.Collapsed
+			rowPanel.RepeatFor = r.RepeatFor
+			rowPanel.GeneralOptions().GridPos = pos
+			panels = append(panels, rowPanel)
+			continue
+		}
+
+		p := r.Panels[pl.Panel]
+		opts := p.GeneralOptions()
+		opts.GridPos = pos
+		opts.Span = 0
+		opts.Height = ""
+		if pl.Collapsed {
+			rowPanel.Panels = append(rowPanel.Panels, p)
+		} else {
+			panels = append(panels, p)
+		}
+	}
+
+	d.Panels = append(d.Panels, panels...)
+	d.Rows = nil
+	d.SchemaVersion = gridLayoutSchemaVersion
+}
+
 //Panel represents Dashboard's panel
 type Panel interface {
 	GeneralOptions() *panel.GeneralOptions
@@ -182,23 +370,34 @@ type Panel interface {
 type panelType string
 
 const (
-	textPanelType       panelType = "text"
-	singlestatPanelType panelType = "singlestat"
-	graphPanelType      panelType = "graph"
+	textPanelType       panelType = panel.TextType
+	singlestatPanelType panelType = panel.SinglestatType
+	graphPanelType      panelType = panel.GraphType
+	rowPanelType        panelType = "row"
 )
 
+func init() {
+	panel.Register(string(rowPanelType), func() panel.Panel { return new(RowPanel) })
+}
+
 type probePanel struct {
 	ID   uint      `json:"id"`
 	Type panelType `json:"type"`
 
-	panel Panel
+	panel         Panel
+	schemaVersion int // schema version of dashboard, datasources are marshaled as is if zero
 }
 
 func (p *probePanel) GeneralOptions() *panel.GeneralOptions {
 	return p.panel.GeneralOptions()
 }
 
+// UnmarshalJSON implements json.Unmarshaler interface
 func (p *probePanel) UnmarshalJSON(data []byte) error {
+	return p.unmarshalJSON(data, nil)
+}
+
+func (p *probePanel) unmarshalJSON(data []byte, resolver *queryResolver) error {
 	type JSONPanel probePanel
 	jp := struct {
 		*JSONPanel
@@ -209,15 +408,8 @@ func (p *probePanel) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	var pp Panel
-	switch jp.Type {
-	case textPanelType:
-		pp = new(panel.Text)
-	case singlestatPanelType:
-		pp = new(panel.Singlestat)
-	case graphPanelType:
-		pp = new(panel.Graph)
-	default:
+	pp, ok := panel.New(string(jp.Type))
+	if !ok {
 		return nil
 	}
 
@@ -225,6 +417,21 @@ func (p *probePanel) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	// Unmarshal panels of collapsed row
+	if rp, ok := pp.(*RowPanel); ok {
+		var jr struct {
+			Panels []json.RawMessage `json:"panels"`
+		}
+		if err := json.Unmarshal(data, &jr); err != nil {
+			return err
+		}
+		panels, err := unmarshalPanels(jr.Panels, resolver)
+		if err != nil {
+			return err
+		}
+		rp.Panels = panels
+	}
+
 	// Unmarshal general options
 	var generalOptions panel.GeneralOptions
 	if err := json.Unmarshal(data, &generalOptions); err != nil {
@@ -234,14 +441,21 @@ func (p *probePanel) UnmarshalJSON(data []byte) error {
 	*gOpts = generalOptions
 
 	// Unmarshal queries
-	var queriesOpts queriesOptions
+	var queriesOpts struct {
+		Datasource panel.DatasourceRef `json:"datasource"`
+		Queries    []json.RawMessage   `json:"targets"`
+	}
 	if err := json.Unmarshal(data, &queriesOpts); err != nil {
 		return err
 	}
 	if queryablePanel, ok := pp.(QueryablePanel); ok {
 		queriesPtr := queryablePanel.Queries()
 		newQueries := []panel.Query{}
-		for _, q := range queriesOpts.Queries {
+		for _, data := range queriesOpts.Queries {
+			var q probeQuery
+			if err := q.unmarshalJSON(data, resolver, queriesOpts.Datasource); err != nil {
+				return err
+			}
 			if q.query == nil {
 				continue
 			}
@@ -257,71 +471,58 @@ func (p *probePanel) UnmarshalJSON(data []byte) error {
 // MarshalJSON implements json.Marshaler interface
 func (p *probePanel) MarshalJSON() ([]byte, error) {
 	type JSONPanel probePanel
-	jp := struct {
-		*JSONPanel
-
-		*panel.Text
-		*panel.Singlestat
-		*panel.Graph
-
-		*panel.GeneralOptions
-		*queriesOptions
-	}{
-		JSONPanel:      (*JSONPanel)(p),
-		GeneralOptions: p.GeneralOptions(),
+	jp := (*JSONPanel)(p)
+	if t, ok := panel.TypeOf(p.panel); ok {
+		jp.Type = panelType(t)
 	}
 
-	switch v := p.panel.(type) {
-	case *panel.Text:
-		jp.Text = v
-		jp.Type = textPanelType
-	case *panel.Singlestat:
-		jp.Singlestat = v
-		jp.Type = singlestatPanelType
-	case *panel.Graph:
-		jp.Graph = v
-		jp.Type = graphPanelType
+	var rowPanels interface{}
+	if v, ok := p.panel.(*RowPanel); ok {
+		panels := make([]Panel, len(v.Panels))
+		for i, p := range v.Panels {
+			if _, ok := p.(*probePanel); !ok {
+				p = &probePanel{panel: p}
+			}
+			panels[i] = p
+		}
+		rowPanels = struct {
+			Panels []Panel `json:"panels"`
+		}{panels}
 	}
 
+	var queriesOpts *queriesOptions
+
 	if qp, ok := p.panel.(QueryablePanel); ok {
-		// Determine do each query uses its own datassource or not
-		isOwnDatasource := false
 		queries := *qp.Queries()
-		for i := 0; i < len(queries)-1; i++ {
-			if queries[i].Datasource() != queries[i+1].Datasource() {
-				isOwnDatasource = true
-			}
-		}
+		datasource := panelDatasource(queries)
+		// Since schema version 33 queries reference their datasources even if all of them use the same one
+		ownDatasources := datasource.IsMixed() || p.schemaVersion >= panel.DatasourceRefSchemaVersion
 
+		refIDs := queryRefIDs(queries)
 		probeQueries := make([]probeQuery, len(queries))
 		for i, q := range queries {
 			pq := probeQuery{
-				RefID: makeRefID(i),
+				RefID: refIDs[i],
 				query: q,
 			}
 
-			if isOwnDatasource {
-				pq.Datasource = q.Datasource()
+			if ds := q.Datasource(); ownDatasources && !ds.IsDefault() {
+				ref := ds.ForSchema(p.schemaVersion)
+				pq.Datasource = &ref
 			}
 			probeQueries[i] = pq
 		}
 
-		var datasource string
-		if isOwnDatasource {
-			datasource = mixedDatasource
-		} else {
-			if len(queries) > 0 {
-				datasource = queries[0].Datasource()
-			}
-		}
-
-		jp.queriesOptions = &queriesOptions{
-			Queries:    probeQueries,
-			Datasource: datasource,
+		queriesOpts = &queriesOptions{Queries: probeQueries}
+		if !datasource.IsDefault() {
+			ref := datasource.ForSchema(p.schemaVersion)
+			queriesOpts.Datasource = &ref
 		}
 	}
 
-	return json.Marshal(jp)
+	// Fields of panels of registered types may conflict with general options, thus they are merged instead of
+	// embedding into single struct.
+	return jsontools.MergeObjects(p.panel, p.GeneralOptions(), rowPanels, queriesOpts, jp)
 }
 
 // QueryablePanel is interface for panels that supports quering metrics from datasources.
@@ -329,34 +530,48 @@ type QueryablePanel interface {
 	Queries() *[]panel.Query
 }
 
-const mixedDatasource = "-- Mixed --"
+// panelDatasource returns datasource of panel with given queries: datasource of the queries if all of them use the
+// same one or mixed datasource otherwise.
+func panelDatasource(queries []panel.Query) panel.DatasourceRef {
+	if len(queries) == 0 {
+		return panel.DatasourceRef{}
+	}
+
+	datasource := queries[0].Datasource()
+	for _, q := range queries[1:] {
+		if !q.Datasource().Equal(datasource) {
+			return panel.MixedDatasourceRef()
+		}
+	}
+	return datasource
+}
 
 type queriesOptions struct {
-	Datasource string       `json:"datasource,omitempty"`
-	Queries    []probeQuery `json:"targets"`
+	Datasource *panel.DatasourceRef `json:"datasource,omitempty"`
+	Queries    []probeQuery         `json:"targets"`
 }
 
 // probeQuery is an auxiliary entity thats purpose to manage marshaling and unmarshal of panel's query into concrete
 // types.
 type probeQuery struct {
-	RefID      string `json:"refid"`
-	Datasource string `json:"datasource,omitempty"`
+	RefID      string               `json:"refId"`
+	Datasource *panel.DatasourceRef `json:"datasource,omitempty"`
 
 	query panel.Query
 }
 
 // UnmarshalJSON implements json.Unmarshaler interface
 func (q *probeQuery) UnmarshalJSON(data []byte) error {
+	return q.unmarshalJSON(data, nil, panel.DatasourceRef{})
+}
+
+// unmarshalJSON decodes query of panel with given datasource. Type of the query is resolved by its own datasource
+// or datasource of the panel. Heuristics are used if type of datasource is unknown.
+func (q *probeQuery) unmarshalJSON(data []byte, resolver *queryResolver, panelDatasource panel.DatasourceRef) error {
 	type JSONQuery probeQuery
 	jq := struct {
 		*JSONQuery
-
-		// Prometheus query fields
-		IntervalFactor *uint   `json:"intervalFactor"`
-		Expression     *string `json:"expr"`
-
-		// Graphite queryfields
-		Target *string `json:"target"`
+		queryFields
 	}{
 		JSONQuery: (*JSONQuery)(q),
 	}
@@ -364,14 +579,24 @@ func (q *probeQuery) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	// There is no any information about query type in Grafana's JSON object. Further more, some queries uses the same
-	// fields. Thus we need to use some heurisitcs to map json fields into our query types properly.
-	// This heurisitcs based on searching specific for query type fields in JSON data.
+	datasource := panelDatasource
+	if q.Datasource != nil && !q.Datasource.IsDefault() {
+		datasource = *q.Datasource
+	}
+	if datasource.IsMixed() {
+		// Queries of mixed panel without own datasource use the default one
+		datasource = panel.DatasourceRef{}
+	}
+
 	var query panel.Query
-	if jq.Expression != nil && jq.IntervalFactor != nil {
-		query = new(panelQuery.Prometheus)
-	} else if jq.Target != nil {
-		query = new(panelQuery.Graphite)
+	if t, ok := resolver.datasourceType(datasource); ok {
+		query, _ = panelQuery.New(string(t))
+	}
+	if query == nil {
+		query, _ = panelQuery.Guess(data)
+	}
+	if query == nil {
+		query = jq.queryFields.guessQuery()
 	}
 
 	// TODO: Initialize Unknown query here instead
@@ -382,47 +607,188 @@ func (q *probeQuery) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &query); err != nil {
 		return err
 	}
+	if qo, ok := query.(queryWithOptions); ok {
+		qo.Options().SetDatasource(resolver.resolve(datasource))
+	}
 
 	q.query = query
 	return nil
 }
 
+// queryFields are fields specific for query types. They are used to guess type of query if type of its datasource
+// is unknown.
+type queryFields struct {
+	// Prometheus query fields
+	IntervalFactor *uint   `json:"intervalFactor"`
+	Expression     *string `json:"expr"`
+
+	// Loki query fields
+	MaxLines   *json.RawMessage `json:"maxLines"`
+	Resolution *json.RawMessage `json:"resolution"`
+
+	// TestData query fields
+	ScenarioID *string `json:"scenarioId"`
+
+	// Graphite queryfields
+	Target *string `json:"target"`
+
+	// Elasticsearch query fields
+	BucketAggs *json.RawMessage `json:"bucketAggs"`
+
+	// SQL query fields
+	RawSQL *string `json:"rawSql"`
+
+	// InfluxDB query fields
+	Measurement *string          `json:"measurement"`
+	Policy      *string          `json:"policy"`
+	GroupBy     *json.RawMessage `json:"groupBy"`
+}
+
+// guessQuery returns empty query of the type guessed by the fields or nil if type of query can't be guessed.
+//
+// There is no any information about query type in Grafana's JSON object. Further more, some queries uses the same
+// fields. Thus we need to use some heurisitcs to map json fields into our query types properly.
+// This heurisitcs based on searching specific for query type fields in JSON data.
+func (f *queryFields) guessQuery() panel.Query {
+	switch {
+	case f.Expression != nil && f.IntervalFactor != nil:
+		return new(panelQuery.Prometheus)
+	case f.Expression != nil && (f.MaxLines != nil || f.Resolution != nil || isLogQL(*f.Expression)):
+		return new(panelQuery.Loki)
+	case f.Expression != nil:
+		return new(panelQuery.Prometheus)
+	case f.ScenarioID != nil:
+		return new(panelQuery.TestData)
+	case f.Target != nil:
+		return new(panelQuery.Graphite)
+	case f.BucketAggs != nil:
+		return new(panelQuery.Elasticsearch)
+	case f.RawSQL != nil:
+		return new(panelQuery.SQL)
+	case f.Measurement != nil || f.Policy != nil || f.GroupBy != nil:
+		return new(panelQuery.InfluxDB)
+	}
+	return nil
+}
+
+// queryResolver resolves types of queries by their datasources while dashboard is decoded.
+type queryResolver struct {
+	datasources *DatasourceRegistry
+	variables   Variables
+	inputs      []DashboardInput
+}
+
+// datasourceType returns type of referenced datasource. Datasource variables and inputs are resolved to type of
+// their datasources.
+func (r *queryResolver) datasourceType(ref panel.DatasourceRef) (datasourceType, bool) {
+	if ref.IsDashboard() {
+		return DashboardDatasource, true
+	}
+	if ref.Type != "" && !ref.IsMixed() {
+		return datasourceType(ref.Type), true
+	}
+	if r == nil {
+		return "", false
+	}
+
+	if name, ok := ref.Variable(); ok {
+		for _, v := range r.variables {
+			if dv, ok := v.(*DatasourceVariable); ok && dv.Name == name {
+				return datasourceType(dv.Query), dv.Query != ""
+			}
+		}
+		for _, in := range r.inputs {
+			if in.Type == DatasourceInput && in.Name == name {
+				return datasourceType(in.PluginID), in.PluginID != ""
+			}
+		}
+		return "", false
+	}
+
+	if r.datasources == nil {
+		return "", false
+	}
+	resolved, ok := r.datasources.Resolve(ref)
+	return datasourceType(resolved.Type), ok
+}
+
+// resolve returns given reference completed with name, uid and type of the datasource if it's known. References to
+// the default datasource are kept as is.
+func (r *queryResolver) resolve(ref panel.DatasourceRef) panel.DatasourceRef {
+	if r == nil || r.datasources == nil || ref.IsDefault() {
+		return ref
+	}
+	if resolved, ok := r.datasources.Resolve(ref); ok {
+		return resolved
+	}
+	return ref
+}
+
+// isLogQL reports whether expression looks like LogQL query, ie. starts with stream selector or contains line
+// filters.
+func isLogQL(expr string) bool {
+	expr = strings.TrimSpace(expr)
+	return strings.HasPrefix(expr, "{") || strings.Contains(expr, "|=") || strings.Contains(expr, "|~")
+}
+
 // MarshalJSON implements json.Marshaler interface
 func (q *probeQuery) MarshalJSON() ([]byte, error) {
+	// Queries of different types use the same JSON fields (ie. "query" or "alias"), so they can't be embedded into
+	// single struct. Instead we merge JSON objects of the query and the probe, refId of the probe takes precedence.
 	type JSONQuery probeQuery
-	jq := struct {
-		*JSONQuery
-		*panelQuery.Prometheus
-		*panelQuery.Graphite
-	}{
-		JSONQuery: (*JSONQuery)(q),
+	return jsontools.MergeObjects(q.query, (*JSONQuery)(q))
+}
+
+// queryWithOptions is a query which keeps its common options, ie. by embedding panel.QueryOptions.
+type queryWithOptions interface {
+	Options() *panel.QueryOptions
+}
+
+// AssignRefIDs sets refIds of given queries to the ones they get when dashboard is saved, so the queries can be
+// referenced by alert conditions and other queries before that. Queries not embedding panel.QueryOptions are skipped.
+func AssignRefIDs(queries []panel.Query) {
+	for i, id := range queryRefIDs(queries) {
+		if qo, ok := queries[i].(queryWithOptions); ok {
+			qo.Options().RefID = id
+		}
 	}
+}
 
-	switch v := q.query.(type) {
-	case *panelQuery.Prometheus:
-		jq.Prometheus = v
-	case *panelQuery.Graphite:
-		jq.Graphite = v
+// queryRefIDs returns refIds of given queries. Own refIds of queries are kept unless they are duplicated. Queries
+// without refIds get the first unused ones the same way as Grafana assigns them.
+func queryRefIDs(queries []panel.Query) []string {
+	refIDs := make([]string, len(queries))
+	used := make(map[string]bool)
+	for i, q := range queries {
+		if qo, ok := q.(queryWithOptions); ok {
+			if id := qo.Options().RefID; id != "" && !used[id] {
+				refIDs[i] = id
+				used[id] = true
+			}
+		}
 	}
 
-	return json.Marshal(jq)
+	next := 0
+	for i := range refIDs {
+		if refIDs[i] != "" {
+			continue
+		}
+		for used[makeRefID(next)] {
+			next++
+		}
+		refIDs[i] = makeRefID(next)
+		used[refIDs[i]] = true
+	}
+	return refIDs
 }
 
-// makeRefID returns symbolic ID for given index.
-// TODO: It has very rough implementation. Needs refactoring.
+// makeRefID returns symbolic ID for given index. IDs are named like spreadsheet columns: A..Z, AA, AB, ..., ZZ, AAA.
 func makeRefID(index int) string {
-	letters := []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
 	var id string
-	if index >= len(letters) {
-		id += makeRefID(index % len(letters))
-	} else {
-		id = string(letters[index])
-	}
-
-	var result string
-	for _, v := range id {
-		result = string(v) + result
+	for n := index + 1; n > 0; n = (n - 1) / len(letters) {
+		id = string(letters[(n-1)%len(letters)]) + id
 	}
-	return result
+	return id
 }
@@ -0,0 +1,83 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+type dashboardLinkType string
+
+// Types of dashboard links
+const (
+	DashboardLinkURL        dashboardLinkType = "link"
+	DashboardLinkDashboards dashboardLinkType = "dashboards"
+)
+
+type dashboardLinkIcon string
+
+// Icons of dashboard links of DashboardLinkURL type
+const (
+	ExternalLinkIcon dashboardLinkIcon = "external link"
+	DashboardIcon    dashboardLinkIcon = "dashboard"
+	QuestionIcon     dashboardLinkIcon = "question"
+	InfoIcon         dashboardLinkIcon = "info"
+	BoltIcon         dashboardLinkIcon = "bolt"
+	DocIcon          dashboardLinkIcon = "doc"
+	CloudIcon        dashboardLinkIcon = "cloud"
+)
+
+// DashboardLink is a link shown in the top navigation of the dashboard.
+type DashboardLink struct {
+	IncludeVars  bool              `json:"includeVars"`
+	KeepTime     bool              `json:"keepTime"`
+	OpenInNewTab bool              `json:"targetBlank"`
+	Title        string            `json:"title"`
+	Type         dashboardLinkType `json:"type"`
+
+	// type=link
+	Icon    dashboardLinkIcon `json:"icon,omitempty"`
+	Tooltip string            `json:"tooltip,omitempty"`
+	URL     string            `json:"url,omitempty"`
+
+	// type=dashboards
+	AsDropdown bool     `json:"asDropdown"`
+	Tags       []string `json:"tags"` // links to dashboards with all of given tags
+}
+
+// NewDashboardLink creates new DashboardLink
+func NewDashboardLink(linkType dashboardLinkType) *DashboardLink {
+	return &DashboardLink{
+		Type: linkType,
+		Tags: []string{},
+	}
+}
+
+// NewURLLink creates new link to given URL.
+func NewURLLink(title, url string) *DashboardLink {
+	l := NewDashboardLink(DashboardLinkURL)
+	l.Title = title
+	l.URL = url
+	l.Icon = ExternalLinkIcon
+	return l
+}
+
+// NewTaggedDashboardsDropdown creates a dropdown with links to all dashboards having given tags. Links keep current
+// time range and variables, so it's handy to navigate between similar dashboards, ie. dashboards of different
+// services sharing "service" tag.
+func NewTaggedDashboardsDropdown(title string, tags ...string) *DashboardLink {
+	l := NewDashboardLink(DashboardLinkDashboards)
+	l.Title = title
+	l.Tags = append(l.Tags, tags...)
+	l.AsDropdown = true
+	l.KeepTime = true
+	l.IncludeVars = true
+	return l
+}
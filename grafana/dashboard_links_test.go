@@ -0,0 +1,87 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+)
+
+func TestDashboardLink_MarshalJSON(t *testing.T) {
+	urlLink := NewURLLink("Docs", "https://example.com/docs")
+	urlLink.Tooltip = "Documentation"
+	urlLink.OpenInNewTab = true
+	links := []*DashboardLink{urlLink, NewTaggedDashboardsDropdown("Services", "service", "prod")}
+
+	got, err := json.MarshalIndent(links, "", "\t\t")
+	if err != nil {
+		t.Fatalf("DashboardLink.MarshalJSON returned error %s", err)
+	}
+	expected := []byte(`[{
+		"includeVars": false,
+		"keepTime": false,
+		"targetBlank": true,
+		"title": "Docs",
+		"type": "link",
+		"icon": "external link",
+		"tooltip": "Documentation",
+		"url": "https://example.com/docs",
+		"asDropdown": false,
+		"tags": []
+	}, {
+		"includeVars": true,
+		"keepTime": true,
+		"targetBlank": false,
+		"title": "Services",
+		"type": "dashboards",
+		"asDropdown": true,
+		"tags": ["service", "prod"]
+	}]`)
+	if eq, err := JSONBytesEqual(expected, got); err != nil {
+		t.Fatalf("DashboardLink.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("DashboardLink.MarshalJSON: got %s, want %s\n", got, expected)
+	}
+}
+
+func TestDashboard_UnmarshalJSON_Links(t *testing.T) {
+	data := []byte(`{
+		"links": [{
+			"asDropdown": true,
+			"icon": "external link",
+			"includeVars": true,
+			"keepTime": true,
+			"tags": ["service"],
+			"targetBlank": false,
+			"title": "Services",
+			"tooltip": "",
+			"type": "dashboards",
+			"url": ""
+		}]
+	}`)
+	var got Dashboard
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Dashboard.UnmarshalJSON returned error %s", err)
+	}
+
+	link := NewTaggedDashboardsDropdown("Services", "service")
+	link.Icon = ExternalLinkIcon
+	expected := []DashboardLink{*link}
+	if !reflect.DeepEqual(got.Links, expected) {
+		t.Errorf("Dashboard.UnmarshalJSON: %s", pretty.Diff(got.Links, expected))
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+type inputType string
+
+// Types of dashboard inputs
+const (
+	DatasourceInput inputType = "datasource"
+	ConstantInput   inputType = "constant"
+)
+
+// DashboardInput is an input of dashboard shared on grafana.com or exported for sharing. References to the input,
+// ie. "${DS_PROMETHEUS}", are replaced by its value when the dashboard is imported.
+type DashboardInput struct {
+	Name        string    `json:"name"` // ie. "DS_PROMETHEUS"
+	Label       string    `json:"label"`
+	Description string    `json:"description"`
+	Type        inputType `json:"type"`
+	PluginID    string    `json:"pluginId,omitempty"`   // type of datasource of DatasourceInput
+	PluginName  string    `json:"pluginName,omitempty"` // name of the datasource plugin, ie. "Prometheus"
+	Value       string    `json:"value,omitempty"`      // default value of ConstantInput
+}
+
+// DashboardRequirement is a plugin required by dashboard shared on grafana.com or exported for sharing.
+type DashboardRequirement struct {
+	Type    string `json:"type"` // "grafana", "datasource" or "panel"
+	ID      string `json:"id"`   // ie. "prometheus" or "graph"
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ExportForSharing returns JSON of the dashboard with given JSON ready to be shared with other Grafana instances.
+// Datasources walked by WalkQueryDatasources are replaced by inputs, ie. "${DS_PROMETHEUS}", which are bound to
+// datasources of the instance on import. Datasources are resolved by given registry, references to the default
+// datasource are replaced too if the registry has the default one. Plugins of used datasources and panels are listed
+// as requirements. Other fields are kept as is, so panels of unregistered types are shared too.
+//
+// Unknown datasources are returned as validate.Errors with paths of their references.
+func ExportForSharing(data []byte, datasources *DatasourceRegistry) ([]byte, error) {
+	var d sharingDashboard
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, err
+	}
+
+	e := &sharingExporter{datasources: datasources, inputs: make(map[string]panel.DatasourceRef)}
+	for _, in := range d.Inputs {
+		e.names = append(e.names, in.Name)
+	}
+	shared, err := WalkQueryDatasources(data, func(path string, ref panel.DatasourceRef) (panel.DatasourceRef, error) {
+		return e.export(ref)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := append(d.Inputs, e.exported...)
+	return jsontools.SetFields(shared, map[string]interface{}{
+		"id":         nil, // the dashboard gets new id on import
+		"__inputs":   inputs,
+		"__requires": requirements(inputs, d.panelTypes()),
+	})
+}
+
+// sharingDashboard is a part of dashboard's JSON used by ExportForSharing.
+type sharingDashboard struct {
+	Inputs []DashboardInput `json:"__inputs"`
+	Rows   []struct {
+		Panels []sharingPanel `json:"panels"`
+	} `json:"rows"`
+	Panels []sharingPanel `json:"panels"`
+}
+
+type sharingPanel struct {
+	Type   string         `json:"type"`
+	Panels []sharingPanel `json:"panels"` // panels of collapsed row
+}
+
+// panelTypes returns types of panels of the dashboard, rows are skipped.
+func (d *sharingDashboard) panelTypes() []string {
+	var types []string
+	var add func(panels []sharingPanel)
+	add = func(panels []sharingPanel) {
+		for _, p := range panels {
+			if p.Type != "" && p.Type != string(rowPanelType) {
+				types = append(types, p.Type)
+			}
+			add(p.Panels)
+		}
+	}
+	for _, row := range d.Rows {
+		add(row.Panels)
+	}
+	add(d.Panels)
+	return types
+}
+
+// sharingExporter replaces datasources of dashboard by inputs.
+type sharingExporter struct {
+	datasources *DatasourceRegistry
+	inputs      map[string]panel.DatasourceRef // references to inputs by names of datasources
+	names       []string                       // names of inputs
+	exported    []DashboardInput
+}
+
+// export returns reference to input of the referenced datasource. References to special datasources and to
+// variables are kept.
+func (e *sharingExporter) export(ref panel.DatasourceRef) (panel.DatasourceRef, error) {
+	if _, ok := ref.Variable(); ok || ref.IsMixed() || ref.IsDashboard() || ref.Equal(panel.GrafanaDatasourceRef()) {
+		return ref, nil
+	}
+	if e.datasources == nil {
+		if ref.IsDefault() {
+			return ref, nil
+		}
+		return ref, fmt.Errorf("datasource %q is unknown", datasourceRefName(ref))
+	}
+	resolved, ok := e.datasources.Resolve(ref)
+	if !ok {
+		if ref.IsDefault() {
+			return ref, nil
+		}
+		return ref, fmt.Errorf("datasource %q is unknown", datasourceRefName(ref))
+	}
+
+	if in, ok := e.inputs[resolved.Name]; ok {
+		return in, nil
+	}
+	name := e.inputName(resolved.Name)
+	in := panel.DatasourceRef{Name: "${" + name + "}", UID: "${" + name + "}", Type: resolved.Type}
+	e.inputs[resolved.Name] = in
+	e.exported = append(e.exported, DashboardInput{
+		Name:       name,
+		Label:      resolved.Name,
+		Type:       DatasourceInput,
+		PluginID:   resolved.Type,
+		PluginName: pluginName(resolved.Type),
+	})
+	return in, nil
+}
+
+// inputName returns unused name of input of given datasource the same way as Grafana names them, ie. DS_PROMETHEUS.
+func (e *sharingExporter) inputName(datasource string) string {
+	base := "DS_" + strings.Map(func(r rune) rune {
+		switch {
+		case 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case 'a' <= r && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, datasource)
+
+	name := base
+	for i := 2; containsString(e.names, name); i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	e.names = append(e.names, name)
+	return name
+}
+
+// requirements returns plugins required by dashboard with given inputs and types of panels: Grafana, datasources of
+// the inputs and the panels.
+func requirements(inputs []DashboardInput, panelTypes []string) []DashboardRequirement {
+	requires := []DashboardRequirement{{Type: "grafana", ID: "grafana", Name: "Grafana"}}
+	var datasources []string
+	for _, in := range inputs {
+		if in.Type == DatasourceInput && in.PluginID != "" && !containsString(datasources, in.PluginID) {
+			datasources = append(datasources, in.PluginID)
+		}
+	}
+	sort.Strings(datasources)
+	for _, t := range datasources {
+		requires = append(requires, DashboardRequirement{Type: "datasource", ID: t, Name: pluginName(t)})
+	}
+
+	var panels []string
+	for _, t := range panelTypes {
+		if !containsString(panels, t) {
+			panels = append(panels, t)
+		}
+	}
+	sort.Strings(panels)
+	for _, t := range panels {
+		requires = append(requires, DashboardRequirement{Type: "panel", ID: t, Name: pluginName(t)})
+	}
+	return requires
+}
+
+// pluginNames are names of built-in plugins of Grafana by their ids.
+var pluginNames = map[string]string{
+	string(GraphiteDatasource):      "Graphite",
+	string(PrometheusDatasource):    "Prometheus",
+	string(ElasticsearchDatasource): "Elasticsearch",
+	string(InfluxDBDatasource):      "InfluxDB",
+	string(MySQLDatasource):         "MySQL",
+	string(PostgreSQLDatasource):    "PostgreSQL",
+	string(MSSQLDatasource):         "Microsoft SQL Server",
+	string(LokiDatasource):          "Loki",
+	string(TestDataDatasource):      "TestData DB",
+	panel.GraphType:                 "Graph",
+	panel.SinglestatType:            "Singlestat",
+	panel.TextType:                  "Text",
+	"table":                         "Table",
+	"heatmap":                       "Heatmap",
+	"dashlist":                      "Dashboard list",
+	"alertlist":                     "Alert list",
+}
+
+// pluginName returns name of plugin with given id, ie. "Prometheus" of "prometheus". Ids of unknown plugins are
+// returned as is.
+func pluginName(id string) string {
+	if name, ok := pluginNames[id]; ok {
+		return name
+	}
+	return id
+}
+
+func datasourceRefName(ref panel.DatasourceRef) string {
+	if ref.Name != "" {
+		return ref.Name
+	}
+	return ref.UID
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,166 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	panelQuery "github.com/spoof/go-grafana/grafana/query"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+// sharedDashboard is a dashboard exported for sharing like ones of grafana.com
+const sharedDashboard = `{
+	"__inputs": [{
+		"name": "DS_PROMETHEUS",
+		"label": "Prometheus",
+		"description": "",
+		"type": "datasource",
+		"pluginId": "prometheus",
+		"pluginName": "Prometheus"
+	}],
+	"__requires": [
+		{"type": "grafana", "id": "grafana", "name": "Grafana", "version": "5.0.0"},
+		{"type": "datasource", "id": "prometheus", "name": "Prometheus", "version": "1.0.0"}
+	],
+	"title": "Node Exporter",
+	"schemaVersion": 16,
+	"panels": [{
+		"type": "graph",
+		"title": "Load",
+		"datasource": "${DS_PROMETHEUS}",
+		"targets": [{"refId": "A", "expr": "node_load1"}]
+	}]
+}`
+
+func TestDashboard_UnmarshalJSON_Inputs(t *testing.T) {
+	var d Dashboard
+	if err := json.Unmarshal([]byte(sharedDashboard), &d); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+
+	wantInputs := []DashboardInput{{
+		Name:       "DS_PROMETHEUS",
+		Label:      "Prometheus",
+		Type:       DatasourceInput,
+		PluginID:   "prometheus",
+		PluginName: "Prometheus",
+	}}
+	if !reflect.DeepEqual(d.Inputs, wantInputs) {
+		t.Errorf("Unmarshal: inputs %+v, want %+v", d.Inputs, wantInputs)
+	}
+	if len(d.Requires) != 2 || d.Requires[1].ID != "prometheus" || d.Requires[0].Version != "5.0.0" {
+		t.Errorf("Unmarshal: unexpected requirements %+v", d.Requires)
+	}
+
+	// Types of queries are resolved by plugins of inputs
+	q := (*d.Panels[0].(QueryablePanel).Queries())[0]
+	if _, ok := q.(*panelQuery.Prometheus); !ok {
+		t.Errorf("Unmarshal: query %T is not resolved by input", q)
+	}
+
+	data, err := json.Marshal(&d)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	for _, s := range []string{`"__inputs":[{"name":"DS_PROMETHEUS"`, `"__requires":[`, `"datasource":"${DS_PROMETHEUS}"`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("Marshal: %s is missing in %s", s, data)
+		}
+	}
+}
+
+func TestExportForSharing(t *testing.T) {
+	data := []byte(`{
+		"id": 7,
+		"uid": "api",
+		"title": "API",
+		"schemaVersion": 16,
+		"templating": {"list": [
+			{"type": "query", "name": "job", "datasource": "Prometheus", "query": "label_values(job)"},
+			{"type": "datasource", "name": "ds", "query": "prometheus"}
+		]},
+		"panels": [
+			{"type": "graph", "title": "Requests", "targets": [{"refId": "A", "expr": "up"}]},
+			{"type": "row", "title": "Logs", "collapsed": true, "panels": [
+				{"type": "graph", "title": "Mixed", "datasource": "-- Mixed --", "targets": [
+					{"refId": "A", "datasource": "Loki main", "expr": "{job=\"api\"}"},
+					{"refId": "B", "datasource": "$ds", "expr": "up"}
+				]}
+			]},
+			{"type": "grafana-worldmap-panel", "title": "Map", "locationData": "countries",
+				"targets": [{"refId": "A", "expr": "sum by (country) (up)"}]},
+			{"type": "text", "title": "Notes"}
+		]
+	}`)
+	datasources := NewDatasourceRegistry(
+		&Datasource{Name: "Prometheus", Type: PrometheusDatasource, IsDefault: true},
+		&Datasource{Name: "Loki main", Type: LokiDatasource},
+	)
+
+	shared, err := ExportForSharing(data, datasources)
+	if err != nil {
+		t.Fatalf("ExportForSharing returned error: %v", err)
+	}
+	want := []byte(`{
+		"__inputs": [
+			{"name": "DS_PROMETHEUS", "label": "Prometheus", "description": "", "type": "datasource",
+				"pluginId": "prometheus", "pluginName": "Prometheus"},
+			{"name": "DS_LOKI_MAIN", "label": "Loki main", "description": "", "type": "datasource",
+				"pluginId": "loki", "pluginName": "Loki"}
+		],
+		"__requires": [
+			{"type": "grafana", "id": "grafana", "name": "Grafana", "version": ""},
+			{"type": "datasource", "id": "loki", "name": "Loki", "version": ""},
+			{"type": "datasource", "id": "prometheus", "name": "Prometheus", "version": ""},
+			{"type": "panel", "id": "grafana-worldmap-panel", "name": "grafana-worldmap-panel", "version": ""},
+			{"type": "panel", "id": "graph", "name": "Graph", "version": ""},
+			{"type": "panel", "id": "text", "name": "Text", "version": ""}
+		],
+		"uid": "api",
+		"title": "API",
+		"schemaVersion": 16,
+		"templating": {"list": [
+			{"type": "query", "name": "job", "datasource": "${DS_PROMETHEUS}", "query": "label_values(job)"},
+			{"type": "datasource", "name": "ds", "query": "prometheus"}
+		]},
+		"panels": [
+			{"type": "graph", "title": "Requests", "datasource": "${DS_PROMETHEUS}",
+				"targets": [{"refId": "A", "expr": "up"}]},
+			{"type": "row", "title": "Logs", "collapsed": true, "panels": [
+				{"type": "graph", "title": "Mixed", "datasource": "-- Mixed --", "targets": [
+					{"refId": "A", "datasource": "${DS_LOKI_MAIN}", "expr": "{job=\"api\"}"},
+					{"refId": "B", "datasource": "$ds", "expr": "up"}
+				]}
+			]},
+			{"type": "grafana-worldmap-panel", "title": "Map", "locationData": "countries",
+				"datasource": "${DS_PROMETHEUS}", "targets": [{"refId": "A", "expr": "sum by (country) (up)"}]},
+			{"type": "text", "title": "Notes"}
+		]
+	}`)
+	if ok, err := jsontools.BytesEqual(shared, want); err != nil || !ok {
+		t.Errorf("ExportForSharing returned %s, want %s", shared, want)
+	}
+}
+
+func TestExportForSharing_UnknownDatasource(t *testing.T) {
+	data := []byte(`{"title": "API", "templating": {"list": [{"type": "query", "datasource": "Graphite"}]}}`)
+	_, err := ExportForSharing(data, NewDatasourceRegistry())
+	if err == nil || err.Error() != `templating.list[0].datasource: datasource "Graphite" is unknown` {
+		t.Errorf("ExportForSharing returned error %v", err)
+	}
+}
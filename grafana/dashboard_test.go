@@ -17,9 +17,11 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/kr/pretty"
 	"github.com/spoof/go-grafana/grafana/panel"
+	panelQuery "github.com/spoof/go-grafana/grafana/query"
 	"github.com/spoof/go-grafana/pkg/field"
 )
 
@@ -91,6 +93,7 @@ func TestDashboard_MarshalJSON(t *testing.T) {
 		"editable": true,
 		"graphTooltip": 2,
 		"hideControls": true,
+		"links": null,
 		"templating": {
 			"list": []
 		},
@@ -140,7 +143,9 @@ func TestDashboard_MarshalJSON(t *testing.T) {
 			"title": "Row Title 2",
 			"titleSize": "h2"
 		}],
+		"refresh": false,
 		"style": "light",
+		"time": {"from": "now-6h", "to": "now"},
 		"timezone": "MSK",
 		"title": "Dashboard Title",
 		"tags": ["tag1", "tag2"]
@@ -164,6 +169,7 @@ func TestDashboard_UnmarshalJSON(t *testing.T) {
 		"timezone": "msk",
 		"title": "Dashboard Title",
 		"tags": ["tag1", "tag2"],
+		"time": {"from": "now-6h", "to": "now"},
 		"schemaVersion": 12
 	}`)
 	var got Dashboard
@@ -189,6 +195,222 @@ func TestDashboard_UnmarshalJSON(t *testing.T) {
 
 }
 
+func TestDashboard_TimeOptions_RoundTrip(t *testing.T) {
+	data := []byte(`{
+		"schemaVersion": 16,
+		"editable": true,
+		"fiscalYearStartMonth": 3,
+		"graphTooltip": 0,
+		"hideControls": false,
+		"links": [],
+		"templating": {
+			"list": []
+		},
+		"refresh": "1m",
+		"style": "dark",
+		"time": {"from": "now-1d/d", "to": "now/d"},
+		"timepicker": {
+			"hidden": true,
+			"nowDelay": "1m",
+			"refresh_intervals": ["5s", "1m", "1h"],
+			"time_options": ["5m", "1h", "7d"]
+		},
+		"timezone": "utc",
+		"title": "Dashboard Title",
+		"tags": [],
+		"weekStart": "monday"
+	}`)
+	var d Dashboard
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatalf("Dashboard.UnmarshalJSON returned error %s", err)
+	}
+
+	expected := NewDashboard("Dashboard Title")
+	expected.SchemaVersion = 16
+	expected.FiscalYearStartMonth = 3
+	expected.Refresh = "1m"
+	expected.Time = NewTimeRange("now-1d/d", "now/d")
+	expected.TimePicker = &TimePicker{
+		Hidden:           true,
+		NowDelay:         "1m",
+		RefreshIntervals: []string{"5s", "1m", "1h"},
+		TimeOptions:      []string{"5m", "1h", "7d"},
+	}
+	expected.Timezone = "utc"
+	expected.WeekStart = WeekStartMonday
+	expected.Variables = Variables{}
+	expected.Links = []DashboardLink{}
+	if !reflect.DeepEqual(&d, expected) {
+		t.Errorf("Dashboard.UnmarshalJSON: %s", pretty.Diff(&d, expected))
+	}
+
+	got, err := json.Marshal(&d)
+	if err != nil {
+		t.Fatalf("Dashboard.MarshalJSON returned error %s", err)
+	}
+	if eq, err := JSONBytesEqual(data, got); err != nil {
+		t.Fatalf("Dashboard.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("Dashboard.MarshalJSON: got %s, want %s\n", got, data)
+	}
+}
+
+func TestDashboard_TimeOptions_Legacy(t *testing.T) {
+	data := []byte(`{
+		"title": "Dashboard Title",
+		"timepicker": {
+			"collapse": false,
+			"enable": true,
+			"notice": false,
+			"now": true,
+			"status": "Stable",
+			"type": "timepicker",
+			"refresh_intervals": ["5s", "1m"]
+		}
+	}`)
+	var d Dashboard
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatalf("Dashboard.UnmarshalJSON returned error %s", err)
+	}
+	if d.Time != nil {
+		t.Errorf("Dashboard.UnmarshalJSON: unexpected time range %v", d.Time)
+	}
+	d.TimePicker.RefreshIntervals = append(d.TimePicker.RefreshIntervals, "1h")
+
+	got, err := json.Marshal(&d)
+	if err != nil {
+		t.Fatalf("Dashboard.MarshalJSON returned error %s", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(got, &fields); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err)
+	}
+	if _, ok := fields["time"]; ok {
+		t.Errorf("Dashboard.MarshalJSON: dashboard without time range got %s", fields["time"])
+	}
+	expected := []byte(`{
+		"hidden": false,
+		"collapse": false,
+		"enable": true,
+		"notice": false,
+		"now": true,
+		"status": "Stable",
+		"type": "timepicker",
+		"refresh_intervals": ["5s", "1m", "1h"]
+	}`)
+	if eq, err := JSONBytesEqual(expected, fields["timepicker"]); err != nil {
+		t.Fatalf("Dashboard.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("Dashboard.MarshalJSON: got timepicker %s, want %s\n", fields["timepicker"], expected)
+	}
+}
+
+func TestRefreshInterval_UnmarshalJSON(t *testing.T) {
+	ts := []struct {
+		data     string
+		expected RefreshInterval
+	}{
+		{`{"refresh": false}`, ""},
+		{`{"refresh": null}`, ""},
+		{`{"refresh": ""}`, ""},
+		{`{"refresh": "5s"}`, "5s"},
+	}
+
+	for _, tt := range ts {
+		var got struct {
+			Refresh RefreshInterval `json:"refresh"`
+		}
+		if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+			t.Fatalf("RefreshInterval.UnmarshalJSON returned error %s", err)
+		}
+		if got.Refresh != tt.expected {
+			t.Errorf("RefreshInterval.UnmarshalJSON(%s): expected %q, got %q", tt.data, tt.expected, got.Refresh)
+		}
+	}
+}
+
+func TestParseRelativeTime(t *testing.T) {
+	ts := []struct {
+		expr     string
+		expected []TimeOperation
+		hasError bool
+	}{
+		{expr: "now", expected: nil},
+		{expr: "now-6h", expected: []TimeOperation{{Op: '-', Count: 6, Unit: Hour}}},
+		{expr: "now/d", expected: []TimeOperation{{Op: '/', Unit: Day}}},
+		{expr: "now-1d/d", expected: []TimeOperation{{Op: '-', Count: 1, Unit: Day}, {Op: '/', Unit: Day}}},
+		{expr: "now+30m", expected: []TimeOperation{{Op: '+', Count: 30, Unit: Minute}}},
+		{expr: "now/fy", expected: []TimeOperation{{Op: '/', Unit: Year, Fiscal: true}}},
+		{expr: "now-6", hasError: true},
+		{expr: "now-6x", hasError: true},
+		{expr: "now*2d", hasError: true},
+		{expr: "now/fd", hasError: true},
+		{expr: "6h", hasError: true},
+	}
+
+	for _, tt := range ts {
+		got, err := ParseRelativeTime(tt.expr)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("ParseRelativeTime(%q): expected error", tt.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRelativeTime(%q) returned error %s", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Operations, tt.expected) {
+			t.Errorf("ParseRelativeTime(%q): expected %+v, got %+v", tt.expr, tt.expected, got.Operations)
+		}
+		if got.String() != tt.expr {
+			t.Errorf("RelativeTime.String(): expected %q, got %q", tt.expr, got.String())
+		}
+	}
+}
+
+func TestTimeRange_Times(t *testing.T) {
+	now := time.Date(2017, 11, 15, 10, 30, 0, 0, time.UTC)
+	ts := []struct {
+		timeRange    *TimeRange
+		expectedFrom time.Time
+		expectedTo   time.Time
+	}{
+		{NewTimeRange("now-6h", "now"), time.Date(2017, 11, 15, 4, 30, 0, 0, time.UTC), now},
+		{
+			NewTimeRange("now-1d/d", "now-1d/d"),
+			time.Date(2017, 11, 14, 0, 0, 0, 0, time.UTC),
+			time.Date(2017, 11, 14, 23, 59, 59, 999000000, time.UTC),
+		},
+		{
+			NewTimeRange("now/fy", "now"),
+			time.Date(2017, 4, 1, 0, 0, 0, 0, time.UTC),
+			now,
+		},
+		{
+			NewTimeRange("2017-11-01T00:00:00Z", "1510704000000"),
+			time.Date(2017, 11, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2017, 11, 15, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range ts {
+		from, to, err := tt.timeRange.Times(now, 3)
+		if err != nil {
+			t.Errorf("TimeRange%+v.Times returned error %s", tt.timeRange, err)
+			continue
+		}
+		if !from.Equal(tt.expectedFrom) || !to.Equal(tt.expectedTo) {
+			t.Errorf("TimeRange%+v.Times: expected %s - %s, got %s - %s",
+				tt.timeRange, tt.expectedFrom, tt.expectedTo, from, to)
+		}
+	}
+
+	if err := NewTimeRange("now-1x", "now").Validate(); err == nil {
+		t.Errorf("TimeRange.Validate: expected error for invalid from time")
+	}
+}
+
 func TestProbePanel_UnmarshalJSON(t *testing.T) {
 	data := []byte(`{
 		"description": "Panel Description",
@@ -265,3 +487,602 @@ func TestProbePanel_MarshalJSON(t *testing.T) {
 		t.Errorf("probePanel.MarshalJSON: got %s, want %s\n", got, expected)
 	}
 }
+
+func TestDashboard_MarshalJSON_GridLayout(t *testing.T) {
+	d := NewDashboard("Dashboard Title")
+	d.SchemaVersion = 16
+
+	row := NewRowPanel("Row Title")
+	row.Collapsed = true
+	row.GeneralOptions().GridPos = &panel.GridPos{H: 1, W: 24, X: 0, Y: 0}
+	p1 := panel.NewText(panel.TextPanelHTMLMode)
+	p1.GeneralOptions().GridPos = &panel.GridPos{H: 7, W: 12, X: 0, Y: 1}
+	row.Panels = []Panel{p1}
+	p2 := panel.NewText(panel.TextPanelHTMLMode)
+	p2.GeneralOptions().GridPos = &panel.GridPos{H: 7, W: 24, X: 0, Y: 1}
+	d.Panels = []Panel{row, p2}
+
+	got, err := json.MarshalIndent(&d, "", "\t")
+	if err != nil {
+		t.Fatalf("Dashboard.MarshalJSON returned error %s", err)
+	}
+	expected := []byte(`{
+		"schemaVersion": 16,
+		"editable": true,
+		"graphTooltip": 0,
+		"hideControls": false,
+		"links": null,
+		"templating": {
+			"list": []
+		},
+		"panels": [{
+			"id": 1,
+			"type": "row",
+			"collapsed": true,
+			"description": "",
+			"gridPos": {"h": 1, "w": 24, "x": 0, "y": 0},
+			"height": "",
+			"links": null,
+			"minSpan": 0,
+			"span": 0,
+			"title": "Row Title",
+			"transparent": false,
+			"panels": [{
+				"id": 2,
+				"type": "text",
+				"description": "",
+				"gridPos": {"h": 7, "w": 12, "x": 0, "y": 1},
+				"height": "",
+				"links": null,
+				"minSpan": 0,
+				"span": 0,
+				"title": "",
+				"transparent": false,
+				"content": "",
+				"mode": "html"
+			}]
+		},
+		{
+			"id": 3,
+			"type": "text",
+			"description": "",
+			"gridPos": {"h": 7, "w": 24, "x": 0, "y": 1},
+			"height": "",
+			"links": null,
+			"minSpan": 0,
+			"span": 0,
+			"title": "",
+			"transparent": false,
+			"content": "",
+			"mode": "html"
+		}],
+		"refresh": false,
+		"style": "dark",
+		"time": {"from": "now-6h", "to": "now"},
+		"timezone": "",
+		"title": "Dashboard Title",
+		"tags": []
+	}`)
+	if eq, err := JSONBytesEqual(expected, got); err != nil {
+		t.Fatalf("Dashboard.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("Dashboard.MarshalJSON: got %s, want %s\n", got, expected)
+	}
+}
+
+func TestDashboard_UnmarshalJSON_GridLayout(t *testing.T) {
+	data := []byte(`{
+		"title": "Dashboard Title",
+		"schemaVersion": 16,
+		"panels": [{
+			"id": 1,
+			"type": "row",
+			"title": "Row Title",
+			"collapsed": true,
+			"gridPos": {"h": 1, "w": 24, "x": 0, "y": 0},
+			"panels": [{
+				"id": 2,
+				"type": "text",
+				"gridPos": {"h": 7, "w": 12, "x": 0, "y": 1},
+				"mode": "html"
+			}]
+		},
+		{
+			"id": 3,
+			"type": "unknown"
+		},
+		{
+			"id": 4,
+			"type": "text",
+			"gridPos": {"h": 7, "w": 24, "x": 0, "y": 1},
+			"mode": "markdown"
+		}]
+	}`)
+	var got Dashboard
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Dashboard.UnmarshalJSON returned error %s", err)
+	}
+
+	expected := NewDashboard("Dashboard Title")
+	expected.Editable = false
+	expected.Style = ""
+	expected.SchemaVersion = 16
+	expected.Time = nil
+	row := NewRowPanel("Row Title")
+	row.Collapsed = true
+	row.GeneralOptions().GridPos = &panel.GridPos{H: 1, W: 24, X: 0, Y: 0}
+	p1 := panel.NewText(panel.TextPanelHTMLMode)
+	p1.GeneralOptions().GridPos = &panel.GridPos{H: 7, W: 12, X: 0, Y: 1}
+	row.Panels = []Panel{p1}
+	p2 := panel.NewText(panel.TextPanelMarkdownMode)
+	p2.GeneralOptions().GridPos = &panel.GridPos{H: 7, W: 24, X: 0, Y: 1}
+	expected.Panels = []Panel{row, p2}
+
+	if !reflect.DeepEqual(&got, expected) {
+		t.Errorf("Dashboard.UnmarshalJSON: %s", pretty.Diff(&got, expected))
+	}
+}
+
+func TestDashboard_ConvertRowsToGrid(t *testing.T) {
+	newText := func(span uint, height string) *panel.Text {
+		p := panel.NewText(panel.TextPanelHTMLMode)
+		p.GeneralOptions().Span = span
+		p.GeneralOptions().Height = field.ForceString(height)
+		return p
+	}
+
+	d := NewDashboard("Dashboard Title")
+	row1 := NewRow()
+	row1.ShowTitle = true
+	row1.Title = "Row 1"
+	row1.Panels = []Panel{newText(6, ""), newText(6, ""), newText(12, "")}
+	row2 := NewRow()
+	row2.Title = "Row 2"
+	row2.Collapsed = true
+	row2.Height = "300px"
+	row2.Panels = []Panel{newText(4, "500")}
+	row3 := NewRow()
+	row3.Title = "Row 3"
+	row3.Panels = []Panel{newText(0, "")}
+	d.Rows = []*Row{row1, row2, row3}
+
+	d.ConvertRowsToGrid()
+
+	if d.SchemaVersion != 16 {
+		t.Errorf("Dashboard.ConvertRowsToGrid: got schema version %d, want 16", d.SchemaVersion)
+	}
+	if d.Rows != nil {
+		t.Errorf("Dashboard.ConvertRowsToGrid: rows should be removed, got %v", d.Rows)
+	}
+
+	type item struct {
+		title   string
+		gridPos panel.GridPos
+	}
+	expected := []item{
+		{"Row 1", panel.GridPos{X: 0, Y: 0, W: 24, H: 1}},
+		{"", panel.GridPos{X: 0, Y: 1, W: 12, H: 7}},
+		{"", panel.GridPos{X: 12, Y: 1, W: 12, H: 7}},
+		{"", panel.GridPos{X: 0, Y: 8, W: 24, H: 7}},
+		{"Row 2", panel.GridPos{X: 0, Y: 15, W: 24, H: 1}},
+		{"Row 3", panel.GridPos{X: 0, Y: 16, W: 24, H: 1}},
+		{"", panel.GridPos{X: 0, Y: 17, W: 8, H: 7}},
+	}
+	var got []item
+	for _, p := range d.Panels {
+		opts := p.GeneralOptions()
+		got = append(got, item{opts.Title, *opts.GridPos})
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Dashboard.ConvertRowsToGrid: %s", pretty.Diff(got, expected))
+	}
+
+	collapsed := d.Panels[4].(*RowPanel)
+	if len(collapsed.Panels) != 1 {
+		t.Fatalf("Dashboard.ConvertRowsToGrid: collapsed row should contain 1 panel, got %d", len(collapsed.Panels))
+	}
+	want := panel.GridPos{X: 0, Y: 16, W: 8, H: 14}
+	if got := *collapsed.Panels[0].GeneralOptions().GridPos; got != want {
+		t.Errorf("Dashboard.ConvertRowsToGrid: collapsed panel got %+v, want %+v", got, want)
+	}
+}
+
+func TestProbeQuery_UnmarshalJSON(t *testing.T) {
+	ts := []struct {
+		data     string
+		expected panel.Query
+	}{
+		{`{"refId": "A", "expr": "up", "intervalFactor": 2}`, &panelQuery.Prometheus{Expression: "up", IntervalFactor: 2}},
+		{`{"refId": "A", "target": "a.b.c"}`, &panelQuery.Graphite{Target: "a.b.c"}},
+		{
+			`{"refId": "A", "query": "*", "timeField": "@timestamp", "metrics": [], "bucketAggs": []}`,
+			&panelQuery.Elasticsearch{
+				Query:      "*",
+				TimeField:  "@timestamp",
+				Metrics:    []*panelQuery.ElasticsearchMetric{},
+				BucketAggs: []*panelQuery.ElasticsearchBucketAgg{},
+			},
+		},
+		{
+			`{"refId": "A", "query": "SELECT 1", "rawQuery": true, "policy": "default", "resultFormat": "table"}`,
+			&panelQuery.InfluxDB{Query: "SELECT 1", RawQuery: true, Policy: "default", ResultFormat: panelQuery.InfluxTable},
+		},
+		{
+			`{"refId": "A", "expr": "{app=\"api\"} |= \"error\""}`,
+			&panelQuery.Loki{Expression: `{app="api"} |= "error"`},
+		},
+		{
+			`{"refId": "A", "expr": "sum(rate({app=\"api\"}[5m]))", "maxLines": 500, "resolution": 2}`,
+			&panelQuery.Loki{Expression: `sum(rate({app="api"}[5m]))`, MaxLines: 500, Resolution: 2},
+		},
+		{
+			`{"refId": "A", "scenarioId": "csv_metric_values", "stringInput": "1,20,90", "alias": "csv"}`,
+			&panelQuery.TestData{ScenarioID: panelQuery.TestDataCSVMetricValues, StringInput: "1,20,90", Alias: "csv"},
+		},
+		{
+			`{"refId": "A", "rawSql": "SELECT $__time(ts), v FROM t", "format": "time_series", "rawQuery": true}`,
+			&panelQuery.SQL{RawSQL: "SELECT $__time(ts), v FROM t", Format: panelQuery.SQLTimeSeries, RawQuery: true},
+		},
+	}
+
+	for _, tt := range ts {
+		var got probeQuery
+		if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+			t.Fatalf("probeQuery.UnmarshalJSON returned error %s", err)
+		}
+		// refIds are kept by queries
+		tt.expected.(queryWithOptions).Options().RefID = "A"
+		if !reflect.DeepEqual(got.query, tt.expected) {
+			t.Errorf("probeQuery.UnmarshalJSON(%s): %s", tt.data, pretty.Diff(got.query, tt.expected))
+		}
+	}
+}
+
+func TestProbeQuery_MarshalJSON(t *testing.T) {
+	es := panelQuery.NewElasticsearch("Elasticsearch")
+	es.Query = "status:500"
+	es.Alias = "errors"
+	influx := panelQuery.NewRawInfluxDB("InfluxDB", "SELECT 1")
+	influx.Alias = "one"
+
+	ts := []struct {
+		query    panel.Query
+		expected map[string]interface{}
+	}{
+		{es, map[string]interface{}{"refId": "A", "query": "status:500", "alias": "errors"}},
+		{influx, map[string]interface{}{"refId": "A", "query": "SELECT 1", "alias": "one", "rawQuery": true}},
+	}
+
+	for _, tt := range ts {
+		data, err := json.Marshal(&probeQuery{RefID: "A", query: tt.query})
+		if err != nil {
+			t.Fatalf("probeQuery.MarshalJSON returned error %s", err)
+		}
+
+		var got map[string]interface{}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("probeQuery.MarshalJSON returned invalid JSON %s", err)
+		}
+		for k, v := range tt.expected {
+			if !reflect.DeepEqual(got[k], v) {
+				t.Errorf("probeQuery.MarshalJSON(%T): field %q expected %v, got %v", tt.query, k, v, got[k])
+			}
+		}
+	}
+}
+
+func TestUnmarshalDashboard_Datasources(t *testing.T) {
+	data := []byte(`{
+		"templating": {"list": [{"type": "datasource", "name": "ds", "query": "influxdb"}]},
+		"panels": [
+			{"type": "graph", "datasource": "Logs", "targets": [{"refId": "A", "expr": "sum(rate({app=\"api\"}[5m]))"}]},
+			{"type": "graph", "datasource": "Metrics", "targets": [{"refId": "A", "expr": "up"}]},
+			{"type": "graph", "datasource": null, "targets": [{"refId": "A", "expr": "up", "intervalFactor": 1}]},
+			{"type": "graph", "datasource": "-- Mixed --", "targets": [
+				{"refId": "A", "datasource": "Billing", "rawSql": "SELECT 1"},
+				{"refId": "B", "target": "servers.*.cpu"},
+				{"refId": "C", "datasource": "Unknown", "target": "servers.*.mem"}
+			]},
+			{"type": "graph", "datasource": "$ds", "targets": [{"refId": "A", "query": "SELECT 1", "rawQuery": true}]}
+		]
+	}`)
+	datasources := NewDatasourceRegistry(
+		&Datasource{Name: "Logs", Type: LokiDatasource},
+		&Datasource{Name: "Metrics", Type: PrometheusDatasource},
+		&Datasource{Name: "Graphite", Type: GraphiteDatasource, IsDefault: true},
+		&Datasource{Name: "Billing", Type: PostgreSQLDatasource},
+	)
+
+	d, err := UnmarshalDashboard(data, datasources)
+	if err != nil {
+		t.Fatalf("UnmarshalDashboard returned error %s", err)
+	}
+
+	expected := [][]panel.Query{
+		{&panelQuery.Loki{Expression: `sum(rate({app="api"}[5m]))`}},
+		{&panelQuery.Prometheus{Expression: "up"}},
+		{&panelQuery.Graphite{}},
+		{
+			&panelQuery.SQL{RawSQL: "SELECT 1", Dialect: panelQuery.PostgreSQL},
+			&panelQuery.Graphite{Target: "servers.*.cpu"},
+			&panelQuery.Graphite{Target: "servers.*.mem"},
+		},
+		{&panelQuery.InfluxDB{Query: "SELECT 1", RawQuery: true}},
+	}
+	if len(d.Panels) != len(expected) {
+		t.Fatalf("UnmarshalDashboard: expected %d panels, got %d", len(expected), len(d.Panels))
+	}
+	// Known datasources are resolved, unknown ones are kept as is
+	logs := panel.DatasourceRef{Name: "Logs", Type: "loki"}
+	metrics := panel.DatasourceRef{Name: "Metrics", Type: "prometheus"}
+	billing := panel.DatasourceRef{Name: "Billing", Type: "postgres"}
+	refs := [][]panel.DatasourceRef{
+		{logs},
+		{metrics},
+		{{}},
+		{billing, {}, panel.NewDatasourceRef("Unknown")},
+		{panel.NewDatasourceRef("$ds")},
+	}
+	for i, p := range d.Panels {
+		for j, q := range expected[i] {
+			q.(queryWithOptions).Options().RefID = makeRefID(j)
+			q.(queryWithOptions).Options().SetDatasource(refs[i][j])
+		}
+		got := *p.(QueryablePanel).Queries()
+		if !reflect.DeepEqual(got, expected[i]) {
+			t.Errorf("UnmarshalDashboard: queries of panel %d: %s", i, pretty.Diff(got, expected[i]))
+		}
+	}
+}
+
+// fakePiechart is a panel of third-party plugin registered in tests.
+type fakePiechart struct {
+	PieType string `json:"pieType"`
+
+	generalOptions panel.GeneralOptions
+	queries        []panel.Query
+}
+
+func (p *fakePiechart) GeneralOptions() *panel.GeneralOptions {
+	return &p.generalOptions
+}
+
+func (p *fakePiechart) Queries() *[]panel.Query {
+	return &p.queries
+}
+
+// fakeQuery is a query of in-house datasource plugin registered in tests.
+type fakeQuery struct {
+	Metric string `json:"fakeMetric"`
+}
+
+func (q *fakeQuery) Datasource() panel.DatasourceRef {
+	return panel.DatasourceRef{}
+}
+
+func TestDashboard_RegisteredPanelAndQuery(t *testing.T) {
+	panel.Register("fake-piechart-panel", func() panel.Panel { return new(fakePiechart) })
+	panelQuery.Register("fake-datasource", func() panel.Query { return new(fakeQuery) }, "fakeMetric")
+
+	data := []byte(`{
+		"panels": [{
+			"id": 1,
+			"type": "fake-piechart-panel",
+			"title": "Pie",
+			"pieType": "donut",
+			"targets": [{"refid": "A", "fakeMetric": "requests"}]
+		}]
+	}`)
+	var d Dashboard
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatalf("Dashboard.UnmarshalJSON returned error %s", err)
+	}
+
+	expected := &fakePiechart{PieType: "donut", queries: []panel.Query{&fakeQuery{Metric: "requests"}}}
+	expected.GeneralOptions().Title = "Pie"
+	if len(d.Panels) != 1 {
+		t.Fatalf("Dashboard.UnmarshalJSON: expected 1 panel, got %d", len(d.Panels))
+	}
+	if !reflect.DeepEqual(d.Panels[0], expected) {
+		t.Errorf("Dashboard.UnmarshalJSON: %s", pretty.Diff(d.Panels[0], expected))
+	}
+
+	got, err := json.Marshal(&probePanel{ID: 1, panel: d.Panels[0]})
+	if err != nil {
+		t.Fatalf("probePanel.MarshalJSON returned error %s", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(got, &fields); err != nil {
+		t.Fatalf("probePanel.MarshalJSON returned invalid JSON %s", err)
+	}
+	if fields["type"] != "fake-piechart-panel" || fields["pieType"] != "donut" || fields["title"] != "Pie" {
+		t.Errorf("probePanel.MarshalJSON: unexpected fields of registered panel %s", got)
+	}
+	targets, _ := fields["targets"].([]interface{})
+	if len(targets) != 1 || targets[0].(map[string]interface{})["fakeMetric"] != "requests" {
+		t.Errorf("probePanel.MarshalJSON: unexpected targets of registered panel %s", got)
+	}
+}
+
+func TestMakeRefID(t *testing.T) {
+	ts := []struct {
+		index    int
+		expected string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range ts {
+		if got := makeRefID(tt.index); got != tt.expected {
+			t.Errorf("makeRefID(%d): expected %q, got %q", tt.index, tt.expected, got)
+		}
+	}
+}
+
+func TestProbePanel_MarshalJSON_RefIDs(t *testing.T) {
+	alert := panelQuery.NewPrometheus("Prometheus")
+	alert.Expression = "up"
+	alert.RefID = "C"
+	alert.Hide = true
+	duplicate := panelQuery.NewPrometheus("Prometheus")
+	duplicate.RefID = "C"
+
+	g := panel.NewGraph()
+	*g.Queries() = []panel.Query{panelQuery.NewPrometheus("Prometheus"), alert, duplicate, panelQuery.NewPrometheus("Prometheus")}
+
+	data, err := json.Marshal(&probePanel{panel: g})
+	if err != nil {
+		t.Fatalf("probePanel.MarshalJSON returned error %s", err)
+	}
+	var got struct {
+		Targets []struct {
+			RefID string `json:"refId"`
+			Hide  bool   `json:"hide"`
+		} `json:"targets"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("probePanel.MarshalJSON returned invalid JSON %s", err)
+	}
+
+	expected := []string{"A", "C", "B", "D"}
+	for i, target := range got.Targets {
+		if target.RefID != expected[i] {
+			t.Errorf("probePanel.MarshalJSON: refId of query %d: expected %q, got %q", i, expected[i], target.RefID)
+		}
+		if target.Hide != (i == 1) {
+			t.Errorf("probePanel.MarshalJSON: hide of query %d is %v", i, target.Hide)
+		}
+	}
+
+	var pp probePanel
+	if err := json.Unmarshal(data, &pp); err != nil {
+		t.Fatalf("probePanel.UnmarshalJSON returned error %s", err)
+	}
+	for i, q := range *pp.panel.(*panel.Graph).Queries() {
+		if id := q.(*panelQuery.Prometheus).RefID; id != expected[i] {
+			t.Errorf("probePanel.UnmarshalJSON: refId of query %d: expected %q, got %q", i, expected[i], id)
+		}
+	}
+}
+
+func TestDashboard_MarshalJSON_Datasources(t *testing.T) {
+	prometheus := panel.DatasourceRef{Name: "Prometheus", UID: "prom", Type: "prometheus"}
+	loki := panel.DatasourceRef{Name: "Loki", UID: "loki", Type: "loki"}
+
+	up := panelQuery.NewPrometheus("")
+	up.Expression = "up"
+	up.SetDatasource(prometheus)
+	errors := panelQuery.NewLoki("", `{app="api"} |= "error"`)
+	errors.SetDatasource(loki)
+	mixed := panel.NewGraph()
+	*mixed.Queries() = []panel.Query{up, errors}
+
+	single := panel.NewGraph()
+	*single.Queries() = []panel.Query{up}
+
+	reused := panel.NewGraph()
+	*reused.Queries() = []panel.Query{panelQuery.NewDashboard(1)}
+
+	v := NewQueryVar("instance")
+	v.Datasource = prometheus
+
+	ts := []struct {
+		schemaVersion int
+		expected      string
+	}{
+		{
+			schemaVersion: 14,
+			expected: `{
+				"panels": [
+					{"datasource": "-- Mixed --", "targets": [
+						{"refId": "A", "datasource": "Prometheus"}, {"refId": "B", "datasource": "Loki"}
+					]},
+					{"datasource": "Prometheus", "targets": [{"refId": "A"}]},
+					{"datasource": "-- Dashboard --", "targets": [{"refId": "A", "panelId": 1}]}
+				],
+				"templating": {"list": [{"datasource": "Prometheus"}]}
+			}`,
+		},
+		{
+			schemaVersion: 33,
+			expected: `{
+				"panels": [
+					{"datasource": {"type": "datasource", "uid": "-- Mixed --"}, "targets": [
+						{"refId": "A", "datasource": {"type": "prometheus", "uid": "prom"}},
+						{"refId": "B", "datasource": {"type": "loki", "uid": "loki"}}
+					]},
+					{"datasource": {"type": "prometheus", "uid": "prom"}, "targets": [
+						{"refId": "A", "datasource": {"type": "prometheus", "uid": "prom"}}
+					]},
+					{"datasource": {"type": "datasource", "uid": "-- Dashboard --"}, "targets": [
+						{"refId": "A", "panelId": 1, "datasource": {"type": "datasource", "uid": "-- Dashboard --"}}
+					]}
+				],
+				"templating": {"list": [{"datasource": {"type": "prometheus", "uid": "prom"}}]}
+			}`,
+		},
+	}
+
+	for _, tt := range ts {
+		d := NewDashboard("Datasources")
+		d.SchemaVersion = tt.schemaVersion
+		d.Panels = []Panel{mixed, single, reused}
+		d.Variables = Variables{v}
+
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Fatalf("Dashboard.MarshalJSON returned error %s", err)
+		}
+
+		// Compare datasource fields only
+		type datasources struct {
+			Panels []struct {
+				Datasource interface{} `json:"datasource"`
+				Targets    []struct {
+					RefID      string      `json:"refId"`
+					Datasource interface{} `json:"datasource,omitempty"`
+					PanelID    uint        `json:"panelId,omitempty"`
+				} `json:"targets"`
+			} `json:"panels"`
+			Templating struct {
+				List []struct {
+					Datasource interface{} `json:"datasource"`
+				} `json:"list"`
+			} `json:"templating"`
+		}
+		var got, expected datasources
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Dashboard.MarshalJSON returned invalid JSON %s", err)
+		}
+		if err := json.Unmarshal([]byte(tt.expected), &expected); err != nil {
+			t.Fatalf("invalid expected JSON %s", err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("Dashboard.MarshalJSON(schema %d): %s", tt.schemaVersion, pretty.Diff(got, expected))
+		}
+
+		// Datasources of queries are kept on round trip
+		var decoded Dashboard
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Dashboard.UnmarshalJSON returned error %s", err)
+		}
+		queries := *decoded.Panels[0].(QueryablePanel).Queries()
+		if len(queries) != 2 || !queries[0].Datasource().Equal(prometheus) || !queries[1].Datasource().Equal(loki) {
+			t.Errorf("Dashboard.UnmarshalJSON(schema %d): datasources of mixed panel are lost: %# v", tt.schemaVersion,
+				pretty.Formatter(queries))
+		}
+		if _, ok := queries[1].(*panelQuery.Loki); !ok {
+			t.Errorf("Dashboard.UnmarshalJSON(schema %d): expected Loki query, got %T", tt.schemaVersion, queries[1])
+		}
+		if _, ok := (*decoded.Panels[2].(QueryablePanel).Queries())[0].(*panelQuery.Dashboard); !ok {
+			t.Errorf("Dashboard.UnmarshalJSON(schema %d): expected Dashboard query", tt.schemaVersion)
+		}
+	}
+}
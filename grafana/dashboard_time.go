@@ -0,0 +1,343 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+// TimeRange is a default time range of the dashboard. Its ends are either relative time expressions (see
+// ParseRelativeTime) or absolute times.
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// NewTimeRange creates TimeRange with given ends.
+func NewTimeRange(from, to string) *TimeRange {
+	return &TimeRange{From: from, To: to}
+}
+
+// Validate checks that both ends of the range are valid times.
+func (r TimeRange) Validate() error {
+	if _, err := parseTime(r.From); err != nil {
+		return fmt.Errorf("invalid from time: %s", err)
+	}
+	if _, err := parseTime(r.To); err != nil {
+		return fmt.Errorf("invalid to time: %s", err)
+	}
+	return nil
+}
+
+// Times resolves the range into absolute times relative to given now. Rounded "to" time is rounded up to the end of
+// the unit, ie. now/d is the end of the current day.
+func (r TimeRange) Times(now time.Time, fiscalYearStartMonth uint8) (from, to time.Time, err error) {
+	f, err := parseTime(r.From)
+	if err != nil {
+		return from, to, fmt.Errorf("invalid from time: %s", err)
+	}
+	t, err := parseTime(r.To)
+	if err != nil {
+		return from, to, fmt.Errorf("invalid to time: %s", err)
+	}
+
+	return f(now, false, fiscalYearStartMonth), t(now, true, fiscalYearStartMonth), nil
+}
+
+type timeFunc func(now time.Time, roundUp bool, fiscalYearStartMonth uint8) time.Time
+
+// parseTime parses relative time expression, RFC 3339 time or unix timestamp in milliseconds.
+func parseTime(s string) (timeFunc, error) {
+	if strings.HasPrefix(s, "now") {
+		rt, err := ParseRelativeTime(s)
+		if err != nil {
+			return nil, err
+		}
+		return rt.Time, nil
+	}
+
+	var t time.Time
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		t = time.Unix(0, ms*int64(time.Millisecond)).UTC()
+	} else if t, err = time.Parse(time.RFC3339, s); err != nil {
+		return nil, fmt.Errorf("%q is neither relative nor absolute time", s)
+	}
+	return func(time.Time, bool, uint8) time.Time { return t }, nil
+}
+
+// TimeUnit is a unit of relative time expressions.
+type TimeUnit string
+
+// Units of relative time expressions.
+const (
+	Second  TimeUnit = "s"
+	Minute  TimeUnit = "m"
+	Hour    TimeUnit = "h"
+	Day     TimeUnit = "d"
+	Week    TimeUnit = "w"
+	Month   TimeUnit = "M"
+	Quarter TimeUnit = "Q"
+	Year    TimeUnit = "y"
+)
+
+// TimeOperation is a single operation of relative time expression: shift by Count units (+/-) or rounding to the
+// unit (/).
+type TimeOperation struct {
+	Op     byte
+	Count  int
+	Unit   TimeUnit
+	Fiscal bool // rounding to fiscal quarter or year, ie. now/fy
+}
+
+// RelativeTime is parsed relative time expression, ie. now-6h, now/d or now-1d/d.
+type RelativeTime struct {
+	Operations []TimeOperation
+}
+
+// ParseRelativeTime parses Grafana's relative time expression. Expression starts with "now" followed by any number
+// of shifts ("-6h", "+1d") and roundings ("/d", "/fy").
+func ParseRelativeTime(s string) (*RelativeTime, error) {
+	if !strings.HasPrefix(s, "now") {
+		return nil, fmt.Errorf("relative time %q should start with now", s)
+	}
+
+	rt := &RelativeTime{}
+	rest := s[len("now"):]
+	for len(rest) > 0 {
+		op := TimeOperation{Op: rest[0]}
+		rest = rest[1:]
+		switch op.Op {
+		case '+', '-':
+			i := 0
+			for i < len(rest) && rest[i] >= '0' && rest[i] <= '9' {
+				i++
+			}
+			op.Count = 1
+			if i > 0 {
+				op.Count, _ = strconv.Atoi(rest[:i])
+			}
+			rest = rest[i:]
+		case '/':
+			if strings.HasPrefix(rest, "f") {
+				op.Fiscal = true
+				rest = rest[1:]
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in relative time %q", op.Op, s)
+		}
+
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("missing unit in relative time %q", s)
+		}
+		op.Unit = TimeUnit(rest[:1])
+		rest = rest[1:]
+		if !op.Unit.valid() {
+			return nil, fmt.Errorf("unknown unit %q in relative time %q", op.Unit, s)
+		}
+		if op.Fiscal && op.Unit != Quarter && op.Unit != Year {
+			return nil, fmt.Errorf("fiscal rounding is allowed only to quarter or year in %q", s)
+		}
+
+		rt.Operations = append(rt.Operations, op)
+	}
+
+	return rt, nil
+}
+
+// String implements fmt.Stringer interface
+func (t *RelativeTime) String() string {
+	s := "now"
+	for _, op := range t.Operations {
+		s += string(op.Op)
+		switch {
+		case op.Op == '/' && op.Fiscal:
+			s += "f"
+		case op.Op != '/':
+			s += strconv.Itoa(op.Count)
+		}
+		s += string(op.Unit)
+	}
+	return s
+}
+
+// Time resolves expression into absolute time. Rounding operations round down to the start of the unit or up to the
+// end of the unit if roundUp is true. Weeks start on Sunday, fiscal year starts at given month (0 is January).
+func (t *RelativeTime) Time(now time.Time, roundUp bool, fiscalYearStartMonth uint8) time.Time {
+	result := now
+	for _, op := range t.Operations {
+		switch op.Op {
+		case '+':
+			result = shiftTime(result, op.Count, op.Unit)
+		case '-':
+			result = shiftTime(result, -op.Count, op.Unit)
+		case '/':
+			result = roundTime(result, op, roundUp, int(fiscalYearStartMonth))
+		}
+	}
+	return result
+}
+
+func (u TimeUnit) valid() bool {
+	switch u {
+	case Second, Minute, Hour, Day, Week, Month, Quarter, Year:
+		return true
+	}
+	return false
+}
+
+func shiftTime(t time.Time, count int, unit TimeUnit) time.Time {
+	switch unit {
+	case Second:
+		return t.Add(time.Duration(count) * time.Second)
+	case Minute:
+		return t.Add(time.Duration(count) * time.Minute)
+	case Hour:
+		return t.Add(time.Duration(count) * time.Hour)
+	case Day:
+		return t.AddDate(0, 0, count)
+	case Week:
+		return t.AddDate(0, 0, 7*count)
+	case Month:
+		return t.AddDate(0, count, 0)
+	case Quarter:
+		return t.AddDate(0, 3*count, 0)
+	case Year:
+		return t.AddDate(count, 0, 0)
+	}
+	return t
+}
+
+func roundTime(t time.Time, op TimeOperation, roundUp bool, fiscalYearStartMonth int) time.Time {
+	y, m, d := t.Date()
+	loc := t.Location()
+
+	var start time.Time
+	var next func(time.Time) time.Time
+	switch op.Unit {
+	case Second:
+		start = t.Truncate(time.Second)
+		next = func(t time.Time) time.Time { return t.Add(time.Second) }
+	case Minute:
+		start = time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, loc)
+		next = func(t time.Time) time.Time { return t.Add(time.Minute) }
+	case Hour:
+		start = time.Date(y, m, d, t.Hour(), 0, 0, 0, loc)
+		next = func(t time.Time) time.Time { return t.Add(time.Hour) }
+	case Day:
+		start = time.Date(y, m, d, 0, 0, 0, 0, loc)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case Week:
+		start = time.Date(y, m, d-int(t.Weekday()), 0, 0, 0, 0, loc)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	case Month:
+		start = time.Date(y, m, 1, 0, 0, 0, 0, loc)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	case Quarter, Year:
+		months := 3
+		if op.Unit == Year {
+			months = 12
+		}
+		offset := 0
+		if op.Fiscal {
+			offset = fiscalYearStartMonth
+		}
+		// Months since the beginning of the (fiscal) period
+		since := ((int(m)-1-offset)%months + months) % months
+		start = time.Date(y, m-time.Month(since), 1, 0, 0, 0, 0, loc)
+		next = func(t time.Time) time.Time { return t.AddDate(0, months, 0) }
+	default:
+		return t
+	}
+
+	if roundUp {
+		return next(start).Add(-time.Millisecond)
+	}
+	return start
+}
+
+// TimePicker represents options of dashboard's time picker. Fields not supported by the library, ie. legacy
+// "collapse" or "status", are kept as is.
+type TimePicker struct {
+	Hidden           bool     `json:"hidden"`
+	NowDelay         string   `json:"nowDelay,omitempty"`
+	RefreshIntervals []string `json:"refresh_intervals,omitempty"`
+	TimeOptions      []string `json:"time_options,omitempty"`
+
+	extra map[string]json.RawMessage
+}
+
+type jsonTimePicker TimePicker
+
+// MarshalJSON implements json.Marshaler interface
+func (p *TimePicker) MarshalJSON() ([]byte, error) {
+	return jsontools.MergeObjects(p.extra, (*jsonTimePicker)(p))
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (p *TimePicker) UnmarshalJSON(data []byte) error {
+	var jp jsonTimePicker
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+	extra, err := jsontools.ExtraFields(data, &jp)
+	if err != nil {
+		return err
+	}
+
+	*p = TimePicker(jp)
+	p.extra = extra
+	return nil
+}
+
+// RefreshInterval is an auto-refresh interval of the dashboard, ie. "5s" or "1m". Empty interval disables
+// auto-refresh.
+type RefreshInterval string
+
+// MarshalJSON implements json.Marshaler interface
+func (r RefreshInterval) MarshalJSON() ([]byte, error) {
+	if r == "" {
+		return []byte("false"), nil
+	}
+	return json.Marshal(string(r))
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (r *RefreshInterval) UnmarshalJSON(data []byte) error {
+	var val interface{}
+	if err := json.Unmarshal(data, &val); err != nil {
+		return err
+	}
+
+	// Grafana stores disabled auto-refresh as false
+	s, _ := val.(string)
+	*r = RefreshInterval(s)
+	return nil
+}
+
+// weekStart is the first day of week in dashboard's time picker
+type weekStart string
+
+// Possible values of the first day of week. Empty value means browser's locale default.
+const (
+	WeekStartDefault  weekStart = ""
+	WeekStartMonday   weekStart = "monday"
+	WeekStartSaturday weekStart = "saturday"
+	WeekStartSunday   weekStart = "sunday"
+)
@@ -15,15 +15,25 @@ package grafana
 
 import (
 	"encoding/json"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/field"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
 )
 
 type Variables []Variable
 
 // MarshalJSON implements json.Marshaler interface
 func (v Variables) MarshalJSON() ([]byte, error) {
+	return v.marshalJSON(0)
+}
+
+// marshalJSON marshals variables with datasources in the form of given schema version. Datasources are marshaled as
+// is if the version is zero.
+func (v Variables) marshalJSON(schemaVersion int) ([]byte, error) {
 	vars := make([]probeVariable, len(v))
 	for i, vv := range v {
-		vars[i] = probeVariable{variable: vv}
+		vars[i] = probeVariable{variable: vv, schemaVersion: schemaVersion}
 	}
 
 	jv := struct {
@@ -65,12 +75,15 @@ const (
 	datasourceVarType variableType = "datasource"
 	customVarType     variableType = "custom"
 	constantVarType   variableType = "constant"
+	adhocVarType      variableType = "adhoc"
+	textboxVarType    variableType = "textbox"
 )
 
 type probeVariable struct {
 	Type variableType `json:"type"`
 
-	variable Variable
+	variable      Variable
+	schemaVersion int
 }
 
 // MarshalJSON implements json.Marshaler interface
@@ -87,12 +100,14 @@ func (v *probeVariable) MarshalJSON() ([]byte, error) {
 			IntervalVariable: vv,
 		}
 	case *QueryVariable:
+		qv := *vv
+		qv.Datasource = vv.Datasource.ForSchema(v.schemaVersion)
 		jj = struct {
 			Type variableType `json:"type"`
 			*QueryVariable
 		}{
 			Type:          queryVarType,
-			QueryVariable: vv,
+			QueryVariable: &qv,
 		}
 	case *DatasourceVariable:
 		jj = struct {
@@ -118,8 +133,41 @@ func (v *probeVariable) MarshalJSON() ([]byte, error) {
 			Type:             constantVarType,
 			ConstantVariable: vv,
 		}
+	case *AdHocVariable:
+		av := *vv
+		av.Datasource = vv.Datasource.ForSchema(v.schemaVersion)
+		jj = struct {
+			Type variableType `json:"type"`
+			*AdHocVariable
+		}{
+			Type:          adhocVarType,
+			AdHocVariable: &av,
+		}
+	case *TextboxVariable:
+		jj = struct {
+			Type variableType `json:"type"`
+			*TextboxVariable
+		}{
+			Type:            textboxVarType,
+			TextboxVariable: vv,
+		}
+	case *UnknownVariable:
+		if vv.raw != nil {
+			return vv.raw, nil
+		}
+		jj = struct {
+			Type string `json:"type"`
+			*commonVarOptions
+		}{
+			Type:             vv.Type,
+			commonVarOptions: &vv.commonVarOptions,
+		}
 	}
 
+	// Fields not supported by the library are kept as is
+	if extra := v.variable.commonOptions().extra; extra != nil {
+		return jsontools.MergeObjects(extra, jj)
+	}
 	return json.Marshal(jj)
 }
 
@@ -137,7 +185,6 @@ func (v *probeVariable) UnmarshalJSON(data []byte) error {
 
 	var vv Variable
 	switch jv.Type {
-	// TODO handle unknown type
 	case queryVarType:
 		vv = new(QueryVariable)
 	case intervalVarType:
@@ -148,10 +195,23 @@ func (v *probeVariable) UnmarshalJSON(data []byte) error {
 		vv = new(CustomVariable)
 	case constantVarType:
 		vv = new(ConstantVariable)
+	case adhocVarType:
+		vv = new(AdHocVariable)
+	case textboxVarType:
+		vv = new(TextboxVariable)
+	default:
+		vv = &UnknownVariable{Type: string(jv.Type), raw: append(json.RawMessage(nil), data...)}
 	}
 	if err := json.Unmarshal(data, vv); err != nil {
 		return err
 	}
+	if _, ok := vv.(*UnknownVariable); !ok {
+		extra, err := jsontools.ExtraFields(data, &probeVariable{variable: vv})
+		if err != nil {
+			return err
+		}
+		vv.commonOptions().extra = extra
+	}
 
 	v.variable = vv
 
@@ -159,9 +219,13 @@ func (v *probeVariable) UnmarshalJSON(data []byte) error {
 }
 
 type commonVarOptions struct {
-	Name  string   `json:"name"`
-	Label string   `json:"label"`
-	Hide  hideType `json:"hide"`
+	Name    string           `json:"name"`
+	Label   string           `json:"label"`
+	Hide    hideType         `json:"hide"`
+	Current *VariableCurrent `json:"current,omitempty"`
+	Options []VariableOption `json:"options,omitempty"`
+
+	extra map[string]json.RawMessage // fields not supported by the library, ie. "definition" or "skipUrlSync"
 }
 
 type hideType uint
@@ -172,33 +236,59 @@ const (
 	HideVariable           = 2
 )
 
-// TODO: "refresh": 1, // it seems that On Dashboard Load / On Time Range change
+// VariableCurrent is current selection of the variable. Text and Value are arrays for multi-value variables.
+type VariableCurrent struct {
+	Text  field.Strings `json:"text"`
+	Value field.Strings `json:"value"`
+	Tags  []string      `json:"tags,omitempty"`
+}
+
+// NewVariableCurrent creates selection of given values. Several values are stored as arrays. Text of the values
+// is the same as values.
+func NewVariableCurrent(values ...string) *VariableCurrent {
+	return &VariableCurrent{
+		Text:  field.NewStrings(values...),
+		Value: field.NewStrings(values...),
+	}
+}
+
+// VariableOption is one of possible values of the variable.
+type VariableOption struct {
+	Selected bool   `json:"selected"`
+	Text     string `json:"text"`
+	Value    string `json:"value"`
+}
+
+type refreshType uint
+
+// Possible ways of refreshing variable's options.
+const (
+	NeverRefresh             refreshType = 0
+	RefreshOnDashboardLoad   refreshType = 1
+	RefreshOnTimeRangeChange refreshType = 2
+)
+
+// Values of "All" option of variables
+const (
+	AllVariableText  = "All"
+	AllVariableValue = "$__all"
+)
 
 type IntervalVariable struct {
-	Auto      bool   `json:"auto"`
-	StepCount uint   `json:"auto_count"`
-	Min       uint   `json:"auto_min"`
-	Query     string `json:"query"` // Values
+	Auto      bool              `json:"auto"`
+	StepCount uint              `json:"auto_count"`
+	Min       field.ForceString `json:"auto_min"` // ie. "10s"
+	Query     string            `json:"query"`    // Values
+	Refresh   refreshType       `json:"refresh"`
 
 	commonVarOptions
-	/* TODO:
-	"current": {
-		"text": "1m",
-		"value": "1m"
-	},
-	"options": [{
-		"selected": false,
-		"text": "auto",
-		"value": "$__auto_interval"
-	}]
-	*/
 }
 
 func (v IntervalVariable) varType() variableType {
 	return intervalVarType
 }
 
-func (v IntervalVariable) commonOptions() *commonVarOptions {
+func (v *IntervalVariable) commonOptions() *commonVarOptions {
 	return &v.commonVarOptions
 }
 
@@ -213,17 +303,18 @@ const (
 )
 
 type QueryVariable struct {
-	Datasource string   `json:"datasource"`
-	IncludeAll bool     `json:"includeAll"`
-	Multi      bool     `json:"multi"`
-	Query      string   `json:"query"`
-	Regex      string   `json:"regex"`
-	Sort       sortType `json:"sort"`
-	AllValue   string   `json:"allValue"`
-
-	/*
-		TODO: "refresh": 2, // it seems that On Dashboard Load / On Time Range change
-	*/
+	Datasource     panel.DatasourceRef `json:"datasource"`
+	IncludeAll     bool                `json:"includeAll"`
+	Multi          bool                `json:"multi"`
+	Query          string              `json:"query"`
+	Refresh        refreshType         `json:"refresh"`
+	Regex          string              `json:"regex"`
+	Sort           sortType            `json:"sort"`
+	AllValue       string              `json:"allValue"`
+	UseTags        bool                `json:"useTags,omitempty"`
+	TagsQuery      string              `json:"tagsQuery,omitempty"`
+	TagValuesQuery string              `json:"tagValuesQuery,omitempty"`
+
 	commonVarOptions
 }
 
@@ -240,13 +331,14 @@ func (v *QueryVariable) commonOptions() *commonVarOptions {
 }
 
 type DatasourceVariable struct {
-	Query string `json:"query"` // it's datasource name
-	Regex string `json:"regex"`
+	Query   string      `json:"query"` // it's datasource type
+	Regex   string      `json:"regex"`
+	Refresh refreshType `json:"refresh"`
 
 	commonVarOptions
 }
 
-func (v DatasourceVariable) commonOptions() *commonVarOptions {
+func (v *DatasourceVariable) commonOptions() *commonVarOptions {
 	return &v.commonVarOptions
 }
 
@@ -259,7 +351,7 @@ type CustomVariable struct {
 	commonVarOptions
 }
 
-func (v CustomVariable) commonOptions() *commonVarOptions {
+func (v *CustomVariable) commonOptions() *commonVarOptions {
 	return &v.commonVarOptions
 }
 
@@ -278,6 +370,71 @@ func NewConstantVariable(name string) *ConstantVariable {
 	}
 }
 
-func (v ConstantVariable) commonOptions() *commonVarOptions {
+func (v *ConstantVariable) commonOptions() *commonVarOptions {
+	return &v.commonVarOptions
+}
+
+// TextboxVariable is a dashboard variable of Text box type. Its value is entered by user.
+type TextboxVariable struct {
+	Query string `json:"query"` // default value
+	commonVarOptions
+}
+
+// NewTextboxVariable creates instance of TextboxVariable with given name and default value.
+func NewTextboxVariable(name, defaultValue string) *TextboxVariable {
+	return &TextboxVariable{
+		Query: defaultValue,
+		commonVarOptions: commonVarOptions{
+			Name: name,
+		},
+	}
+}
+
+func (v *TextboxVariable) commonOptions() *commonVarOptions {
+	return &v.commonVarOptions
+}
+
+// AdHocVariable is a dashboard variable of Ad hoc filters type. Its filters are applied automatically to all queries
+// of the datasource.
+type AdHocVariable struct {
+	Datasource panel.DatasourceRef `json:"datasource"`
+	Filters    []AdHocFilter       `json:"filters"`
+	commonVarOptions
+}
+
+// NewAdHocVariable creates instance of AdHocVariable with given name for given datasource.
+func NewAdHocVariable(name, datasource string) *AdHocVariable {
+	return &AdHocVariable{
+		Datasource: panel.NewDatasourceRef(datasource),
+		Filters:    []AdHocFilter{},
+		commonVarOptions: commonVarOptions{
+			Name: name,
+		},
+	}
+}
+
+func (v *AdHocVariable) commonOptions() *commonVarOptions {
+	return &v.commonVarOptions
+}
+
+// AdHocFilter is a single filter of AdHocVariable.
+type AdHocFilter struct {
+	Key       string `json:"key"`
+	Operator  string `json:"operator"` // =, !=, <, >, =~, !~
+	Value     string `json:"value"`
+	Condition string `json:"condition,omitempty"` // AND
+}
+
+// UnknownVariable is a variable of the type unknown to the library. It's marshaled back exactly as it was read, so
+// changes of its common options are not saved. Variables created in Go are marshaled with their type and common
+// options.
+type UnknownVariable struct {
+	Type string `json:"-"`
+	commonVarOptions
+
+	raw json.RawMessage
+}
+
+func (v *UnknownVariable) commonOptions() *commonVarOptions {
 	return &v.commonVarOptions
 }
@@ -17,6 +17,10 @@ import (
 	"encoding/json"
 	"reflect"
 	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/field"
 )
 
 func TestVariables_UnmarshalJSON(t *testing.T) {
@@ -31,9 +35,13 @@ func TestVariables_UnmarshalJSON(t *testing.T) {
 			"includeAll": true,
 			"multi": true,
 			"query": "up{job=\"prometheus\"}",
+			"refresh": 1,
 			"regex": "/local/",
 			"sort": 4,
-			"allValue": ".*"
+			"allValue": ".*",
+			"tagsQuery": "tags",
+			"current": {"text": "a + b", "value": ["a", "b"]},
+			"options": [{"selected": true, "text": "a", "value": "a"}]
 		}]
 	}`)
 	var got Variables
@@ -48,10 +56,15 @@ func TestVariables_UnmarshalJSON(t *testing.T) {
 	v.Query = `up{job="prometheus"}`
 	v.IncludeAll = true
 	v.Multi = true
-	v.Datasource = "Prometheus"
+	v.Datasource = panel.NewDatasourceRef("Prometheus")
 	v.AllValue = ".*"
 	v.Regex = "/local/"
 	v.Sort = NumericalDESC
+	v.Refresh = RefreshOnDashboardLoad
+	v.TagsQuery = "tags"
+	v.Current = NewVariableCurrent("a", "b")
+	v.Current.Text = field.NewStrings("a + b")
+	v.Options = []VariableOption{{Selected: true, Text: "a", Value: "a"}}
 	expected := Variables{v}
 
 	if !reflect.DeepEqual(got, expected) {
@@ -66,10 +79,12 @@ func TestVariables_MarshalJSON(t *testing.T) {
 	v.Query = `up{job="prometheus"}`
 	v.IncludeAll = true
 	v.Multi = true
-	v.Datasource = "Prometheus"
+	v.Datasource = panel.NewDatasourceRef("Prometheus")
 	v.AllValue = ".*"
 	v.Regex = "/local/"
 	v.Sort = NumericalDESC
+	v.Refresh = RefreshOnTimeRangeChange
+	v.Current = NewVariableCurrent("a")
 	variables := Variables{v}
 
 	got, err := json.MarshalIndent(variables, "", "\t\t")
@@ -86,9 +101,11 @@ func TestVariables_MarshalJSON(t *testing.T) {
 			"includeAll": true,
 			"multi": true,
 			"query": "up{job=\"prometheus\"}",
+			"refresh": 2,
 			"regex": "/local/",
 			"sort": 4,
-			"allValue": ".*"
+			"allValue": ".*",
+			"current": {"text": "a", "value": "a"}
 		}]
 	}`)
 	if eq, err := JSONBytesEqual(expected, got); err != nil {
@@ -97,3 +114,124 @@ func TestVariables_MarshalJSON(t *testing.T) {
 		t.Errorf("probeVariable.MarshalJSON: got %s, want %s\n", got, expected)
 	}
 }
+
+func TestVariables_RoundTrip(t *testing.T) {
+	data := []byte(`{
+		"list": [{
+			"name": "filters",
+			"label": "",
+			"hide": 0,
+			"type": "adhoc",
+			"datasource": "Prometheus",
+			"filters": [{"key": "job", "operator": "=", "value": "api"}]
+		}, {
+			"name": "text",
+			"label": "Text",
+			"description": "Free text",
+			"hide": 0,
+			"skipUrlSync": true,
+			"type": "textbox",
+			"query": "default",
+			"current": {"text": "value", "value": "value"}
+		}, {
+			"name": "interval",
+			"label": "",
+			"hide": 0,
+			"type": "interval",
+			"definition": "",
+			"auto": true,
+			"auto_count": 30,
+			"auto_min": "10s",
+			"query": "1m,10m",
+			"refresh": 2,
+			"current": {"text": "1m", "value": "1m"},
+			"options": [{"selected": false, "text": "auto", "value": "$__auto_interval_interval"}]
+		}, {
+			"name": "unknown",
+			"type": "plugin",
+			"someOption": {"nested": true}
+		}]
+	}`)
+	var got Variables
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Variables.UnmarshalJSON returned error %s", err)
+	}
+
+	adhoc := NewAdHocVariable("filters", "Prometheus")
+	adhoc.Filters = []AdHocFilter{{Key: "job", Operator: "=", Value: "api"}}
+	textbox := NewTextboxVariable("text", "default")
+	textbox.Label = "Text"
+	textbox.Current = NewVariableCurrent("value")
+	textbox.extra = map[string]json.RawMessage{
+		"description": json.RawMessage(`"Free text"`),
+		"skipUrlSync": json.RawMessage(`true`),
+	}
+	if !reflect.DeepEqual(got[0], adhoc) {
+		t.Errorf("Variables.UnmarshalJSON: %s", pretty.Diff(got[0], adhoc))
+	}
+	if !reflect.DeepEqual(got[1], textbox) {
+		t.Errorf("Variables.UnmarshalJSON: %s", pretty.Diff(got[1], textbox))
+	}
+	if interval, ok := got[2].(*IntervalVariable); !ok || interval.Min != "10s" {
+		t.Errorf("Variables.UnmarshalJSON: expected interval variable, got %+v", got[2])
+	}
+	unknown, ok := got[3].(*UnknownVariable)
+	if !ok {
+		t.Fatalf("Variables.UnmarshalJSON: expected unknown variable, got %+v", got[3])
+	}
+	if unknown.Type != "plugin" || unknown.commonOptions().Name != "unknown" {
+		t.Errorf("Variables.UnmarshalJSON: unexpected unknown variable %+v", unknown)
+	}
+
+	marshaled, err := json.Marshal(got)
+	if err != nil {
+		t.Fatalf("Variables.MarshalJSON returned error %s", err)
+	}
+	if eq, err := JSONBytesEqual(data, marshaled); err != nil {
+		t.Fatalf("Variables.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("Variables.MarshalJSON: got %s, want %s\n", marshaled, data)
+	}
+}
+
+func TestUnknownVariable_MarshalJSON(t *testing.T) {
+	v := &UnknownVariable{Type: "plugin"}
+	v.Name = "unknown"
+	v.Current = NewVariableCurrent("a")
+
+	got, err := json.Marshal(Variables{v})
+	if err != nil {
+		t.Fatalf("Variables.MarshalJSON returned error %s", err)
+	}
+	expected := []byte(`{"list": [{
+		"type": "plugin",
+		"name": "unknown",
+		"label": "",
+		"hide": 0,
+		"current": {"text": "a", "value": "a"}
+	}]}`)
+	if eq, err := JSONBytesEqual(expected, got); err != nil {
+		t.Fatalf("Variables.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("Variables.MarshalJSON: got %s, want %s\n", got, expected)
+	}
+}
+
+func TestVariable_CommonOptions(t *testing.T) {
+	vars := Variables{
+		&IntervalVariable{},
+		NewQueryVar(""),
+		&DatasourceVariable{},
+		&CustomVariable{},
+		NewConstantVariable(""),
+		NewTextboxVariable("", ""),
+		NewAdHocVariable("", ""),
+	}
+
+	for _, v := range vars {
+		v.commonOptions().Name = "changed"
+		if got := v.commonOptions().Name; got != "changed" {
+			t.Errorf("%T.commonOptions() should return pointer to variable's options, got name %q", v, got)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// DatasourceWalkFunc is called by WalkQueryDatasources for every reference to datasource with its path, ie.
+// "panels[0].targets[1].datasource". The reference is replaced by the returned one.
+type DatasourceWalkFunc func(path string, ref panel.DatasourceRef) (panel.DatasourceRef, error)
+
+// WalkQueryDatasources calls fn for datasources of queries of dashboard with given JSON and returns the JSON with
+// references replaced by ones returned by fn. Datasources of panels, their queries, query and ad hoc variables and
+// annotations are walked, including panels of rows and collapsed row panels. Queries without datasource use the
+// datasource of their panel, so they are walked only if they override it. Replaced references are in the form of
+// dashboard's schema version, other fields are kept as is, so panels of unregistered types are walked too.
+//
+// Errors of fn are returned as validate.Errors with paths of the references.
+func WalkQueryDatasources(data []byte, fn DatasourceWalkFunc) ([]byte, error) {
+	// Numbers are decoded as is, so ids and thresholds are not rounded
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var root map[string]interface{}
+	if err := dec.Decode(&root); err != nil {
+		return nil, err
+	}
+
+	w := &datasourceWalker{fn: fn}
+	if v, ok := root["schemaVersion"].(json.Number); ok {
+		version, _ := v.Int64()
+		w.schemaVersion = int(version)
+	}
+
+	for i, v := range objectList(root["templating"]) {
+		switch variableType(stringField(v, "type")) {
+		case queryVarType, adhocVarType:
+			w.walk(validate.Join("templating", validate.Index("list", i)), v, true)
+		}
+	}
+	for i, a := range objectList(root["annotations"]) {
+		w.walk(validate.Join("annotations", validate.Index("list", i)), a, false)
+	}
+	if rows, ok := root["rows"].([]interface{}); ok {
+		for i, r := range rows {
+			if row, ok := r.(map[string]interface{}); ok {
+				w.walkPanels(validate.Index("rows", i), row["panels"])
+			}
+		}
+	}
+	w.walkPanels("", root["panels"])
+
+	if err := w.errs.Err(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(root)
+}
+
+// datasourceWalker replaces datasources of decoded dashboard's JSON.
+type datasourceWalker struct {
+	fn            DatasourceWalkFunc
+	schemaVersion int
+	errs          validate.Errors
+}
+
+func (w *datasourceWalker) walkPanels(path string, v interface{}) {
+	panels, _ := v.([]interface{})
+	for i, p := range panels {
+		o, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		panelPath := validate.Join(path, validate.Index("panels", i))
+
+		// Panels without queries, ie. text panels and rows, don't use the default datasource
+		targets, _ := o["targets"].([]interface{})
+		w.walk(panelPath, o, len(targets) > 0)
+		for j, t := range targets {
+			if target, ok := t.(map[string]interface{}); ok {
+				w.walk(validate.Join(panelPath, validate.Index("targets", j)), target, false)
+			}
+		}
+
+		// Panels of collapsed rows
+		w.walkPanels(panelPath, o["panels"])
+	}
+}
+
+// walk replaces datasource of given object. Missing datasource references the default one, it's walked only if
+// implicit is true.
+func (w *datasourceWalker) walk(path string, o map[string]interface{}, implicit bool) {
+	v, ok := o["datasource"]
+	if !ok && !implicit {
+		return
+	}
+	path = validate.Join(path, "datasource")
+
+	var ref panel.DatasourceRef
+	if ok {
+		if err := convertJSON(v, &ref); err != nil {
+			w.errs.Add(path, err)
+			return
+		}
+	}
+	replaced, err := w.fn(path, ref)
+	if err != nil {
+		w.errs.Add(path, err)
+		return
+	}
+	if replaced == ref {
+		return
+	}
+
+	var value interface{}
+	if err := convertJSON(replaced.ForSchema(w.schemaVersion), &value); err != nil {
+		w.errs.Add(path, err)
+		return
+	}
+	o["datasource"] = value
+}
+
+// objectList returns objects of "list" field of given object, ie. of "templating" or "annotations".
+func objectList(v interface{}) []map[string]interface{} {
+	o, _ := v.(map[string]interface{})
+	list, _ := o["list"].([]interface{})
+	objects := make([]map[string]interface{}, len(list))
+	for i, item := range list {
+		objects[i], _ = item.(map[string]interface{})
+	}
+	return objects
+}
+
+func stringField(o map[string]interface{}, name string) string {
+	s, _ := o[name].(string)
+	return s
+}
+
+// convertJSON converts value to given type by encoding it into JSON and decoding back.
+func convertJSON(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+func TestWalkQueryDatasources(t *testing.T) {
+	data := []byte(`{
+		"schemaVersion": 36,
+		"annotations": {"list": [
+			{"name": "Annotations & Alerts", "builtIn": 1, "datasource": {"type": "grafana", "uid": "-- Grafana --"}}
+		]},
+		"templating": {"list": [
+			{"type": "query", "name": "job", "datasource": {"type": "prometheus", "uid": "prom"}},
+			{"type": "custom", "name": "env", "query": "prod,staging"}
+		]},
+		"panels": [
+			{"type": "stat", "datasource": {"type": "prometheus", "uid": "prom"}, "targets": [
+				{"refId": "A", "expr": "up"},
+				{"refId": "B", "datasource": {"type": "loki", "uid": "loki"}, "expr": "{job=\"api\"}"}
+			]},
+			{"type": "row", "collapsed": true, "panels": [
+				{"type": "grafana-worldmap-panel", "threshold": 12345678901234567890, "targets": [{"refId": "A"}]}
+			]}
+		]
+	}`)
+
+	var paths []string
+	got, err := WalkQueryDatasources(data, func(path string, ref panel.DatasourceRef) (panel.DatasourceRef, error) {
+		paths = append(paths, path)
+		switch {
+		case ref.UID == "prom":
+			return panel.DatasourceRef{Name: "Prometheus", UID: "P1", Type: "prometheus"}, nil
+		case ref.IsDefault():
+			return panel.NewDatasourceVariableRef("ds"), nil
+		}
+		return ref, nil
+	})
+	if err != nil {
+		t.Fatalf("WalkQueryDatasources returned error: %v", err)
+	}
+
+	wantPaths := []string{
+		"templating.list[0].datasource",
+		"annotations.list[0].datasource",
+		"panels[0].datasource",
+		"panels[0].targets[1].datasource",
+		"panels[1].panels[0].datasource",
+	}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("WalkQueryDatasources walked %v, want %v", paths, wantPaths)
+	}
+
+	// References are replaced in the form of schema version, other fields are kept as is
+	want := []byte(`{
+		"schemaVersion": 36,
+		"annotations": {"list": [
+			{"name": "Annotations & Alerts", "builtIn": 1, "datasource": {"type": "grafana", "uid": "-- Grafana --"}}
+		]},
+		"templating": {"list": [
+			{"type": "query", "name": "job", "datasource": {"type": "prometheus", "uid": "P1"}},
+			{"type": "custom", "name": "env", "query": "prod,staging"}
+		]},
+		"panels": [
+			{"type": "stat", "datasource": {"type": "prometheus", "uid": "P1"}, "targets": [
+				{"refId": "A", "expr": "up"},
+				{"refId": "B", "datasource": {"type": "loki", "uid": "loki"}, "expr": "{job=\"api\"}"}
+			]},
+			{"type": "row", "collapsed": true, "panels": [
+				{"type": "grafana-worldmap-panel", "threshold": 12345678901234567890, "targets": [{"refId": "A"}],
+					"datasource": {"uid": "${ds}"}}
+			]}
+		]
+	}`)
+	if eq, err := jsontools.BytesEqual(got, want); err != nil || !eq {
+		t.Errorf("WalkQueryDatasources returned\n%s\nwant\n%s", got, want)
+	}
+	if !strings.Contains(string(got), "12345678901234567890") {
+		t.Errorf("WalkQueryDatasources changed numbers: %s", got)
+	}
+
+	_, err = WalkQueryDatasources(data, func(path string, ref panel.DatasourceRef) (panel.DatasourceRef, error) {
+		if ref.Type == "loki" {
+			return ref, errors.New("unknown datasource")
+		}
+		return ref, nil
+	})
+	if err == nil || err.Error() != "panels[0].targets[1].datasource: unknown datasource" {
+		t.Errorf("WalkQueryDatasources returned error %v", err)
+	}
+}
@@ -13,7 +13,11 @@
 
 package grafana
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+)
 
 type (
 	// DatasourceID represents id type of datasource
@@ -33,8 +37,16 @@ type datasourceType string
 
 // Types of datasource
 const (
-	GraphiteDatasource   datasourceType = "graphite"
-	PrometheusDatasource datasourceType = "prometheus"
+	GraphiteDatasource      datasourceType = "graphite"
+	PrometheusDatasource    datasourceType = "prometheus"
+	ElasticsearchDatasource datasourceType = "elasticsearch"
+	InfluxDBDatasource      datasourceType = "influxdb"
+	MySQLDatasource         datasourceType = "mysql"
+	PostgreSQLDatasource    datasourceType = "postgres"
+	MSSQLDatasource         datasourceType = "mssql"
+	LokiDatasource          datasourceType = "loki"
+	TestDataDatasource      datasourceType = "testdata"
+	DashboardDatasource     datasourceType = "dashboard" // queries reusing results of another panel
 )
 
 // Datasource represents datasource entity of Grafana.
@@ -42,6 +54,7 @@ type Datasource struct {
 	id    DatasourceID
 	OrgID OrgID `json:"orgId"`
 
+	UID               string         `json:"uid,omitempty"`
 	Name              string         `json:"name"`
 	Type              datasourceType `json:"type"`
 	Access            httpAccessType `json:"access"`
@@ -55,8 +68,12 @@ type Datasource struct {
 	IsDefault         bool           `json:"isDefault"`
 	WithCredentials   bool           `json:"withCredentials"`
 
-	// SecureJsonData    securejsondata.SecureJsonData
-	// JsonData          *simplejson.Json
+	// JSONData holds type specific settings of the datasource, ie. timeInterval or httpMethod.
+	JSONData map[string]interface{} `json:"jsonData,omitempty"`
+	// SecureJSONFields lists secure settings of the datasource which are set. Their values are never returned
+	// by Grafana.
+	SecureJSONFields map[string]bool `json:"secureJsonFields,omitempty"`
+
 	// "typeLogoUrl": "",
 
 }
@@ -92,3 +109,55 @@ func (d *Datasource) UnmarshalJSON(data []byte) error {
 
 	return json.Unmarshal(data, &jd)
 }
+
+// DatasourceRegistry resolves datasources referenced by dashboards. It's used to decode panels' queries into types
+// of their datasources.
+type DatasourceRegistry struct {
+	byName      map[string]panel.DatasourceRef
+	byUID       map[string]panel.DatasourceRef
+	defaultName string
+}
+
+// NewDatasourceRegistry creates registry of given datasources, ie. fetched from Grafana.
+func NewDatasourceRegistry(datasources ...*Datasource) *DatasourceRegistry {
+	r := &DatasourceRegistry{
+		byName: make(map[string]panel.DatasourceRef),
+		byUID:  make(map[string]panel.DatasourceRef),
+	}
+	for _, d := range datasources {
+		r.Add(d)
+	}
+	return r
+}
+
+// Add adds given datasource to the registry. Default datasource is used by panels and queries without datasource.
+func (r *DatasourceRegistry) Add(d *Datasource) {
+	ref := panel.DatasourceRef{Name: d.Name, UID: d.UID, Type: string(d.Type)}
+	r.byName[d.Name] = ref
+	if d.UID != "" {
+		r.byUID[d.UID] = ref
+	}
+	if d.IsDefault {
+		r.defaultName = d.Name
+	}
+}
+
+// Resolve returns reference to given datasource with its name, uid and type. Reference to the default datasource
+// is resolved to the datasource marked as default. It returns false if the datasource is unknown.
+func (r *DatasourceRegistry) Resolve(ref panel.DatasourceRef) (panel.DatasourceRef, bool) {
+	if ref.IsDefault() {
+		resolved, ok := r.byName[r.defaultName]
+		return resolved, ok && r.defaultName != ""
+	}
+	if resolved, ok := r.byUID[ref.UID]; ok && ref.UID != "" {
+		return resolved, true
+	}
+	if resolved, ok := r.byName[ref.Name]; ok && ref.Name != "" {
+		return resolved, true
+	}
+	// Unknown datasources are referenced by names as uids since schema version 33
+	if resolved, ok := r.byName[ref.UID]; ok && ref.UID != "" {
+		return resolved, true
+	}
+	return panel.DatasourceRef{}, false
+}
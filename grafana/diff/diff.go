@@ -0,0 +1,415 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff compares dashboards structurally. Unlike comparison of their JSON line by line, items of lists are
+// matched by their identity: rows and panels by titles (ids of untitled panels), queries by refIds, variables and
+// annotations by names. So reordered panels are reported as moved and changes inside them have paths of the panels.
+//
+// Reports are rendered as text, unified diff of dashboards' JSON or JSON report suitable for PR comments.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/spoof/go-grafana/grafana"
+)
+
+type changeOp string
+
+// Operations of changes
+const (
+	Added   changeOp = "added"
+	Removed changeOp = "removed"
+	Changed changeOp = "changed"
+	Moved   changeOp = "moved"
+)
+
+// Change is a single difference of compared documents.
+type Change struct {
+	Op      changeOp    `json:"op"`
+	Path    string      `json:"path"`              // ie. "rows[1].panels[0].span". Path of old document for removed items.
+	OldPath string      `json:"oldPath,omitempty"` // previous path of moved item
+	Item    string      `json:"item,omitempty"`    // added, removed or moved item, ie. `panel "Requests"`
+	Old     interface{} `json:"old,omitempty"`
+	New     interface{} `json:"new,omitempty"`
+}
+
+// String returns human readable description of the change, ie. `~ rows[0].panels[1].span: 4 => 6`.
+func (c Change) String() string {
+	item := ""
+	if c.Item != "" {
+		item = " (" + c.Item + ")"
+	}
+
+	switch c.Op {
+	case Added:
+		if item != "" {
+			return "+ " + c.Path + item
+		}
+		return "+ " + c.Path + ": " + FormatValue(c.New)
+	case Removed:
+		if item != "" {
+			return "- " + c.Path + item
+		}
+		return "- " + c.Path + ": " + FormatValue(c.Old)
+	case Moved:
+		return "> " + c.Path + item + " moved from " + c.OldPath
+	}
+	return "~ " + c.Path + ": " + FormatValue(c.Old) + " => " + FormatValue(c.New)
+}
+
+// maxValueLength is a maximum length of values formatted by FormatValue
+const maxValueLength = 80
+
+// FormatValue formats decoded JSON value as compact JSON. Long values are truncated.
+func FormatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(data) > maxValueLength {
+		return string(data[:maxValueLength-3]) + "..."
+	}
+	return string(data)
+}
+
+// Report is a result of comparison of two documents.
+type Report struct {
+	Changes []Change
+
+	OldName string // names of compared documents shown by unified diff, "old" and "new" by default
+	NewName string
+
+	old, new interface{}
+}
+
+// Empty returns true if documents are equal.
+func (r *Report) Empty() bool {
+	return len(r.Changes) == 0
+}
+
+// Dashboards compares two dashboards.
+func Dashboards(old, new *grafana.Dashboard) (*Report, error) {
+	oldData, err := json.Marshal(old)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := json.Marshal(new)
+	if err != nil {
+		return nil, err
+	}
+	return JSON(oldData, newData)
+}
+
+// JSON compares two JSON documents, ie. files of dashboards. Lists of dashboards' items are compared by identity of
+// the items, other values are compared field by field and element by element.
+func JSON(old, new []byte) (*Report, error) {
+	r := &Report{OldName: "old", NewName: "new"}
+	if err := json.Unmarshal(old, &r.old); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(new, &r.new); err != nil {
+		return nil, err
+	}
+	r.Changes = Values(r.old, r.new)
+	return r, nil
+}
+
+// Values compares two decoded JSON values the same way as JSON does it.
+func Values(old, new interface{}) []Change {
+	var changes []Change
+	compare(&changes, "", "", old, new)
+	return changes
+}
+
+// list is a kind of dashboard's list whose items are matched by identity
+type list struct {
+	item string                              // name of the item, ie. "panel"
+	key  func(map[string]interface{}) string // identity of the item
+}
+
+var (
+	rowList = list{"row", func(o map[string]interface{}) string {
+		return stringField(o, "title")
+	}}
+	panelList = list{"panel", func(o map[string]interface{}) string {
+		if title := stringField(o, "title"); title != "" {
+			return title
+		}
+		if id, ok := o["id"]; ok {
+			return "#" + FormatValue(id)
+		}
+		return ""
+	}}
+	queryList = list{"query", func(o map[string]interface{}) string {
+		return stringField(o, "refId")
+	}}
+	namedList = func(item string) list {
+		return list{item, func(o map[string]interface{}) string {
+			return stringField(o, "name")
+		}}
+	}
+)
+
+// listOf returns kind of the list with given path or false if its items are compared by position.
+func listOf(parent, field string) (list, bool) {
+	switch {
+	case field == "panels":
+		return panelList, true
+	case field == "rows" && parent == "":
+		return rowList, true
+	case field == "targets":
+		return queryList, true
+	case field == "list" && parent == "templating":
+		return namedList("variable"), true
+	case field == "list" && parent == "annotations":
+		return namedList("annotation"), true
+	}
+	return list{}, false
+}
+
+func stringField(o map[string]interface{}, name string) string {
+	s, _ := o[name].(string)
+	return s
+}
+
+func join(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// compare compares old and new values with given path. Field is a name of the value in its parent object.
+func compare(changes *[]Change, path, field string, old, new interface{}) {
+	switch o := old.(type) {
+	case map[string]interface{}:
+		n, ok := new.(map[string]interface{})
+		if !ok {
+			break
+		}
+		compareObjects(changes, path, o, n)
+		return
+	case []interface{}:
+		n, ok := new.([]interface{})
+		if !ok {
+			break
+		}
+		if l, ok := listOf(parentField(path, field), field); ok {
+			compareLists(changes, path, l, o, n)
+			return
+		}
+		for i := 0; i < len(o) || i < len(n); i++ {
+			switch {
+			case i >= len(o):
+				*changes = append(*changes, Change{Op: Added, Path: index(path, i), New: n[i]})
+			case i >= len(n):
+				*changes = append(*changes, Change{Op: Removed, Path: index(path, i), Old: o[i]})
+			default:
+				compare(changes, index(path, i), "", o[i], n[i])
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Op: Changed, Path: path, Old: old, New: new})
+	}
+}
+
+// parentField returns name of the object containing field with given path, ie. "templating" for "templating.list".
+func parentField(path, field string) string {
+	if field == "" {
+		return ""
+	}
+	parent := path[:len(path)-len(field)]
+	if parent == "" {
+		return ""
+	}
+	parent = parent[:len(parent)-1] // trailing dot
+	for i := len(parent) - 1; i >= 0; i-- {
+		if parent[i] == '.' || parent[i] == ']' {
+			return parent[i+1:]
+		}
+	}
+	return parent
+}
+
+func compareObjects(changes *[]Change, path string, old, new map[string]interface{}) {
+	keys := make([]string, 0, len(old)+len(new))
+	for k := range old {
+		keys = append(keys, k)
+	}
+	for k := range new {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fieldPath := join(path, k)
+		ov, inOld := old[k]
+		nv, inNew := new[k]
+		switch {
+		case !inOld:
+			*changes = append(*changes, Change{Op: Added, Path: fieldPath, New: nv})
+		case !inNew:
+			*changes = append(*changes, Change{Op: Removed, Path: fieldPath, Old: ov})
+		default:
+			compare(changes, fieldPath, k, ov, nv)
+		}
+	}
+}
+
+// compareLists compares lists whose items are matched by identity. Matched items are compared recursively, items
+// of old list without match are removed, items of new list without match are added. Items without identity are
+// matched in their order. Ids of panels are not compared, they are generated on save.
+func compareLists(changes *[]Change, path string, l list, old, new []interface{}) {
+	oldKeys, newKeys := listKeys(l, old), listKeys(l, new)
+
+	oldByKey := make(map[string]int)
+	for i, k := range oldKeys {
+		if _, dup := oldByKey[k]; dup {
+			oldByKey[k] = -1 // ambiguous identity
+		} else {
+			oldByKey[k] = i
+		}
+	}
+
+	matches := make([]int, len(new)) // index of matched old item for every new item or -1
+	matched := make([]bool, len(old))
+	for i, k := range newKeys {
+		matches[i] = -1
+		if j, ok := oldByKey[k]; ok && k != "" && j >= 0 && !matched[j] {
+			matches[i] = j
+			matched[j] = true
+		}
+	}
+	// Items without identity are matched in order
+	j := 0
+	for i, k := range newKeys {
+		if k != "" || matches[i] >= 0 {
+			continue
+		}
+		for j < len(old) && (matched[j] || oldKeys[j] != "") {
+			j++
+		}
+		if j < len(old) {
+			matches[i] = j
+			matched[j] = true
+		}
+	}
+
+	inOrder := increasingSubsequence(matches)
+	for i, item := range new {
+		itemPath := index(path, i)
+		m := matches[i]
+		if m < 0 {
+			*changes = append(*changes, Change{Op: Added, Path: itemPath, Item: describe(l, newKeys[i]), New: item})
+			continue
+		}
+		if !inOrder[i] {
+			*changes = append(*changes, Change{Op: Moved, Path: itemPath, OldPath: index(path, m),
+				Item: describe(l, newKeys[i])})
+		}
+
+		o, n := old[m], item
+		if l.item == "panel" {
+			o, n = withoutID(o), withoutID(n)
+		}
+		compare(changes, itemPath, "", o, n)
+	}
+	for j, item := range old {
+		if !matched[j] {
+			*changes = append(*changes, Change{Op: Removed, Path: index(path, j), Item: describe(l, oldKeys[j]),
+				Old: item})
+		}
+	}
+}
+
+func listKeys(l list, items []interface{}) []string {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		if o, ok := item.(map[string]interface{}); ok {
+			keys[i] = l.key(o)
+		}
+	}
+	return keys
+}
+
+func describe(l list, key string) string {
+	switch {
+	case key == "":
+		return l.item
+	case key[0] == '#':
+		return l.item + " " + key
+	case l.item == "query":
+		return l.item + " " + key
+	}
+	return l.item + " " + strconv.Quote(key)
+}
+
+func withoutID(item interface{}) interface{} {
+	o, ok := item.(map[string]interface{})
+	if !ok {
+		return item
+	}
+	copied := make(map[string]interface{}, len(o))
+	for k, v := range o {
+		if k != "id" {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+// increasingSubsequence returns which of matched items keep their relative order: they form the longest increasing
+// subsequence of old indexes. Other matched items are moved. Later items win ties, so the item moved to the top is
+// reported rather than the items it jumped over.
+func increasingSubsequence(matches []int) []bool {
+	n := len(matches)
+	length := make([]int, n)
+	prev := make([]int, n)
+	best := -1
+	for i := 0; i < n; i++ {
+		prev[i] = -1
+		if matches[i] < 0 {
+			continue
+		}
+		length[i] = 1
+		for j := 0; j < i; j++ {
+			if matches[j] >= 0 && matches[j] < matches[i] && length[j]+1 > length[i] {
+				length[i] = length[j] + 1
+				prev[i] = j
+			}
+		}
+		if best < 0 || length[i] >= length[best] {
+			best = i
+		}
+	}
+
+	inOrder := make([]bool, n)
+	for i := best; i >= 0; i = prev[i] {
+		inOrder[i] = true
+	}
+	return inOrder
+}
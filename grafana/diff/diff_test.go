@@ -0,0 +1,149 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/diff"
+)
+
+func TestDashboards(t *testing.T) {
+	var old, new grafana.Dashboard
+	if err := json.Unmarshal([]byte(`{
+		"title": "API",
+		"rows": [{"title": "Overview", "panels": [
+			{"type": "graph", "title": "Requests", "span": 4, "targets": [
+				{"refId": "A", "expr": "rate(requests_total[1m])"},
+				{"refId": "B", "expr": "up"}
+			]},
+			{"type": "graph", "title": "Errors", "span": 4},
+			{"type": "graph", "title": "Latency", "span": 4}
+		]}],
+		"templating": {"list": [{"type": "query", "name": "instance"}, {"type": "query", "name": "job"}]}
+	}`), &old); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err)
+	}
+	if err := json.Unmarshal([]byte(`{
+		"title": "API",
+		"rows": [{"title": "Overview", "panels": [
+			{"type": "graph", "title": "Latency", "span": 4},
+			{"type": "graph", "title": "Requests", "span": 6, "targets": [
+				{"refId": "A", "expr": "rate(requests_total[$__rate_interval])"},
+				{"refId": "B", "expr": "up"}
+			]},
+			{"type": "graph", "title": "Saturation", "span": 4}
+		]}],
+		"templating": {"list": [{"type": "query", "name": "job", "query": "label_values(job)"}]}
+	}`), &new); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err)
+	}
+
+	r, err := diff.Dashboards(&old, &new)
+	if err != nil {
+		t.Fatalf("Dashboards returned error %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := r.Write(&out, diff.TextFormat); err != nil {
+		t.Fatalf("Report.Write returned error %s", err)
+	}
+	expected := `> rows[0].panels[0] (panel "Latency") moved from rows[0].panels[2]
+~ rows[0].panels[1].span: 4 => 6
+~ rows[0].panels[1].targets[0].expr: "rate(requests_total[1m])" => "rate(requests_total[$__rate_interval])"
++ rows[0].panels[2] (panel "Saturation")
+- rows[0].panels[1] (panel "Errors")
+~ templating.list[0].query: "" => "label_values(job)"
+- templating.list[0] (variable "instance")
+`
+	if out.String() != expected {
+		t.Errorf("Report.Write(text): expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	if s := r.Summary(); s != (diff.Summary{Added: 1, Removed: 2, Changed: 3, Moved: 1}) {
+		t.Errorf("Report.Summary: unexpected %+v", s)
+	}
+
+	if r, _ := diff.Dashboards(&old, &old); !r.Empty() {
+		t.Errorf("Dashboards: expected no changes of the same dashboard, got %v", r.Changes)
+	}
+}
+
+func TestJSON_Unified(t *testing.T) {
+	old := `{"title": "API", "panels": [{"id": 1, "title": "A", "span": 4}, {"id": 2, "title": "B"}], "version": 1}`
+	new := `{"title": "API", "panels": [{"id": 1, "title": "A", "span": 6}, {"id": 2, "title": "B"}], "version": 1}`
+
+	r, err := diff.JSON([]byte(old), []byte(new))
+	if err != nil {
+		t.Fatalf("JSON returned error %s", err)
+	}
+	r.OldName, r.NewName = "live", "api.json"
+
+	var out bytes.Buffer
+	if err := r.Write(&out, diff.UnifiedFormat); err != nil {
+		t.Fatalf("Report.Write returned error %s", err)
+	}
+	expected := `--- live
++++ api.json
+@@ -2,7 +2,7 @@
+   "panels": [
+     {
+       "id": 1,
+-      "span": 4,
++      "span": 6,
+       "title": "A"
+     },
+     {
+`
+	if out.String() != expected {
+		t.Errorf("Report.Write(unified): expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestJSON_Report(t *testing.T) {
+	old := `{"templating": {"list": [{"name": "a"}]}, "tags": ["x"]}`
+	new := `{"templating": {"list": [{"name": "b"}]}, "tags": ["x", "y"]}`
+
+	r, err := diff.JSON([]byte(old), []byte(new))
+	if err != nil {
+		t.Fatalf("JSON returned error %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := r.Write(&out, diff.JSONFormat); err != nil {
+		t.Fatalf("Report.Write returned error %s", err)
+	}
+	var report struct {
+		Summary diff.Summary  `json:"summary"`
+		Changes []diff.Change `json:"changes"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Report.Write(json) returned invalid JSON %s", err)
+	}
+	if report.Summary != (diff.Summary{Added: 2, Removed: 1}) {
+		t.Errorf("Report.Write(json): unexpected summary %+v", report.Summary)
+	}
+	paths := make([]string, len(report.Changes))
+	for i, c := range report.Changes {
+		paths[i] = string(c.Op) + " " + c.Path + " " + c.Item
+	}
+	expected := "added tags[1] ,added templating.list[0] variable \"b\",removed templating.list[0] variable \"a\""
+	if got := strings.Join(paths, ","); got != expected {
+		t.Errorf("Report.Write(json): expected changes %s, got %s", expected, got)
+	}
+}
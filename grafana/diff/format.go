@@ -0,0 +1,305 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type format string
+
+// Formats of reports
+const (
+	TextFormat    format = "text"    // list of changes, one per line
+	UnifiedFormat format = "unified" // unified diff of indented JSON of documents
+	JSONFormat    format = "json"    // JSON report with changes and their summary
+)
+
+// ParseFormat parses format of report from its name.
+func ParseFormat(s string) (format, error) {
+	switch f := format(s); f {
+	case TextFormat, UnifiedFormat, JSONFormat:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q", s)
+}
+
+// Write writes the report in given format into w.
+func (r *Report) Write(w io.Writer, f format) error {
+	switch f {
+	case TextFormat:
+		return r.writeText(w)
+	case UnifiedFormat:
+		return r.writeUnified(w)
+	case JSONFormat:
+		return r.writeJSON(w)
+	}
+	return fmt.Errorf("unknown format %q", f)
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, c := range r.Changes {
+		fmt.Fprintln(bw, c.String())
+	}
+	return bw.Flush()
+}
+
+// Summary is a number of changes of each operation.
+type Summary struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+	Moved   int `json:"moved"`
+}
+
+// Summary returns number of changes of each operation.
+func (r *Report) Summary() Summary {
+	var s Summary
+	for _, c := range r.Changes {
+		switch c.Op {
+		case Added:
+			s.Added++
+		case Removed:
+			s.Removed++
+		case Changed:
+			s.Changed++
+		case Moved:
+			s.Moved++
+		}
+	}
+	return s
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	changes := r.Changes
+	if changes == nil {
+		changes = []Change{}
+	}
+	report := struct {
+		Old     string   `json:"old"`
+		New     string   `json:"new"`
+		Summary Summary  `json:"summary"`
+		Changes []Change `json:"changes"`
+	}{r.OldName, r.NewName, r.Summary(), changes}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// contextLines is a number of unchanged lines around changed ones in unified diff
+const contextLines = 3
+
+func (r *Report) writeUnified(w io.Writer) error {
+	oldLines, err := indentedLines(r.old)
+	if err != nil {
+		return err
+	}
+	newLines, err := indentedLines(r.new)
+	if err != nil {
+		return err
+	}
+
+	edits := diffLines(oldLines, newLines)
+	hunks := groupHunks(edits, contextLines)
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", r.OldName, r.NewName)
+	for _, h := range hunks {
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(h.oldStart, h.oldCount), hunkRange(h.newStart, h.newCount))
+		for _, e := range h.edits {
+			switch e.op {
+			case editEqual:
+				b.WriteString(" " + oldLines[e.old] + "\n")
+			case editDelete:
+				b.WriteString("-" + oldLines[e.old] + "\n")
+			case editInsert:
+				b.WriteString("+" + newLines[e.new] + "\n")
+			}
+		}
+	}
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// indentedLines returns lines of indented JSON of the value. Keys of objects are sorted.
+func indentedLines(v interface{}) ([]string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(data), "\n"), nil
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+type editOp int
+
+const (
+	editEqual editOp = iota
+	editDelete
+	editInsert
+)
+
+// edit is an edit of line diff. Old and new are indexes of the line in old and new lines.
+type edit struct {
+	op       editOp
+	old, new int
+}
+
+// diffLines returns the shortest edit script transforming a into b using Myers' algorithm.
+func diffLines(a, b []string) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= max && !found; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	// Backtrack the trace from the end
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{editEqual, x, y})
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{editInsert, x, y})
+		} else {
+			x--
+			edits = append(edits, edit{editDelete, x, y})
+		}
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+type hunk struct {
+	oldStart, oldCount int
+	newStart, newCount int
+	edits              []edit
+}
+
+// groupHunks groups edits into hunks with given number of unchanged lines around changes.
+func groupHunks(edits []edit, context int) []hunk {
+	var hunks []hunk
+	for i := 0; i < len(edits); {
+		if edits[i].op == editEqual {
+			i++
+			continue
+		}
+
+		// Start of the hunk with preceding context
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(edits) {
+			if edits[end].op != editEqual {
+				end++
+				continue
+			}
+			// Equal lines: continue the hunk if next change is close enough
+			next := end
+			for next < len(edits) && edits[next].op == editEqual {
+				next++
+			}
+			if next < len(edits) && next-end <= 2*context {
+				end = next
+				continue
+			}
+			end += context
+			if end > len(edits) {
+				end = len(edits)
+			}
+			break
+		}
+
+		h := hunk{edits: edits[start:end], oldStart: edits[start].old, newStart: edits[start].new}
+		for _, e := range h.edits {
+			if e.op != editInsert {
+				h.oldCount++
+			}
+			if e.op != editDelete {
+				h.newCount++
+			}
+		}
+		hunks = append(hunks, h)
+		i = end
+	}
+	return hunks
+}
@@ -0,0 +1,24 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+// FolderID represents id type of dashboard folder. Dashboards outside of folders are in the General folder with id 0.
+type FolderID uint
+
+// Folder represents folder of dashboards.
+type Folder struct {
+	ID    FolderID `json:"id"`
+	UID   string   `json:"uid"`
+	Title string   `json:"title"`
+}
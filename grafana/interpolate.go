@@ -0,0 +1,648 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	panelQuery "github.com/spoof/go-grafana/grafana/query"
+	"github.com/spoof/go-grafana/pkg/interval"
+)
+
+// VariableValues are selected values of dashboard's variables by variable name. Use AllVariableValue to select
+// "All" option.
+type VariableValues map[string][]string
+
+type variableFormat string
+
+// Formats of variable values. Format is set in variable reference, ie. ${var:csv}.
+const (
+	FormatGlob          variableFormat = "glob"
+	FormatRegex         variableFormat = "regex"
+	FormatPipe          variableFormat = "pipe"
+	FormatCSV           variableFormat = "csv"
+	FormatJSON          variableFormat = "json"
+	FormatLucene        variableFormat = "lucene"
+	FormatRaw           variableFormat = "raw"
+	FormatSingleQuote   variableFormat = "singlequote"
+	FormatDoubleQuote   variableFormat = "doublequote"
+	FormatSQLString     variableFormat = "sqlstring"
+	FormatPercentEncode variableFormat = "percentencode"
+	FormatQueryParam    variableFormat = "queryparam"
+	FormatText          variableFormat = "text"
+	FormatDistributed   variableFormat = "distributed"
+)
+
+// formatSQL is default format of SQL datasources: single value is used as is, several values are quoted SQL strings.
+const formatSQL variableFormat = "sql"
+
+// formatMultiRegex is default format of regex-based datasources like Prometheus: values of multi-value variables and
+// variables with "All" option are formatted as regex, values of other variables are used as is.
+const formatMultiRegex variableFormat = "multiregex"
+
+// formatPrometheus is default format of Prometheus queries: like formatMultiRegex but values of other variables are
+// escaped for PromQL strings the same way as Grafana does it.
+const formatPrometheus variableFormat = "prometheus"
+
+// Default number of data points used to calculate $__interval.
+const defaultMaxDataPoints = 1000
+
+// Interpolate replaces references to variables in given text using Grafana's rules. Values of variables are taken
+// from given values or from current selection of dashboard's variables. Built-in variables like $__interval and
+// $__range are calculated from dashboard's time range relative to current time.
+func Interpolate(d *Dashboard, text string, values VariableValues) (string, error) {
+	i := &Interpolator{Dashboard: d, Values: values}
+	return i.Interpolate(text)
+}
+
+// Interpolator replaces references to variables of the dashboard.
+type Interpolator struct {
+	Dashboard *Dashboard
+	Values    VariableValues // override current selection of variables
+
+	Now           time.Time     // time relative to which time range is resolved. Current time if zero.
+	MaxDataPoints uint          // used to calculate $__interval. 1000 if zero.
+	Interval      time.Duration // explicit $__interval, calculated from time range if zero
+}
+
+var variableRe = regexp.MustCompile(`\$(\w+)|\[\[(\w+?)(?::(\w+))?\]\]|\$\{(\w+)(?:\.([^:^\}]+))?(?::([^\}]+))?\}`)
+
+// Interpolate replaces references to variables in given text. Glob format is used for multiple values if format
+// is not set in the reference. References to unknown variables are left as is.
+func (i *Interpolator) Interpolate(text string) (string, error) {
+	return i.interpolate(text, FormatGlob, 0)
+}
+
+// InterpolateWithFormat is like Interpolate but uses given format by default.
+func (i *Interpolator) InterpolateWithFormat(text string, format variableFormat) (string, error) {
+	return i.interpolate(text, format, 0)
+}
+
+// Query returns a copy of given query with all variables interpolated. Formats of multiple values are the same as
+// Grafana's datasources use: regex-based datasources escape values of multi-value variables and variables with "All"
+// option, values of other variables are used as is. Queries of unsupported types are returned as is.
+func (i *Interpolator) Query(q panel.Query) (panel.Query, error) {
+	var err error
+	interpolate := func(s *string, format variableFormat) {
+		if err == nil {
+			*s, err = i.InterpolateWithFormat(*s, format)
+		}
+	}
+
+	switch v := q.(type) {
+	case *panelQuery.Prometheus:
+		qq := *v
+		interpolate(&qq.Expression, formatPrometheus)
+		interpolate(&qq.LegendFormat, FormatRaw)
+		interpolate(&qq.Interval, FormatGlob)
+		return &qq, err
+	case *panelQuery.Graphite:
+		qq := *v
+		interpolate(&qq.Target, FormatGlob)
+		interpolate(&qq.TargetFull, FormatGlob)
+		return &qq, err
+	case *panelQuery.Elasticsearch:
+		qq := *v
+		interpolate(&qq.Query, FormatLucene)
+		interpolate(&qq.Alias, FormatGlob)
+		qq.BucketAggs = make([]*panelQuery.ElasticsearchBucketAgg, len(v.BucketAggs))
+		for j, a := range v.BucketAggs {
+			agg := *a
+			filters := make([]panelQuery.ElasticsearchFilter, len(agg.Settings.Filters))
+			for k, f := range agg.Settings.Filters {
+				interpolate(&f.Query, FormatLucene)
+				filters[k] = f
+			}
+			if agg.Settings.Filters == nil {
+				filters = nil
+			}
+			agg.Settings.Filters = filters
+			interpolate(&agg.Field, FormatGlob)
+			qq.BucketAggs[j] = &agg
+		}
+		return &qq, err
+	case *panelQuery.InfluxDB:
+		qq := *v
+		interpolate(&qq.Query, formatMultiRegex)
+		interpolate(&qq.Alias, FormatGlob)
+		if influxRegexRe.MatchString(qq.Measurement) {
+			interpolate(&qq.Measurement, formatMultiRegex)
+		}
+		qq.Tags = make([]panelQuery.InfluxDBTag, len(v.Tags))
+		for j, tag := range v.Tags {
+			if tag.Operator == "=~" || tag.Operator == "!~" || tag.Operator == "" && influxRegexRe.MatchString(tag.Value) {
+				interpolate(&tag.Value, formatMultiRegex)
+			} else {
+				interpolate(&tag.Value, FormatGlob)
+			}
+			qq.Tags[j] = tag
+		}
+		return &qq, err
+	case *panelQuery.Loki:
+		qq := *v
+		interpolate(&qq.Expression, formatMultiRegex)
+		return &qq, err
+	case *panelQuery.TestData:
+		qq := *v
+		interpolate(&qq.StringInput, FormatGlob)
+		interpolate(&qq.Alias, FormatGlob)
+		return &qq, err
+	case *panelQuery.SQL:
+		qq := *v
+		interpolate(&qq.RawSQL, formatSQL)
+		return &qq, err
+	}
+	return q, nil
+}
+
+// SQL returns raw SQL of given query with variables interpolated and macros expanded for dashboard's time range.
+// Dialect of the query must be set.
+func (i *Interpolator) SQL(q *panelQuery.SQL) (string, error) {
+	if err := q.Validate(); err != nil {
+		return "", err
+	}
+
+	iq, err := i.Query(q)
+	if err != nil {
+		return "", err
+	}
+	from, to := i.timeRange()
+	return iq.(*panelQuery.SQL).Expand(from, to, i.interval())
+}
+
+// maxInterpolationDepth limits interpolation of variables referencing other variables, ie. custom "All" values.
+const maxInterpolationDepth = 10
+
+func (i *Interpolator) interpolate(text string, defaultFormat variableFormat, depth int) (string, error) {
+	if depth > maxInterpolationDepth {
+		return "", fmt.Errorf("variables are nested deeper than %d levels in %q", maxInterpolationDepth, text)
+	}
+
+	var err error
+	result := variableRe.ReplaceAllStringFunc(text, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+
+		m := variableRe.FindStringSubmatch(ref)
+		name := m[1] + m[2] + m[4]
+		format := variableFormat(m[3] + m[6])
+		if idx := strings.Index(string(format), ":"); idx >= 0 {
+			// Arguments of formats are not supported
+			format = format[:idx]
+		}
+		if format == "" {
+			format = defaultFormat
+		}
+
+		var value string
+		var ok bool
+		value, ok, err = i.resolve(name, format, depth)
+		if !ok {
+			return ref
+		}
+		return value
+	})
+	return result, err
+}
+
+// resolve returns formatted value of variable with given name.
+func (i *Interpolator) resolve(name string, format variableFormat, depth int) (string, bool, error) {
+	if value, ok := i.builtin(name); ok {
+		return value, true, nil
+	}
+
+	v := i.variable(name)
+	values, hasValues := i.Values[name]
+	if v == nil && !hasValues {
+		return "", false, nil
+	}
+
+	var texts []string
+	if hasValues {
+		texts = values
+	} else {
+		values, texts = currentValues(v)
+	}
+
+	if len(values) == 1 && values[0] == AllVariableValue && v != nil {
+		if allValue := customAllValue(v); allValue != "" {
+			if format == FormatText {
+				return AllVariableText, true, nil
+			}
+			// Custom "All" values are not formatted
+			value, err := i.interpolate(allValue, format, depth+1)
+			return value, true, err
+		}
+		values = allValues(v)
+		texts = values
+	}
+
+	resolved := make([]string, len(values))
+	for j, value := range values {
+		if value == "$__auto_interval_"+name || value == "$__auto_interval" {
+			value = i.autoInterval(v)
+		}
+		resolved[j] = value
+	}
+	values = resolved
+
+	// Variables without selection and options are empty like in Grafana
+	if len(values) == 0 {
+		return "", true, nil
+	}
+
+	if format == formatMultiRegex || format == formatPrometheus {
+		if len(values) > 1 || isMultiValue(v) {
+			format = FormatRegex
+		} else if format == formatMultiRegex {
+			format = FormatRaw
+		}
+	}
+	if format == FormatText {
+		return strings.Join(texts, " + "), true, nil
+	}
+	return formatValues(name, values, format), true, nil
+}
+
+// builtin returns value of built-in variable.
+func (i *Interpolator) builtin(name string) (string, bool) {
+	if !strings.HasPrefix(name, "__") {
+		return "", false
+	}
+	if values, ok := i.Values[name]; ok && len(values) > 0 {
+		return values[0], true
+	}
+
+	switch name {
+	case "__dashboard":
+		if i.Dashboard != nil {
+			return i.Dashboard.Title, true
+		}
+	case "__from", "__to":
+		from, to := i.timeRange()
+		t := from
+		if name == "__to" {
+			t = to
+		}
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10), true
+	case "__range", "__range_s", "__range_ms":
+		from, to := i.timeRange()
+		r := to.Sub(from)
+		seconds := int64((r + time.Second/2) / time.Second)
+		switch name {
+		case "__range":
+			return fmt.Sprintf("%ds", seconds), true
+		case "__range_s":
+			return strconv.FormatInt(seconds, 10), true
+		default:
+			return strconv.FormatInt(int64(r/time.Millisecond), 10), true
+		}
+	case "__interval":
+		return formatInterval(i.interval()), true
+	case "__interval_ms":
+		return strconv.FormatInt(int64(i.interval()/time.Millisecond), 10), true
+	case "__rate_interval":
+		// Prometheus' rate interval with default scrape interval of 15s
+		const scrapeInterval = 15 * time.Second
+		rate := i.interval() + scrapeInterval
+		if rate < 4*scrapeInterval {
+			rate = 4 * scrapeInterval
+		}
+		return formatInterval(rate), true
+	}
+	return "", false
+}
+
+// isMultiValue reports whether several values or "All" option can be selected in the variable.
+func isMultiValue(v Variable) bool {
+	switch v := v.(type) {
+	case *QueryVariable:
+		return v.Multi || v.IncludeAll
+	case *CustomVariable:
+		return v.Multi || v.IncludeAll
+	}
+	return false
+}
+
+func (i *Interpolator) variable(name string) Variable {
+	if i.Dashboard == nil {
+		return nil
+	}
+	for _, v := range i.Dashboard.Variables {
+		if v.commonOptions().Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+func (i *Interpolator) timeRange() (from, to time.Time) {
+	now := i.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if i.Dashboard == nil {
+		return now, now
+	}
+
+	// Grafana shows the last 6 hours if dashboard has no time range
+	timeRange := i.Dashboard.Time
+	if timeRange == nil {
+		timeRange = NewTimeRange("now-6h", "now")
+	}
+	from, to, err := timeRange.Times(now, i.Dashboard.FiscalYearStartMonth)
+	if err != nil {
+		return now, now
+	}
+	return from, to
+}
+
+// interval calculates $__interval like Grafana does: time range divided by max data points rounded to the nearest
+// "nice" interval.
+func (i *Interpolator) interval() time.Duration {
+	if i.Interval > 0 {
+		return i.Interval
+	}
+
+	maxDataPoints := i.MaxDataPoints
+	if maxDataPoints == 0 {
+		maxDataPoints = defaultMaxDataPoints
+	}
+	from, to := i.timeRange()
+	return roundInterval(to.Sub(from) / time.Duration(maxDataPoints))
+}
+
+// autoInterval calculates value of automatic interval variable.
+func (i *Interpolator) autoInterval(v Variable) string {
+	iv, ok := v.(*IntervalVariable)
+	if !ok || iv.StepCount == 0 {
+		return formatInterval(i.interval())
+	}
+
+	from, to := i.timeRange()
+	d := roundInterval(to.Sub(from) / time.Duration(iv.StepCount))
+	if min, err := interval.Parse(string(iv.Min)); err == nil && d < min {
+		d = min
+	}
+	return formatInterval(d)
+}
+
+// currentValues returns values and texts of current selection of the variable.
+func currentValues(v Variable) (values, texts []string) {
+	if c, ok := v.(*ConstantVariable); ok {
+		return []string{c.Value}, []string{c.Value}
+	}
+
+	opts := v.commonOptions()
+	if opts.Current != nil {
+		values = append(values, opts.Current.Value.Values...)
+		texts = append(texts, opts.Current.Text.Values...)
+		return values, texts
+	}
+
+	if tb, ok := v.(*TextboxVariable); ok {
+		return []string{tb.Query}, []string{tb.Query}
+	}
+	return nil, nil
+}
+
+func customAllValue(v Variable) string {
+	switch vv := v.(type) {
+	case *QueryVariable:
+		return vv.AllValue
+	case *CustomVariable:
+		return vv.AllValue
+	}
+	return ""
+}
+
+// allValues returns values of all options of the variable except "All" option itself.
+func allValues(v Variable) []string {
+	var values []string
+	for _, o := range v.commonOptions().Options {
+		if o.Value != AllVariableValue {
+			values = append(values, o.Value)
+		}
+	}
+
+	if cv, ok := v.(*CustomVariable); ok && len(values) == 0 {
+		values = splitCustomQuery(cv.Query)
+	}
+	return values
+}
+
+// splitCustomQuery splits values of custom variable. Commas could be escaped with backslash.
+func splitCustomQuery(query string) []string {
+	var values []string
+	var current string
+	for j := 0; j < len(query); j++ {
+		switch {
+		case query[j] == '\\' && j+1 < len(query) && query[j+1] == ',':
+			current += ","
+			j++
+		case query[j] == ',':
+			values = append(values, strings.TrimSpace(current))
+			current = ""
+		default:
+			current += string(query[j])
+		}
+	}
+	if strings.TrimSpace(current) != "" {
+		values = append(values, strings.TrimSpace(current))
+	}
+	return values
+}
+
+// formatValues formats values of variable with given name. Single value is formatted as a string, several values
+// as a list.
+func formatValues(name string, values []string, format variableFormat) string {
+	if format == FormatQueryParam {
+		params := make([]string, len(values))
+		for j, v := range values {
+			params[j] = "var-" + percentEncode(name) + "=" + percentEncode(v)
+		}
+		return strings.Join(params, "&")
+	}
+
+	if len(values) == 1 {
+		value := values[0]
+		switch format {
+		case FormatRegex:
+			return escapeRegex(value)
+		case FormatJSON:
+			data, _ := json.Marshal(value)
+			return string(data)
+		case FormatLucene:
+			return escapeLucene(value)
+		case FormatSingleQuote:
+			return "'" + strings.Replace(value, "'", `\'`, -1) + "'"
+		case FormatDoubleQuote:
+			return `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+		case FormatSQLString:
+			return "'" + strings.Replace(value, "'", "''", -1) + "'"
+		case FormatPercentEncode:
+			return percentEncode(value)
+		case formatPrometheus:
+			return escapePrometheus(value)
+		}
+		return value
+	}
+
+	mapValues := func(f func(string) string) []string {
+		mapped := make([]string, len(values))
+		for j, v := range values {
+			mapped[j] = f(v)
+		}
+		return mapped
+	}
+
+	switch format {
+	case FormatRegex:
+		return "(" + strings.Join(mapValues(escapeRegex), "|") + ")"
+	case FormatPipe:
+		return strings.Join(values, "|")
+	case FormatCSV, FormatRaw:
+		return strings.Join(values, ",")
+	case FormatJSON:
+		data, _ := json.Marshal(values)
+		return string(data)
+	case FormatLucene:
+		return `("` + strings.Join(mapValues(escapeLucene), `" OR "`) + `")`
+	case FormatSingleQuote:
+		return strings.Join(mapValues(func(v string) string {
+			return "'" + strings.Replace(v, "'", `\'`, -1) + "'"
+		}), ",")
+	case FormatDoubleQuote:
+		return strings.Join(mapValues(func(v string) string {
+			return `"` + strings.Replace(v, `"`, `\"`, -1) + `"`
+		}), ",")
+	case FormatSQLString, formatSQL:
+		return strings.Join(mapValues(func(v string) string {
+			return "'" + strings.Replace(v, "'", "''", -1) + "'"
+		}), ",")
+	case FormatPercentEncode:
+		return percentEncode("{" + strings.Join(values, ",") + "}")
+	case FormatDistributed:
+		result := ""
+		for j, v := range values {
+			if j > 0 {
+				result += "," + name + "="
+			}
+			result += v
+		}
+		return result
+	}
+	return "{" + strings.Join(values, ",") + "}"
+}
+
+// escapePrometheus escapes backslashes and single quotes of the value like Grafana's prometheusRegularEscape.
+func escapePrometheus(value string) string {
+	return strings.Replace(strings.Replace(value, `\`, `\\`, -1), "'", `\\'`, -1)
+}
+
+var (
+	influxRegexRe = regexp.MustCompile(`^/.*/$`)
+	regexEscaper  = regexp.MustCompile(`[\\^$*+?.()|[\]{}/]`)
+	luceneEscaper = regexp.MustCompile(`[!*+\-=<>\s&|()[\]{}^~?:\\/"]`)
+)
+
+func escapeRegex(s string) string {
+	return regexEscaper.ReplaceAllString(s, `\$0`)
+}
+
+func escapeLucene(s string) string {
+	return luceneEscaper.ReplaceAllString(s, `\$0`)
+}
+
+// percentEncode encodes string like JavaScript's encodeURIComponent.
+func percentEncode(s string) string {
+	const unreserved = "-_.!~*'()"
+	var result string
+	for _, b := range []byte(s) {
+		if b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || strings.IndexByte(unreserved, b) >= 0 {
+			result += string(b)
+		} else {
+			result += fmt.Sprintf("%%%02X", b)
+		}
+	}
+	return result
+}
+
+// roundInterval rounds interval to the nearest "nice" one using Grafana's table.
+func roundInterval(interval time.Duration) time.Duration {
+	ms := int64(interval / time.Millisecond)
+	steps := []struct{ below, value int64 }{
+		{15, 10},
+		{35, 20},
+		{75, 50},
+		{150, 100},
+		{350, 200},
+		{750, 500},
+		{1500, 1000},
+		{3500, 2000},
+		{7500, 5000},
+		{12500, 10000},
+		{17500, 15000},
+		{25000, 20000},
+		{45000, 30000},
+		{90000, 60000},
+		{210000, 120000},
+		{450000, 300000},
+		{750000, 600000},
+		{1050000, 900000},
+		{1500000, 1200000},
+		{2700000, 1800000},
+		{5400000, 3600000},
+		{9000000, 7200000},
+		{16200000, 10800000},
+		{32400000, 21600000},
+		{86400000, 43200000},
+		{604800000, 86400000},
+		{1814400000, 604800000},
+		{3628800000, 2592000000},
+	}
+	for _, s := range steps {
+		if ms < s.below {
+			return time.Duration(s.value) * time.Millisecond
+		}
+	}
+	return 365 * 24 * time.Hour
+}
+
+// formatInterval formats interval in the largest whole unit like Grafana does, ie. 1m, 12h or 500ms.
+func formatInterval(interval time.Duration) string {
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"y", 365 * 24 * time.Hour},
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+	for _, u := range units {
+		if n := interval / u.size; n > 0 {
+			return fmt.Sprintf("%d%s", n, u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dms", interval/time.Millisecond)
+}
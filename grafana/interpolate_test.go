@@ -0,0 +1,248 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	panelQuery "github.com/spoof/go-grafana/grafana/query"
+)
+
+// interpolationDashboard has variables of all interpolated types
+const interpolationDashboard = `{
+	"title": "Dashboard Title",
+	"time": {"from": "now-1h", "to": "now"},
+	"templating": {"list": [
+		{
+			"type": "query",
+			"name": "job",
+			"multi": true,
+			"includeAll": true,
+			"current": {"text": "api", "value": "api"},
+			"options": [
+				{"selected": false, "text": "All", "value": "$__all"},
+				{"selected": true, "text": "api", "value": "api"},
+				{"selected": false, "text": "web", "value": "web"},
+				{"selected": false, "text": "db.master", "value": "db.master"}
+			]
+		},
+		{
+			"type": "query",
+			"name": "instance",
+			"includeAll": true,
+			"allValue": ".*",
+			"current": {"text": "All", "value": "$__all"}
+		},
+		{
+			"type": "custom",
+			"name": "env",
+			"includeAll": true,
+			"query": "prod,stage\\,eu",
+			"current": {"text": "$__all", "value": "$__all"}
+		},
+		{"type": "constant", "name": "prefix", "query": "app"},
+		{
+			"type": "interval",
+			"name": "step",
+			"auto": true,
+			"auto_count": 10,
+			"auto_min": "10m",
+			"query": "1m,10m",
+			"current": {"text": "$__auto_interval_step", "value": "$__auto_interval_step"}
+		},
+		{"type": "query", "name": "empty"}
+	]}
+}`
+
+func TestInterpolator_Interpolate(t *testing.T) {
+	d := new(Dashboard)
+	if err := json.Unmarshal([]byte(interpolationDashboard), d); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err)
+	}
+	now := time.Date(2017, 11, 15, 10, 0, 0, 0, time.UTC)
+
+	ts := []struct {
+		text     string
+		values   VariableValues
+		expected string
+	}{
+		{text: "$job", expected: "api"},
+		{text: "${job}-[[job]]", expected: "api-api"},
+		{text: "$job", values: VariableValues{"job": {"api", "web"}}, expected: "{api,web}"},
+		{text: "${job:regex}", values: VariableValues{"job": {"api", "db.master"}}, expected: `(api|db\.master)`},
+		{text: "${job:pipe}", values: VariableValues{"job": {"api", "web"}}, expected: "api|web"},
+		{text: "${job:csv}", values: VariableValues{"job": {"api", "web"}}, expected: "api,web"},
+		{text: "[[job:csv]]", values: VariableValues{"job": {"api", "web"}}, expected: "api,web"},
+		{text: "${job:json}", values: VariableValues{"job": {"api", "web"}}, expected: `["api","web"]`},
+		{text: "${job:lucene}", values: VariableValues{"job": {"api", "web"}}, expected: `("api" OR "web")`},
+		{text: "${job:sqlstring}", values: VariableValues{"job": {"o'neil"}}, expected: `'o''neil'`},
+		{text: "${job:queryparam}", values: VariableValues{"job": {"api", "web"}}, expected: "var-job=api&var-job=web"},
+		{text: "${job:text}", values: VariableValues{"job": {"api", "web"}}, expected: "api + web"},
+		{text: "${job:regex}", values: VariableValues{"job": {AllVariableValue}}, expected: `(api|web|db\.master)`},
+		{text: "$instance", expected: ".*"},
+		{text: "${instance:text}", expected: "All"},
+		{text: "${env:csv}", expected: "prod,stage,eu"},
+		{text: "$prefix-$unknown", expected: "app-$unknown"},
+		{text: "$__dashboard", expected: "Dashboard Title"},
+		{text: "$__range $__range_s $__range_ms", expected: "3600s 3600 3600000"},
+		{text: "$__interval $__interval_ms", expected: "5s 5000"},
+		{text: "$__rate_interval", expected: "1m"},
+		{text: "$__from-$__to", expected: "1510736400000-1510740000000"},
+		{text: "$step", expected: "10m"},
+		{text: "[$empty]", expected: "[]"},
+		{text: "[${empty:regex}]", expected: "[]"},
+		{text: "$__interval", values: VariableValues{"__interval": {"1m"}}, expected: "1m"},
+	}
+
+	for _, tt := range ts {
+		i := &Interpolator{Dashboard: d, Values: tt.values, Now: now}
+		got, err := i.Interpolate(tt.text)
+		if err != nil {
+			t.Errorf("Interpolator.Interpolate(%q) returned error %s", tt.text, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("Interpolator.Interpolate(%q, %v): expected %q, got %q", tt.text, tt.values, tt.expected, got)
+		}
+	}
+}
+
+func TestInterpolate_NestedAllValue(t *testing.T) {
+	d := NewDashboard("Dashboard Title")
+	v := NewQueryVar("var")
+	v.AllValue = "$var"
+	v.Current = NewVariableCurrent(AllVariableValue)
+	d.Variables = Variables{v}
+
+	if _, err := Interpolate(d, "$var", nil); err == nil {
+		t.Errorf("Interpolate: expected error for recursive variable")
+	}
+}
+
+func TestInterpolator_Query(t *testing.T) {
+	d := new(Dashboard)
+	if err := json.Unmarshal([]byte(interpolationDashboard), d); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err)
+	}
+	i := &Interpolator{Dashboard: d, Values: VariableValues{"job": {"api", "db.master"}}, Interval: time.Minute}
+
+	q := panelQuery.NewPrometheus("Prometheus")
+	q.Expression = `rate(requests{job=~"$job"}[$__interval])`
+	q.LegendFormat = "{{instance}}"
+	got, err := i.Query(q)
+	if err != nil {
+		t.Fatalf("Interpolator.Query returned error %s", err)
+	}
+
+	expected := `rate(requests{job=~"(api|db\.master)"}[1m])`
+	if expr := got.(*panelQuery.Prometheus).Expression; expr != expected {
+		t.Errorf("Interpolator.Query: expected %q, got %q", expected, expr)
+	}
+	if q.Expression == expected {
+		t.Errorf("Interpolator.Query: original query should not be changed")
+	}
+
+	g := panelQuery.NewGraphite("Graphite")
+	g.Target = "servers.$job.cpu"
+	got, err = i.Query(g)
+	if err != nil {
+		t.Fatalf("Interpolator.Query returned error %s", err)
+	}
+	if target := got.(*panelQuery.Graphite).Target; target != "servers.{api,db.master}.cpu" {
+		t.Errorf("Interpolator.Query: expected %q, got %q", "servers.{api,db.master}.cpu", target)
+	}
+
+	es := panelQuery.NewElasticsearch("Elasticsearch")
+	es.Query = "job:$job"
+	got, err = i.Query(es)
+	if err != nil {
+		t.Fatalf("Interpolator.Query returned error %s", err)
+	}
+	if query := got.(*panelQuery.Elasticsearch).Query; query != `job:("api" OR "db.master")` {
+		t.Errorf("Interpolator.Query: expected %q, got %q", `job:("api" OR "db.master")`, query)
+	}
+}
+
+func TestInterpolator_Query_SingleValue(t *testing.T) {
+	d := NewDashboard("Dashboard Title")
+	host := NewQueryVar("host")
+	host.Current = NewVariableCurrent("api.v1")
+	job := NewQueryVar("job")
+	job.Multi = true
+	job.Current = NewVariableCurrent("api.v1")
+	path := NewQueryVar("path")
+	path.Current = NewVariableCurrent(`C:\logs\o'neil`)
+	d.Variables = Variables{host, job, path}
+	i := &Interpolator{Dashboard: d}
+
+	prom := panelQuery.NewPrometheus("Prometheus")
+	prom.Expression = `up{host="$host", job=~"$job", path='$path'}`
+	prom.LegendFormat = "$host {{instance}}"
+	loki := panelQuery.NewLoki("Loki", `{host="$host"} |~ "$job"`)
+	influx := panelQuery.NewInfluxDB("InfluxDB")
+	influx.Tags = []panelQuery.InfluxDBTag{{Key: "host", Operator: "=~", Value: "/^$host$/"}}
+
+	ts := []struct {
+		query    panel.Query
+		field    func(panel.Query) string
+		expected string
+	}{
+		{prom, func(q panel.Query) string { return q.(*panelQuery.Prometheus).Expression },
+			`up{host="api.v1", job=~"api\.v1", path='C:\\logs\\o\\'neil'}`},
+		{prom, func(q panel.Query) string { return q.(*panelQuery.Prometheus).LegendFormat }, "api.v1 {{instance}}"},
+		{loki, func(q panel.Query) string { return q.(*panelQuery.Loki).Expression }, `{host="api.v1"} |~ "api\.v1"`},
+		{influx, func(q panel.Query) string { return q.(*panelQuery.InfluxDB).Tags[0].Value }, "/^api.v1$/"},
+	}
+	for _, tt := range ts {
+		got, err := i.Query(tt.query)
+		if err != nil {
+			t.Fatalf("Interpolator.Query returned error %s", err)
+		}
+		if value := tt.field(got); value != tt.expected {
+			t.Errorf("Interpolator.Query(%T): expected %q, got %q", tt.query, tt.expected, value)
+		}
+	}
+}
+
+func TestInterpolator_SQL(t *testing.T) {
+	d := new(Dashboard)
+	if err := json.Unmarshal([]byte(interpolationDashboard), d); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err)
+	}
+	now := time.Date(2017, 7, 18, 12, 0, 0, 0, time.UTC)
+	i := &Interpolator{Dashboard: d, Values: VariableValues{"job": {"api", "o'neil"}}, Now: now, Interval: time.Minute}
+
+	q := panelQuery.NewPostgreSQL("PostgreSQL",
+		`SELECT $__timeGroupAlias(ts, $__interval), count(*) FROM jobs WHERE $__timeFilter(ts) AND job IN ($job) `+
+			`AND env = '$prefix' GROUP BY 1`)
+	got, err := i.SQL(q)
+	if err != nil {
+		t.Fatalf("Interpolator.SQL returned error %s", err)
+	}
+
+	expected := `SELECT floor(extract(epoch from ts)/60)*60 AS "time", count(*) FROM jobs ` +
+		`WHERE ts BETWEEN '2017-07-18T11:00:00Z' AND '2017-07-18T12:00:00Z' AND job IN ('api','o''neil') ` +
+		`AND env = 'app' GROUP BY 1`
+	if got != expected {
+		t.Errorf("Interpolator.SQL:\nexpected %s\ngot      %s", expected, got)
+	}
+
+	q.RawSQL = "SELECT $__timeGroup(ts) FROM jobs"
+	if _, err := i.SQL(q); err == nil {
+		t.Errorf("Interpolator.SQL: expected error for invalid macro")
+	}
+}
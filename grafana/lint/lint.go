@@ -0,0 +1,253 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint checks dashboards against team conventions which go beyond structural validation of
+// Dashboard.Validate: descriptions of panels, units of axes, usage of datasources and so on. Every convention is a
+// Rule. Linter walks dashboard and passes its parts to the rules which are interested in them.
+//
+// Rules can be suppressed for particular dashboard with its tags: "nolint" suppresses all rules, "nolint:<rule>"
+// suppresses the rule with given name, ie. "nolint:panel-description".
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/grafana/query"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// Severity is a severity of problems reported by a rule.
+type Severity int
+
+// Severity levels. Rules with Off severity are not checked.
+const (
+	Off Severity = iota
+	Info
+	Warning
+	Error
+)
+
+// String implements fmt.Stringer interface
+func (s Severity) String() string {
+	switch s {
+	case Off:
+		return "off"
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses severity from its name, ie. "warning".
+func ParseSeverity(s string) (Severity, error) {
+	for _, severity := range []Severity{Off, Info, Warning, Error} {
+		if strings.EqualFold(s, severity.String()) {
+			return severity, nil
+		}
+	}
+	return Off, fmt.Errorf("unknown severity %q", s)
+}
+
+// Problem is a violation of rule found in dashboard.
+type Problem struct {
+	Rule     string
+	Severity Severity
+	Path     string // path of the field in dashboard's JSON, ie. "rows[2].panels[0].description"
+	Message  string
+}
+
+// String implements fmt.Stringer interface
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", p.Severity, p.Path, p.Message, p.Rule)
+}
+
+// Rule is a convention which dashboards should follow. Rule checks parts of dashboard by implementing one or more of
+// DashboardRule, RowRule, PanelRule and PrometheusRule interfaces.
+type Rule interface {
+	Name() string        // ie. "panel-description". It's used to configure and suppress the rule.
+	Severity() Severity  // default severity of the rule's problems
+	Description() string // what the rule checks
+}
+
+// DashboardRule checks the whole dashboard.
+type DashboardRule interface {
+	Rule
+	CheckDashboard(r *Reporter, d *grafana.Dashboard)
+}
+
+// RowRule checks rows of legacy layout (schema version < 16).
+type RowRule interface {
+	Rule
+	CheckRow(r *Reporter, row *grafana.Row)
+}
+
+// PanelRule checks panels, including row panels and panels of collapsed rows of grid layout.
+type PanelRule interface {
+	Rule
+	CheckPanel(r *Reporter, p grafana.Panel)
+}
+
+// PrometheusRule checks queries of Prometheus datasource.
+type PrometheusRule interface {
+	Rule
+	CheckPrometheus(r *Reporter, q *query.Prometheus)
+}
+
+// Reporter collects problems found by the rule. Paths of problems are relative to the checked part of dashboard.
+type Reporter struct {
+	rule     Rule
+	severity Severity
+	path     string
+	problems *[]Problem
+}
+
+// Report reports problem of the field with given path. Empty path means the checked part itself.
+func (r *Reporter) Report(path, format string, a ...interface{}) {
+	*r.problems = append(*r.problems, Problem{
+		Rule:     r.rule.Name(),
+		Severity: r.severity,
+		Path:     validate.Join(r.path, path),
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+// Linter checks dashboards with configured rules.
+type Linter struct {
+	rules      []Rule
+	severities map[string]Severity
+}
+
+// NewLinter creates new Linter with given rules. All built-in rules are used if none are given.
+func NewLinter(rules ...Rule) *Linter {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	return &Linter{
+		rules:      rules,
+		severities: make(map[string]Severity),
+	}
+}
+
+// SetSeverity overrides default severity of the rule with given name. Rule is disabled with Off severity.
+func (l *Linter) SetSeverity(rule string, severity Severity) {
+	l.severities[rule] = severity
+}
+
+// Lint checks the dashboard and returns found problems in the order of dashboard's parts.
+func (l *Linter) Lint(d *grafana.Dashboard) []Problem {
+	var suppressed []string
+	if d.Tags != nil {
+		suppressed = d.Tags.Value()
+	}
+
+	var reporters []*Reporter
+	problems := []Problem{}
+	for _, rule := range l.rules {
+		severity, ok := l.severities[rule.Name()]
+		if !ok {
+			severity = rule.Severity()
+		}
+		if severity == Off || isSuppressed(suppressed, rule.Name()) {
+			continue
+		}
+		reporters = append(reporters, &Reporter{rule: rule, severity: severity, problems: &problems})
+	}
+
+	for _, r := range reporters {
+		if rule, ok := r.rule.(DashboardRule); ok {
+			rule.CheckDashboard(r.at(""), d)
+		}
+	}
+
+	for i, row := range d.Rows {
+		path := validate.Index("rows", i)
+		for _, r := range reporters {
+			if rule, ok := r.rule.(RowRule); ok {
+				rule.CheckRow(r.at(path), row)
+			}
+		}
+	}
+
+	walkPanels(d, func(path string, p grafana.Panel) {
+		for _, r := range reporters {
+			if rule, ok := r.rule.(PanelRule); ok {
+				rule.CheckPanel(r.at(path), p)
+			}
+		}
+
+		for i, q := range queries(p) {
+			prometheus, ok := q.(*query.Prometheus)
+			if !ok {
+				continue
+			}
+			queryPath := validate.Join(path, validate.Index("targets", i))
+			for _, r := range reporters {
+				if rule, ok := r.rule.(PrometheusRule); ok {
+					rule.CheckPrometheus(r.at(queryPath), prometheus)
+				}
+			}
+		}
+	})
+
+	return problems
+}
+
+// at returns reporter of the rule for dashboard's part with given path.
+func (r *Reporter) at(path string) *Reporter {
+	rr := *r
+	rr.path = path
+	return &rr
+}
+
+func isSuppressed(tags []string, rule string) bool {
+	for _, tag := range tags {
+		if tag == "nolint" || tag == "nolint:"+rule {
+			return true
+		}
+	}
+	return false
+}
+
+// walkPanels calls fn for every panel of the dashboard with path of the panel: panels of rows of legacy layout,
+// panels of grid layout and panels of collapsed rows.
+func walkPanels(d *grafana.Dashboard, fn func(path string, p grafana.Panel)) {
+	for i, row := range d.Rows {
+		walkPanelList(validate.Index("rows", i), row.Panels, fn)
+	}
+	walkPanelList("", d.Panels, fn)
+}
+
+func walkPanelList(parent string, panels []grafana.Panel, fn func(path string, p grafana.Panel)) {
+	for i, p := range panels {
+		path := validate.Join(parent, validate.Index("panels", i))
+		fn(path, p)
+		if rp, ok := p.(*grafana.RowPanel); ok {
+			walkPanelList(path, rp.Panels, fn)
+		}
+	}
+}
+
+// queries returns queries of the panel or nil if the panel has no queries.
+func queries(p grafana.Panel) []panel.Query {
+	if qp, ok := p.(grafana.QueryablePanel); ok {
+		return *qp.Queries()
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/lint"
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/grafana/query"
+)
+
+// linted is a dashboard with problems found by all rules
+const linted = `{
+	"title": "API",
+	"schemaVersion": 16,
+	"templating": {"list": [
+		{"type": "query", "name": "instance", "datasource": "Prometheus"}
+	]},
+	"panels": [
+		{
+			"type": "graph",
+			"title": "Requests",
+			"yaxes": [{"format": "reqps", "show": true}, {"show": false}],
+			"targets": [
+				{"refId": "A", "expr": "sum(rate(http_requests_total{code=~\"5..\"}[$__rate_interval])) by (code)"},
+				{"refId": "B", "datasource": "Prometheus", "expr": "http_requests_total"}
+			]
+		},
+		{
+			"type": "row",
+			"title": "Overview",
+			"collapsed": true,
+			"panels": [
+				{
+					"type": "graph",
+					"title": "Latency",
+					"description": "99th percentile of request duration",
+					"yaxes": [{"format": "short", "show": true}, {"show": false}],
+					"targets": [
+						{"refId": "A", "datasource": "$ds",
+							"expr": "histogram_quantile(0.99, rate(http_request_duration_seconds_bucket[5m]))"}
+					]
+				},
+				{
+					"type": "singlestat",
+					"title": "Requests",
+					"description": "Errors",
+					"targets": [{"refId": "A", "expr": "increase(errors_total[$__range]) / up{job=\"api_total\"}"}]
+				}
+			]
+		}
+	]
+}`
+
+func TestLinter_Lint(t *testing.T) {
+	d, err := grafana.UnmarshalDashboard([]byte(linted), grafana.NewDatasourceRegistry(
+		&grafana.Datasource{Name: "Prometheus", Type: grafana.PrometheusDatasource, IsDefault: true},
+	))
+	if err != nil {
+		t.Fatalf("UnmarshalDashboard returned error %s", err)
+	}
+
+	problems := lint.NewLinter().Lint(d)
+
+	expected := []lint.Problem{
+		{"unique-titles", lint.Error, "panels[1].panels[1].title", `title "Requests" is already used by panels[0]`},
+		{"hardcoded-datasource", lint.Warning, "templating.list[0].datasource", `variable uses datasource "Prometheus"`},
+		{"panel-description", lint.Warning, "panels[0].description", `panel "Requests" has no description`},
+		{"hardcoded-datasource", lint.Warning, "panels[0].targets[1].datasource", `query uses datasource "Prometheus"`},
+		{"prometheus-counter-rate", lint.Error, "panels[0].targets[1].expr", "counter http_requests_total should be used with rate()"},
+		{"graph-units", lint.Warning, "panels[1].panels[0].yaxes[0].format", "y-axis has no unit"},
+		{"prometheus-counter-rate", lint.Error, "panels[1].panels[0].targets[0].expr",
+			"range [5m] of counter http_request_duration_seconds_bucket is hard-coded, use $__rate_interval"},
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Linter.Lint: %s", pretty.Diff(problems, expected))
+	}
+}
+
+func TestLinter_Suppressions(t *testing.T) {
+	d, err := grafana.UnmarshalDashboard([]byte(linted), grafana.NewDatasourceRegistry(
+		&grafana.Datasource{Name: "Prometheus", Type: grafana.PrometheusDatasource, IsDefault: true},
+	))
+	if err != nil {
+		t.Fatalf("UnmarshalDashboard returned error %s", err)
+	}
+	d.Tags.Add("nolint:hardcoded-datasource", "nolint:prometheus-counter-rate")
+
+	l := lint.NewLinter()
+	l.SetSeverity("unique-titles", lint.Off)
+	l.SetSeverity("graph-units", lint.Error)
+
+	expected := []lint.Problem{
+		{"panel-description", lint.Warning, "panels[0].description", `panel "Requests" has no description`},
+		{"graph-units", lint.Error, "panels[1].panels[0].yaxes[0].format", "y-axis has no unit"},
+	}
+	if problems := l.Lint(d); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Linter.Lint: %s", pretty.Diff(problems, expected))
+	}
+
+	d.Tags.Add("nolint")
+	if problems := l.Lint(d); len(problems) != 0 {
+		t.Errorf("Linter.Lint: expected no problems of dashboard with nolint tag, got %v", problems)
+	}
+}
+
+func TestLinter_Rows(t *testing.T) {
+	p := panel.NewText(panel.TextPanelMarkdownMode)
+	row := grafana.NewRow()
+	row.Panels = []grafana.Panel{p}
+	d := grafana.NewDashboard("Rows")
+	d.Rows = []*grafana.Row{grafana.NewRow(), row}
+
+	problems := lint.NewLinter(lint.PanelDescription{}).Lint(d)
+	expected := []lint.Problem{
+		{"panel-description", lint.Warning, "rows[1].panels[0].description", `panel "" has no description`},
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("Linter.Lint: %s", pretty.Diff(problems, expected))
+	}
+}
+
+func TestPrometheusCounterRate(t *testing.T) {
+	ts := []struct {
+		expr     string
+		expected []string
+	}{
+		{`sum by (job_count) (rate(up[$__rate_interval]))`, nil},
+		{`sum without(requests_total) (x) / on(job_sum) group_left(le_bucket) y`, nil},
+		{`up{job_total="a", path!~"b"}`, nil},
+		{`histogram_count(rate(latency[$__rate_interval]))`, nil},
+		{`sum(rate(requests_total{job="api"}[$__rate_interval])) by (code)`, nil},
+		{`sum by (job_count) (requests_total)`, []string{"counter requests_total should be used with rate()"}},
+		{`rate(requests_total{job_count="a"}[5m])`,
+			[]string{"range [5m] of counter requests_total is hard-coded, use $__rate_interval"}},
+	}
+
+	for _, tt := range ts {
+		q := query.NewPrometheus("")
+		q.Expression = tt.expr
+		p := panel.NewGraph()
+		p.GeneralOptions().Title = "Requests"
+		*p.Queries() = []panel.Query{q}
+		d := grafana.NewDashboard("API")
+		d.SchemaVersion = 16
+		d.Panels = []grafana.Panel{p}
+
+		var got []string
+		for _, problem := range lint.NewLinter(lint.PrometheusCounterRate{}).Lint(d) {
+			got = append(got, problem.Message)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("PrometheusCounterRate(%s): got %q, want %q", tt.expr, got, tt.expected)
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for _, s := range []lint.Severity{lint.Off, lint.Info, lint.Warning, lint.Error} {
+		if parsed, err := lint.ParseSeverity(s.String()); err != nil || parsed != s {
+			t.Errorf("ParseSeverity(%q): expected %v, got %v (%v)", s, s, parsed, err)
+		}
+	}
+	if _, err := lint.ParseSeverity("fatal"); err == nil {
+		t.Errorf("ParseSeverity: expected error for unknown severity")
+	}
+}
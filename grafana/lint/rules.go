@@ -0,0 +1,232 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/grafana/query"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// DefaultRules returns all built-in rules.
+func DefaultRules() []Rule {
+	return []Rule{
+		PanelDescription{},
+		UniqueTitles{},
+		GraphUnits{},
+		HardcodedDatasource{},
+		PrometheusCounterRate{},
+	}
+}
+
+// PanelDescription requires every panel to have a description. Rows are not checked.
+type PanelDescription struct{}
+
+// Name implements Rule interface
+func (PanelDescription) Name() string { return "panel-description" }
+
+// Severity implements Rule interface
+func (PanelDescription) Severity() Severity { return Warning }
+
+// Description implements Rule interface
+func (PanelDescription) Description() string { return "every panel should have a description" }
+
+// CheckPanel implements PanelRule interface
+func (PanelDescription) CheckPanel(r *Reporter, p grafana.Panel) {
+	if _, ok := p.(*grafana.RowPanel); ok {
+		return
+	}
+	if strings.TrimSpace(p.GeneralOptions().Description) == "" {
+		r.Report("description", "panel %q has no description", p.GeneralOptions().Title)
+	}
+}
+
+// UniqueTitles requires titles of panels to be unique within dashboard. Panels without titles and rows are not
+// checked.
+type UniqueTitles struct{}
+
+// Name implements Rule interface
+func (UniqueTitles) Name() string { return "unique-titles" }
+
+// Severity implements Rule interface
+func (UniqueTitles) Severity() Severity { return Error }
+
+// Description implements Rule interface
+func (UniqueTitles) Description() string { return "titles of panels should be unique" }
+
+// CheckDashboard implements DashboardRule interface
+func (UniqueTitles) CheckDashboard(r *Reporter, d *grafana.Dashboard) {
+	paths := make(map[string]string)
+	walkPanels(d, func(path string, p grafana.Panel) {
+		if _, ok := p.(*grafana.RowPanel); ok {
+			return
+		}
+		title := p.GeneralOptions().Title
+		if title == "" {
+			return
+		}
+		if first, ok := paths[title]; ok {
+			r.Report(validate.Join(path, "title"), "title %q is already used by %s", title, first)
+			return
+		}
+		paths[title] = path
+	})
+}
+
+// GraphUnits requires visible y-axes of graphs to have units. Default "short" format is not a unit.
+type GraphUnits struct{}
+
+// Name implements Rule interface
+func (GraphUnits) Name() string { return "graph-units" }
+
+// Severity implements Rule interface
+func (GraphUnits) Severity() Severity { return Warning }
+
+// Description implements Rule interface
+func (GraphUnits) Description() string { return "visible y-axes of graphs should have units" }
+
+// CheckPanel implements PanelRule interface
+func (GraphUnits) CheckPanel(r *Reporter, p grafana.Panel) {
+	graph, ok := p.(*panel.Graph)
+	if !ok {
+		return
+	}
+	for i, axis := range []panel.GraphYAxis{graph.YAxes.Left, graph.YAxes.Right} {
+		if axis.Show && (axis.Format == "" || axis.Format == "short") {
+			r.Report(validate.Join(validate.Index("yaxes", i), "format"), "y-axis has no unit")
+		}
+	}
+}
+
+// HardcodedDatasource requires queries and variables to use datasource variables or the default datasource instead
+// of datasources referenced by name or uid, so dashboards can be moved between Grafana instances.
+type HardcodedDatasource struct{}
+
+// Name implements Rule interface
+func (HardcodedDatasource) Name() string { return "hardcoded-datasource" }
+
+// Severity implements Rule interface
+func (HardcodedDatasource) Severity() Severity { return Warning }
+
+// Description implements Rule interface
+func (HardcodedDatasource) Description() string {
+	return "queries and variables should use datasource variables instead of datasource names"
+}
+
+// CheckDashboard implements DashboardRule interface
+func (rule HardcodedDatasource) CheckDashboard(r *Reporter, d *grafana.Dashboard) {
+	for i, v := range d.Variables {
+		var ds panel.DatasourceRef
+		switch v := v.(type) {
+		case *grafana.QueryVariable:
+			ds = v.Datasource
+		case *grafana.AdHocVariable:
+			ds = v.Datasource
+		default:
+			continue
+		}
+		if rule.isHardcoded(ds) {
+			r.Report(validate.Join("templating", validate.Index("list", i), "datasource"),
+				"variable uses datasource %q", datasourceName(ds))
+		}
+	}
+}
+
+// CheckPanel implements PanelRule interface
+func (rule HardcodedDatasource) CheckPanel(r *Reporter, p grafana.Panel) {
+	for i, q := range queries(p) {
+		if ds := q.Datasource(); rule.isHardcoded(ds) {
+			r.Report(validate.Join(validate.Index("targets", i), "datasource"), "query uses datasource %q",
+				datasourceName(ds))
+		}
+	}
+}
+
+func (HardcodedDatasource) isHardcoded(ds panel.DatasourceRef) bool {
+	if ds.IsDefault() || ds.IsMixed() || ds.IsDashboard() || ds.Equal(panel.GrafanaDatasourceRef()) {
+		return false
+	}
+	_, isVariable := ds.Variable()
+	return !isVariable
+}
+
+func datasourceName(ds panel.DatasourceRef) string {
+	if ds.Name != "" {
+		return ds.Name
+	}
+	return ds.UID
+}
+
+// PrometheusCounterRate requires counters in Prometheus queries to be wrapped with rate(), irate() or increase()
+// and ranges of these functions to be variables, ie. $__rate_interval, so they follow dashboard's time range and
+// resolution. Metrics are counters if their names end with _total, _count, _sum or _bucket.
+type PrometheusCounterRate struct{}
+
+// Name implements Rule interface
+func (PrometheusCounterRate) Name() string { return "prometheus-counter-rate" }
+
+// Severity implements Rule interface
+func (PrometheusCounterRate) Severity() Severity { return Error }
+
+// Description implements Rule interface
+func (PrometheusCounterRate) Description() string {
+	return "counters should be used with rate() over variable range, ie. $__rate_interval"
+}
+
+var (
+	promStringRe  = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`)
+	promCounterRe = regexp.MustCompile(`([a-zA-Z_:][\w:]*(?:_total|_count|_sum|_bucket))\b\s*(\{[^}]*\})?\s*(\[([^\]]*)\])?`)
+	promRateRe    = regexp.MustCompile(`\b(?:rate|irate|increase)\s*\(\s*$`)
+	promLabelsRe  = regexp.MustCompile(`\{[^}]*\}|\b(?:by|without|on|ignoring|group_left|group_right)\s*\([^)]*\)`)
+)
+
+// blankLabels replaces label names and matchers of the expression by spaces keeping braces of selectors and
+// offsets of the rest of the expression.
+func blankLabels(expr string) string {
+	return promLabelsRe.ReplaceAllStringFunc(expr, func(s string) string {
+		if strings.HasPrefix(s, "{") {
+			return "{" + strings.Repeat(" ", len(s)-2) + "}"
+		}
+		return strings.Repeat(" ", len(s))
+	})
+}
+
+// CheckPrometheus implements PrometheusRule interface
+func (PrometheusCounterRate) CheckPrometheus(r *Reporter, q *query.Prometheus) {
+	// Strings may contain anything, ie. label values like "requests_total", and labels may be named like counters
+	expr := blankLabels(promStringRe.ReplaceAllString(q.Expression, `""`))
+
+	for _, m := range promCounterRe.FindAllStringSubmatchIndex(expr, -1) {
+		metric := expr[m[2]:m[3]]
+		if m[4] == -1 && strings.HasPrefix(strings.TrimSpace(expr[m[3]:]), "(") {
+			// Functions like histogram_count()
+			continue
+		}
+		if !promRateRe.MatchString(expr[:m[0]]) {
+			r.Report("expr", "counter %s should be used with rate()", metric)
+			continue
+		}
+		if m[6] == -1 {
+			r.Report("expr", "rate() of counter %s has no range", metric)
+			continue
+		}
+		if rng := expr[m[8]:m[9]]; !strings.HasPrefix(strings.TrimSpace(rng), "$") {
+			r.Report("expr", "range [%s] of counter %s is hard-coded, use $__rate_interval", rng, metric)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// objects returns elements of JSON array which are objects.
+func objects(v interface{}) []map[string]interface{} {
+	list, _ := v.([]interface{})
+	objs := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if o, ok := item.(map[string]interface{}); ok {
+			objs = append(objs, o)
+		}
+	}
+	return objs
+}
+
+// panels returns all panels of the dashboard including panels of legacy rows and collapsed row panels.
+func panels(d map[string]interface{}) []map[string]interface{} {
+	var all []map[string]interface{}
+	for _, r := range objects(d["rows"]) {
+		all = append(all, objects(r["panels"])...)
+	}
+	for _, p := range objects(d["panels"]) {
+		all = append(all, p)
+		all = append(all, objects(p["panels"])...)
+	}
+	return all
+}
+
+// variables returns dashboard's template variables.
+func variables(d map[string]interface{}) []map[string]interface{} {
+	templating, _ := d["templating"].(map[string]interface{})
+	return objects(templating["list"])
+}
+
+// intValue converts JSON number or numeric string into int.
+func intValue(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return int(i), true
+		}
+		if f, err := n.Float64(); err == nil {
+			return int(f), true
+		}
+	case float64:
+		return int(n), true
+	case int:
+		return n, true
+	case uint:
+		return int(n), true
+	case string:
+		if i, err := strconv.Atoi(n); err == nil {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// stringValue converts JSON string or number into string.
+func stringValue(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case json.Number:
+		return s.String()
+	}
+	return ""
+}
+
+// isObject reports whether given value is JSON object.
+func isObject(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
@@ -0,0 +1,120 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package migrate upgrades dashboard JSON of older schema versions to the newest one supported by the library.
+//
+// Migrations operate on raw JSON rather than on grafana.Dashboard, so fields unknown to the library are preserved.
+// Each migration upgrades dashboard exactly by one schema version and repeats what Grafana itself does when it loads
+// an old dashboard.
+package migrate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+)
+
+// Supported range of schema versions.
+const (
+	MinVersion    = 13
+	LatestVersion = 33
+)
+
+// ErrUnsupportedVersion represents an error if dashboard's schema version is older than MinVersion.
+var ErrUnsupportedVersion = fmt.Errorf("Schema versions older than %d are not supported", MinVersion)
+
+// Migration is a single step of dashboard's schema upgrade.
+type Migration struct {
+	Version     int    // schema version produced by migration
+	Description string // what migration does
+
+	apply func(m *Migrator, d map[string]interface{}) error
+}
+
+// Migrator upgrades dashboards JSON step by step to LatestVersion.
+type Migrator struct {
+	// Datasources resolves datasource name into reference. It's used by migration to schema version 33. If it's nil
+	// or name is not found, the name is kept as reference's uid.
+	Datasources func(name string) (panel.DatasourceRef, bool)
+}
+
+// Migrate upgrades given dashboard JSON with default Migrator. See Migrator.Migrate.
+func Migrate(data []byte) ([]byte, []Migration, error) {
+	return new(Migrator).Migrate(data)
+}
+
+// Migrate upgrades given dashboard JSON to LatestVersion and returns upgraded JSON with list of applied migrations.
+// Dashboards of LatestVersion or newer are returned as is.
+func (m *Migrator) Migrate(data []byte) ([]byte, []Migration, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var d map[string]interface{}
+	if err := dec.Decode(&d); err != nil {
+		return nil, nil, err
+	}
+	if d == nil {
+		return nil, nil, errors.New("Dashboard should be JSON object")
+	}
+
+	version, _ := intValue(d["schemaVersion"])
+	if version >= LatestVersion {
+		return data, nil, nil
+	}
+	if version < MinVersion {
+		return nil, nil, ErrUnsupportedVersion
+	}
+
+	var applied []Migration
+	for _, mg := range migrations {
+		if mg.Version <= version {
+			continue
+		}
+		if err := mg.apply(m, d); err != nil {
+			return nil, applied, fmt.Errorf("migration to schema version %d: %s", mg.Version, err)
+		}
+		d["schemaVersion"] = mg.Version
+		applied = append(applied, mg)
+	}
+
+	out, err := json.Marshal(d)
+	if err != nil {
+		return nil, applied, err
+	}
+	return out, applied, nil
+}
+
+// Migrations returns all known migrations ordered by version.
+func Migrations() []Migration {
+	mm := make([]Migration, len(migrations))
+	copy(mm, migrations)
+	return mm
+}
+
+// datasourceRef returns reference to datasource with given name. The default datasource is returned as zero
+// reference.
+func (m *Migrator) datasourceRef(name string) panel.DatasourceRef {
+	ref := panel.NewDatasourceRef(name)
+	if ref.IsDefault() {
+		return panel.DatasourceRef{}
+	}
+	if m.Datasources != nil {
+		if resolved, ok := m.Datasources(name); ok {
+			return resolved.ForSchema(panel.DatasourceRefSchemaVersion)
+		}
+	}
+	return ref.ForSchema(panel.DatasourceRefSchemaVersion)
+}
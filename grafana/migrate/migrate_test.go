@@ -0,0 +1,179 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate_test
+
+import (
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana/migrate"
+	"github.com/spoof/go-grafana/grafana/panel"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+func TestMigrate(t *testing.T) {
+	data := []byte(`{
+		"title": "Dashboard",
+		"schemaVersion": 13,
+		"sharedCrosshair": true,
+		"rows": [{
+			"title": "Row",
+			"showTitle": true,
+			"height": "250px",
+			"panels": [{
+				"id": 1,
+				"type": "graph",
+				"span": 6,
+				"minSpan": 4,
+				"datasource": "Prometheus",
+				"links": [{"type": "dashboard", "dashboard": "My Dashboard", "keepTime": true, "title": "Link"}],
+				"targets": [{"refId": "A", "expr": "up"}]
+			}, {
+				"id": 2,
+				"type": "text2",
+				"span": 6,
+				"options": {"angular": {}, "content": "text"}
+			}]
+		}],
+		"templating": {
+			"list": [
+				{"name": "const", "type": "constant", "hide": 0, "query": "value"},
+				{"name": "query", "type": "query", "refresh": 0, "multi": true, "tags": [], "useTags": false,
+					"current": {"text": "a", "value": "a"}, "options": [{"text": "a", "value": "a"}]}
+			]
+		}
+	}`)
+
+	m := migrate.Migrator{
+		Datasources: func(name string) (panel.DatasourceRef, bool) {
+			if name == "Prometheus" {
+				return panel.NewDatasourceUIDRef("prometheus", "prom-uid"), true
+			}
+			return panel.DatasourceRef{}, false
+		},
+	}
+	got, applied, err := m.Migrate(data)
+	if err != nil {
+		t.Fatalf("Migrator.Migrate returned error %s", err)
+	}
+
+	expected := []byte(`{
+		"title": "Dashboard",
+		"schemaVersion": 33,
+		"graphTooltip": 1,
+		"panels": [{
+			"id": 3,
+			"type": "row",
+			"title": "Row",
+			"collapsed": false,
+			"panels": [],
+			"gridPos": {"x": 0, "y": 0, "w": 24, "h": 1}
+		}, {
+			"id": 1,
+			"type": "graph",
+			"maxPerRow": 3,
+			"gridPos": {"x": 0, "y": 1, "w": 12, "h": 7},
+			"datasource": {"type": "prometheus", "uid": "prom-uid"},
+			"links": [{"url": "dashboard/db/my-dashboard?$__url_time_range", "title": "Link", "targetBlank": false}],
+			"targets": [{"refId": "A", "expr": "up", "datasource": {"type": "prometheus", "uid": "prom-uid"}}]
+		}, {
+			"id": 2,
+			"type": "text",
+			"gridPos": {"x": 12, "y": 1, "w": 12, "h": 7},
+			"options": {"content": "text"}
+		}],
+		"templating": {
+			"list": [
+				{"name": "const", "type": "textbox", "hide": 0, "query": "value"},
+				{"name": "query", "type": "query", "refresh": 1, "multi": true,
+					"current": {"text": ["a"], "value": ["a"]}, "options": []}
+			]
+		}
+	}`)
+	if eq, err := jsontools.BytesEqual(expected, got); err != nil {
+		t.Fatalf("Migrator.Migrate returned error %s", err)
+	} else if !eq {
+		t.Errorf("Migrator.Migrate: got %s, want %s\n", got, expected)
+	}
+
+	if len(applied) != migrate.LatestVersion-13 {
+		t.Fatalf("Migrator.Migrate: expected %d migrations, got %d", migrate.LatestVersion-13, len(applied))
+	}
+	for i, mg := range applied {
+		if mg.Version != 14+i {
+			t.Errorf("Migrator.Migrate: migration #%d has version %d, want %d", i, mg.Version, 14+i)
+		}
+	}
+}
+
+func TestMigrate_DatasourceRefs(t *testing.T) {
+	data := []byte(`{
+		"schemaVersion": 32,
+		"panels": [
+			{"type": "graph", "datasource": "default", "targets": [{"refId": "A"}]},
+			{"type": "graph", "datasource": null, "targets": [{"refId": "A", "datasource": "Loki"}]},
+			{"type": "graph", "datasource": "-- Mixed --", "targets": [{"refId": "A"}, {"refId": "B", "datasource": "$ds"}]},
+			{"type": "graph", "datasource": "Loki", "targets": [{"refId": "A", "datasource": null}, {"refId": "B"}]},
+			{"type": "text"}
+		]
+	}`)
+	got, _, err := migrate.Migrate(data)
+	if err != nil {
+		t.Fatalf("Migrate returned error %s", err)
+	}
+
+	expected := []byte(`{
+		"schemaVersion": 33,
+		"panels": [
+			{"type": "graph", "datasource": null, "targets": [{"refId": "A"}]},
+			{"type": "graph", "datasource": null, "targets": [{"refId": "A", "datasource": {"uid": "Loki"}}]},
+			{"type": "graph", "datasource": {"type": "datasource", "uid": "-- Mixed --"},
+				"targets": [{"refId": "A"}, {"refId": "B", "datasource": {"uid": "${ds}"}}]},
+			{"type": "graph", "datasource": {"uid": "Loki"},
+				"targets": [{"refId": "A", "datasource": {"uid": "Loki"}}, {"refId": "B", "datasource": {"uid": "Loki"}}]},
+			{"type": "text"}
+		]
+	}`)
+	if eq, err := jsontools.BytesEqual(expected, got); err != nil {
+		t.Fatalf("Migrate returned error %s", err)
+	} else if !eq {
+		t.Errorf("Migrate: got %s, want %s\n", got, expected)
+	}
+}
+
+func TestMigrate_Versions(t *testing.T) {
+	ts := []struct {
+		data            string
+		expectedErr     error
+		expectedApplied int
+	}{
+		{`{"schemaVersion": 12}`, migrate.ErrUnsupportedVersion, 0},
+		{`{"schemaVersion": 31}`, nil, 2},
+		{`{"schemaVersion": 33}`, nil, 0},
+		{`{"schemaVersion": 36}`, nil, 0},
+	}
+
+	for _, tt := range ts {
+		got, applied, err := migrate.Migrate([]byte(tt.data))
+		if err != tt.expectedErr {
+			t.Errorf("Migrate(%s): expected error %v, got %v", tt.data, tt.expectedErr, err)
+			continue
+		}
+		if len(applied) != tt.expectedApplied {
+			t.Errorf("Migrate(%s): expected %d migrations, got %d", tt.data, tt.expectedApplied, len(applied))
+		}
+		if err == nil && tt.expectedApplied == 0 && string(got) != tt.data {
+			t.Errorf("Migrate(%s): dashboard should be returned as is, got %s", tt.data, got)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrate
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/grid"
+)
+
+// migrations is the list of all known migrations ordered by version. Versions which don't change stored JSON of
+// dashboards still have their entries, so the list covers every version between MinVersion and LatestVersion.
+var migrations = []Migration{
+	{14, "Replace sharedCrosshair with graphTooltip", migrateSharedCrosshair},
+	{15, "No changes", noop},
+	{16, "Convert rows into grid layout", migrateRowsToGrid},
+	{17, "Replace panel's minSpan with maxPerRow", migrateMinSpan},
+	{18, "Move options-gauge of gauge panels into options", migrateGaugeOptions},
+	{19, "Convert panel links into data links", migratePanelLinks},
+	{20, "Rename data links built-in variables", migrateDataLinkVars(
+		"$__series_name", "${__series.name}",
+		"$__value_time", "${__value.time}",
+		"$__field_name", "${__field.name}",
+	)},
+	{21, "Rename __field.label data links variable to __field.labels", migrateDataLinkVars(
+		"__field.labels", "__field.labels", // keep already renamed
+		"__field.label", "__field.labels",
+	)},
+	{22, "Set auto alignment of table panel's styles", migrateTableAlign},
+	{23, "Make current value of multi-value variables an array", migrateMultiCurrent},
+	{24, "No changes", noop},
+	{25, "No changes", noop},
+	{26, "Rename text2 panels to text", migrateText2},
+	{27, "Convert visible constant variables to textbox", migrateVisibleConstants},
+	{28, "Remove tags options of variables", migrateVariableTags},
+	{29, "Refresh query variables on dashboard load and clear their options", migrateQueryRefresh},
+	{30, "No changes", noop},
+	{31, "Add merge transformation after labelsToFields", migrateLabelsToFields},
+	{32, "No changes", noop},
+	{33, "Replace datasource names with references", migrateDatasourceRefs},
+}
+
+func noop(m *Migrator, d map[string]interface{}) error {
+	return nil
+}
+
+func migrateSharedCrosshair(m *Migrator, d map[string]interface{}) error {
+	if shared, ok := d["sharedCrosshair"].(bool); ok {
+		if shared {
+			d["graphTooltip"] = 1
+		} else {
+			d["graphTooltip"] = 0
+		}
+	}
+	delete(d, "sharedCrosshair")
+	return nil
+}
+
+func migrateRowsToGrid(m *Migrator, d map[string]interface{}) error {
+	rows := objects(d["rows"])
+	delete(d, "rows")
+	if len(rows) == 0 {
+		return nil
+	}
+
+	maxID := 0
+	layout := make([]grid.Row, len(rows))
+	for i, r := range rows {
+		collapsed, _ := r["collapse"].(bool)
+		showTitle, _ := r["showTitle"].(bool)
+		layout[i] = grid.Row{
+			Height:    stringValue(r["height"]),
+			Collapsed: collapsed,
+			ShowTitle: showTitle,
+			Repeat:    stringValue(r["repeat"]),
+		}
+		for _, p := range objects(r["panels"]) {
+			if id, ok := intValue(p["id"]); ok && id > maxID {
+				maxID = id
+			}
+			span, _ := intValue(p["span"])
+			layout[i].Panels = append(layout[i].Panels, grid.Panel{Span: uint(span), Height: stringValue(p["height"])})
+		}
+	}
+
+	panels := objects(d["panels"])
+	var rowPanel map[string]interface{}
+	for _, pl := range grid.Convert(layout) {
+		pos := gridPos(pl.Pos)
+		r := rows[pl.Row]
+		if pl.Panel < 0 {
+			maxID++
+			rowPanel = map[string]interface{}{
+				"id":        maxID,
+				"type":      "row",
+				"title":     stringValue(r["title"]),
+				"collapsed": layout[pl.Row].Collapsed,
+				"panels":    []interface{}{},
+				"gridPos":   pos,
+			}
+			if repeat := layout[pl.Row].Repeat; repeat != "" {
+				rowPanel["repeat"] = repeat
+			}
+			panels = append(panels, rowPanel)
+			continue
+		}
+
+		p := objects(r["panels"])[pl.Panel]
+		p["gridPos"] = pos
+		delete(p, "span")
+		delete(p, "height")
+		if minSpan, ok := intValue(p["minSpan"]); ok && minSpan > 0 {
+			p["minSpan"] = grid.Width(uint(minSpan))
+		}
+
+		if pl.Collapsed {
+			rowPanel["panels"] = append(rowPanel["panels"].([]interface{}), p)
+		} else {
+			panels = append(panels, p)
+		}
+	}
+
+	list := make([]interface{}, len(panels))
+	for i, p := range panels {
+		list[i] = p
+	}
+	d["panels"] = list
+	return nil
+}
+
+func gridPos(pos grid.Pos) map[string]interface{} {
+	return map[string]interface{}{"x": pos.X, "y": pos.Y, "w": pos.W, "h": pos.H}
+}
+
+func migrateMinSpan(m *Migrator, d map[string]interface{}) error {
+	factors := []int{1, 2, 3, 4, 6, 8, 12, 24}
+	for _, p := range panels(d) {
+		minSpan, ok := intValue(p["minSpan"])
+		delete(p, "minSpan")
+		if !ok || minSpan <= 0 {
+			continue
+		}
+
+		// minSpan is already in grid units after migration to schema version 16
+		max := grid.Columns / minSpan
+		maxPerRow := 1
+		for _, f := range factors {
+			if f <= max {
+				maxPerRow = f
+			}
+		}
+		p["maxPerRow"] = maxPerRow
+	}
+	return nil
+}
+
+func migrateGaugeOptions(m *Migrator, d map[string]interface{}) error {
+	for _, p := range panels(d) {
+		if opts, ok := p["options-gauge"]; ok {
+			p["options"] = opts
+			delete(p, "options-gauge")
+		}
+	}
+	return nil
+}
+
+// Built-in variables of data links.
+const (
+	keepTimeVar    = "$__url_time_range"
+	includeVarsVar = "$__all_variables"
+)
+
+func migratePanelLinks(m *Migrator, d map[string]interface{}) error {
+	for _, p := range panels(d) {
+		links := objects(p["links"])
+		if len(links) == 0 {
+			continue
+		}
+
+		upgraded := make([]interface{}, len(links))
+		for i, l := range links {
+			url := stringValue(l["url"])
+			if url == "" && stringValue(l["dashboard"]) != "" {
+				url = "dashboard/db/" + slugify(stringValue(l["dashboard"]))
+			}
+			if url == "" && stringValue(l["dashUri"]) != "" {
+				url = "dashboard/" + stringValue(l["dashUri"])
+			}
+			if url == "" {
+				url = "/"
+			}
+			if keepTime, _ := l["keepTime"].(bool); keepTime {
+				url = appendQuery(url, keepTimeVar)
+			}
+			if includeVars, _ := l["includeVars"].(bool); includeVars {
+				url = appendQuery(url, includeVarsVar)
+			}
+			if params := stringValue(l["params"]); params != "" {
+				url = appendQuery(url, params)
+			}
+
+			targetBlank, _ := l["targetBlank"].(bool)
+			upgraded[i] = map[string]interface{}{
+				"url":         url,
+				"title":       stringValue(l["title"]),
+				"targetBlank": targetBlank,
+			}
+		}
+		p["links"] = upgraded
+	}
+	return nil
+}
+
+func appendQuery(url, query string) string {
+	if strings.Contains(url, "?") {
+		return url + "&" + query
+	}
+	return url + "?" + query
+}
+
+var slugRe = regexp.MustCompile(`[^\w\s-]`)
+
+func slugify(s string) string {
+	s = slugRe.ReplaceAllString(strings.ToLower(s), "")
+	return strings.Join(strings.Fields(s), "-")
+}
+
+// migrateDataLinkVars renames variables in urls of panel's links and data links. Pairs are old and new names like
+// in strings.NewReplacer.
+func migrateDataLinkVars(pairs ...string) func(*Migrator, map[string]interface{}) error {
+	replacer := strings.NewReplacer(pairs...)
+
+	return func(m *Migrator, d map[string]interface{}) error {
+		for _, p := range panels(d) {
+			links := objects(p["links"])
+			if opts, ok := p["options"].(map[string]interface{}); ok {
+				links = append(links, objects(opts["dataLinks"])...)
+			}
+			for _, l := range links {
+				if url, ok := l["url"].(string); ok {
+					l["url"] = replacer.Replace(url)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func migrateTableAlign(m *Migrator, d map[string]interface{}) error {
+	for _, p := range panels(d) {
+		if p["type"] != "table" {
+			continue
+		}
+		for _, s := range objects(p["styles"]) {
+			s["align"] = "auto"
+		}
+	}
+	return nil
+}
+
+func migrateMultiCurrent(m *Migrator, d map[string]interface{}) error {
+	for _, v := range variables(d) {
+		multi, _ := v["multi"].(bool)
+		current, ok := v["current"].(map[string]interface{})
+		if !multi || !ok {
+			continue
+		}
+		for _, key := range []string{"text", "value"} {
+			if value, ok := current[key]; ok {
+				if _, isArray := value.([]interface{}); !isArray {
+					current[key] = []interface{}{value}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func migrateText2(m *Migrator, d map[string]interface{}) error {
+	for _, p := range panels(d) {
+		if p["type"] == "text2" {
+			p["type"] = "text"
+			if opts, ok := p["options"].(map[string]interface{}); ok {
+				delete(opts, "angular")
+			}
+		}
+	}
+	return nil
+}
+
+func migrateVisibleConstants(m *Migrator, d map[string]interface{}) error {
+	for _, v := range variables(d) {
+		if v["type"] != "constant" {
+			continue
+		}
+		if hide, _ := intValue(v["hide"]); hide < 2 {
+			v["type"] = "textbox"
+		}
+	}
+	return nil
+}
+
+func migrateVariableTags(m *Migrator, d map[string]interface{}) error {
+	for _, v := range variables(d) {
+		for _, key := range []string{"tags", "tagsQuery", "tagValuesQuery", "useTags"} {
+			delete(v, key)
+		}
+	}
+	return nil
+}
+
+func migrateQueryRefresh(m *Migrator, d map[string]interface{}) error {
+	for _, v := range variables(d) {
+		if v["type"] != "query" {
+			continue
+		}
+		if refresh, _ := intValue(v["refresh"]); refresh != 1 && refresh != 2 {
+			v["refresh"] = 1
+		}
+		if _, ok := v["options"]; ok {
+			v["options"] = []interface{}{}
+		}
+	}
+	return nil
+}
+
+func migrateLabelsToFields(m *Migrator, d map[string]interface{}) error {
+	for _, p := range panels(d) {
+		transformations, ok := p["transformations"].([]interface{})
+		if !ok {
+			continue
+		}
+
+		var upgraded []interface{}
+		for _, t := range transformations {
+			upgraded = append(upgraded, t)
+			if o, ok := t.(map[string]interface{}); ok && o["id"] == "labelsToFields" {
+				upgraded = append(upgraded, map[string]interface{}{"id": "merge", "options": map[string]interface{}{}})
+			}
+		}
+		p["transformations"] = upgraded
+	}
+	return nil
+}
+
+// migrateDatasourceRefs replaces names of datasources of panels and queries by references. The default datasource of
+// panels becomes null, queries without datasource get the one of their panel unless the panel is mixed.
+func migrateDatasourceRefs(m *Migrator, d map[string]interface{}) error {
+	for _, p := range panels(d) {
+		ref := m.datasourceRefValue(p["datasource"])
+		if v, ok := p["datasource"]; ok && !isObject(v) {
+			p["datasource"] = refOrNull(ref)
+		}
+		for _, t := range objects(p["targets"]) {
+			targetRef := m.datasourceRefValue(t["datasource"])
+			switch {
+			case !targetRef.IsDefault():
+				if !isObject(t["datasource"]) {
+					t["datasource"] = targetRef
+				}
+			case !ref.IsDefault() && !ref.IsMixed():
+				t["datasource"] = ref
+			}
+		}
+	}
+	return nil
+}
+
+// datasourceRefValue returns reference to datasource of given JSON value: name, {type, uid} object or null.
+func (m *Migrator) datasourceRefValue(v interface{}) panel.DatasourceRef {
+	switch v := v.(type) {
+	case string:
+		return m.datasourceRef(v)
+	case map[string]interface{}:
+		return panel.NewDatasourceUIDRef(stringValue(v["type"]), stringValue(v["uid"]))
+	}
+	return panel.DatasourceRef{}
+}
+
+// refOrNull returns the reference or nil if it's the default datasource.
+func refOrNull(ref panel.DatasourceRef) interface{} {
+	if ref.IsDefault() {
+		return nil
+	}
+	return ref
+}
@@ -0,0 +1,192 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Normalize returns canonical JSON of the dashboard. Dashboards which differ only in noise have the same canonical
+// JSON: order of keys and tags, omitted fields and their defaults, numbers stored as strings, ids of panels, refIds of
+// queries and options of variables refreshed by Grafana. Canonical JSON is meant for comparison of dashboards, it
+// shouldn't be saved.
+func Normalize(d *Dashboard) ([]byte, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeJSON(data)
+}
+
+// Hash returns hex-encoded SHA-256 hash of canonical JSON of the dashboard. Dashboards with the same hash differ
+// only in noise, see Normalize.
+func Hash(d *Dashboard) (string, error) {
+	data, err := Normalize(d)
+	if err != nil {
+		return "", err
+	}
+	return hash(data), nil
+}
+
+// NormalizeJSON is like Normalize but takes dashboard's JSON, ie. returned by DashboardsService.GetJSON. Id and
+// version of the dashboard are removed too. Fields which are not supported by Dashboard are normalized the same way
+// and kept.
+func NormalizeJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if root, ok := v.(map[string]interface{}); ok {
+		normalizeDashboard(root)
+	}
+	return json.Marshal(compact(v))
+}
+
+// HashJSON is like Hash but takes dashboard's JSON, see NormalizeJSON.
+func HashJSON(data []byte) (string, error) {
+	canonical, err := NormalizeJSON(data)
+	if err != nil {
+		return "", err
+	}
+	return hash(canonical), nil
+}
+
+func hash(canonical []byte) string {
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeDashboard(root map[string]interface{}) {
+	// Id and version are assigned by Grafana on save
+	delete(root, "id")
+	delete(root, "version")
+
+	if tags, ok := root["tags"].([]interface{}); ok {
+		sort.Slice(tags, func(i, j int) bool {
+			a, _ := tags[i].(string)
+			b, _ := tags[j].(string)
+			return a < b
+		})
+	}
+
+	if rows, ok := root["rows"].([]interface{}); ok {
+		for _, r := range rows {
+			if row, ok := r.(map[string]interface{}); ok {
+				normalizeHeight(row)
+				normalizePanels(row["panels"])
+			}
+		}
+	}
+	normalizePanels(root["panels"])
+
+	if templating, ok := root["templating"].(map[string]interface{}); ok {
+		variables, _ := templating["list"].([]interface{})
+		for _, v := range variables {
+			variable, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// Options of variables refreshed on load or time range change are refreshed by Grafana
+			if refresh, ok := variable["refresh"].(float64); ok && refresh != float64(NeverRefresh) {
+				delete(variable, "options")
+			}
+		}
+	}
+}
+
+// normalizePanels removes ids of panels, which are generated on save, and renames refIds of their queries in order.
+func normalizePanels(v interface{}) {
+	panels, _ := v.([]interface{})
+	for _, p := range panels {
+		panel, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delete(panel, "id")
+		normalizeHeight(panel)
+
+		targets, _ := panel["targets"].([]interface{})
+		for i, t := range targets {
+			if target, ok := t.(map[string]interface{}); ok {
+				target["refId"] = makeRefID(i)
+			}
+		}
+
+		// Panels of collapsed rows
+		normalizePanels(panel["panels"])
+	}
+}
+
+var pixelsRe = regexp.MustCompile(`^\d+$`)
+
+// normalizeHeight adds units to heights given as number of pixels, ie. 250 and "250" are the same as "250px".
+func normalizeHeight(o map[string]interface{}) {
+	switch h := o["height"].(type) {
+	case float64:
+		o["height"] = strconv.FormatFloat(h, 'f', -1, 64) + "px"
+	case string:
+		if pixelsRe.MatchString(h) {
+			o["height"] = h + "px"
+		}
+	}
+}
+
+// compact removes nulls, false, zeros, empty strings, lists and objects, which are the same as omitted fields, and
+// converts numbers stored as strings into numbers.
+func compact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, f := range v {
+			if f = compact(f); f == nil || isZero(f) {
+				delete(v, k)
+			} else {
+				v[k] = f
+			}
+		}
+		if len(v) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		for i, e := range v {
+			v[i] = compact(e)
+		}
+	case string:
+		// Only numbers in their shortest form, so ie. "010" is kept as string
+		if f, err := strconv.ParseFloat(v, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == v {
+			return f
+		}
+	}
+	return v
+}
+
+// isZero reports whether JSON value is false, zero or empty string.
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	case string:
+		return v == ""
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+)
+
+func TestNormalize(t *testing.T) {
+	// Dashboard exported from Grafana UI
+	exported := []byte(`{
+		"id": 12,
+		"uid": "api",
+		"version": 7,
+		"title": "API",
+		"tags": ["prod", "api"],
+		"schemaVersion": 14,
+		"links": [],
+		"rows": [{
+			"title": "Overview",
+			"height": 250,
+			"panels": [{
+				"id": 3,
+				"type": "graph",
+				"title": "Requests",
+				"span": 6,
+				"height": "300",
+				"datasource": null,
+				"targets": [{"refId": "C", "expr": "sum(rate(requests_total[1m]))", "intervalFactor": 2}]
+			}, {
+				"id": 4,
+				"type": "grafana-worldmap-panel",
+				"title": "Regions",
+				"locationData": "countries"
+			}]
+		}],
+		"templating": {"list": [{
+			"type": "query",
+			"name": "instance",
+			"query": "label_values(up, instance)",
+			"refresh": 1,
+			"options": [{"text": "host1", "value": "host1", "selected": true}],
+			"current": {"text": "host1", "value": "host1"}
+		}]}
+	}`)
+
+	// The same dashboard kept in a file
+	kept := []byte(`{
+		"templating": {"list": [{
+			"current": {"text": "host1", "value": "host1"},
+			"name": "instance",
+			"query": "label_values(up, instance)",
+			"refresh": 1,
+			"type": "query"
+		}]},
+		"rows": [{
+			"height": "250px",
+			"panels": [{
+				"height": "300px",
+				"span": 6,
+				"targets": [{"expr": "sum(rate(requests_total[1m]))", "intervalFactor": 2, "refId": "C"}],
+				"title": "Requests",
+				"type": "graph"
+			}, {
+				"locationData": "countries",
+				"title": "Regions",
+				"type": "grafana-worldmap-panel"
+			}],
+			"title": "Overview"
+		}],
+		"schemaVersion": 14,
+		"tags": ["api", "prod"],
+		"title": "API",
+		"uid": "api"
+	}`)
+
+	exportedHash, err := HashJSON(exported)
+	if err != nil {
+		t.Fatalf("HashJSON returned error %s", err)
+	}
+	keptHash, err := HashJSON(kept)
+	if err != nil {
+		t.Fatalf("HashJSON returned error %s", err)
+	}
+	if exportedHash != keptHash {
+		a, _ := NormalizeJSON(exported)
+		b, _ := NormalizeJSON(kept)
+		t.Errorf("HashJSON: expected the same hashes of dashboards, canonical JSON:\n%s\n%s", a, b)
+	}
+
+	// Meaningful changes change the hash, including ones of panels of unregistered types
+	for _, change := range []struct{ old, new string }{
+		{`"span": 6`, `"span": 4`},
+		{`"intervalFactor": 2`, `"intervalFactor": 1`},
+		{`"locationData": "countries"`, `"locationData": "states"`},
+	} {
+		changed := bytes.Replace(kept, []byte(change.old), []byte(change.new), 1)
+		if h, _ := HashJSON(changed); h == exportedHash {
+			t.Errorf("HashJSON: expected different hashes of dashboards with changed %s", change.old)
+		}
+	}
+
+	// Canonical JSON is valid and stable
+	data, err := NormalizeJSON(exported)
+	if err != nil {
+		t.Fatalf("NormalizeJSON returned error %s", err)
+	}
+	var canonical map[string]interface{}
+	if err := json.Unmarshal(data, &canonical); err != nil {
+		t.Fatalf("NormalizeJSON returned invalid JSON %s", err)
+	}
+	if again, _ := NormalizeJSON(exported); string(again) != string(data) {
+		t.Errorf("NormalizeJSON is not stable:\n%s\n%s", data, again)
+	}
+}
+
+func TestHashJSON_Noise(t *testing.T) {
+	ts := []struct{ a, b string }{
+		{`{"panels": [{"targets": [{"expr": "up"}, {"expr": "down"}]}]}`,
+			`{"panels": [{"targets": [{"refId": "A", "expr": "up"}, {"refId": "C", "expr": "down"}]}]}`},
+		{`{"panels": [{"span": 4, "decimals": 2}]}`, `{"panels": [{"span": "4", "decimals": "2"}]}`},
+		{`{"panels": [{"type": "graph"}]}`, `{"panels": [{"type": "graph", "transparent": false, "description": ""}]}`},
+		{`{"title": "API", "graphTooltip": 0, "editable": false}`, `{"title": "API"}`},
+	}
+	for _, tt := range ts {
+		a, err := HashJSON([]byte(tt.a))
+		if err != nil {
+			t.Fatalf("HashJSON returned error %s", err)
+		}
+		b, err := HashJSON([]byte(tt.b))
+		if err != nil {
+			t.Fatalf("HashJSON returned error %s", err)
+		}
+		if a != b {
+			t.Errorf("HashJSON: expected the same hashes of %s and %s", tt.a, tt.b)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	// Dashboard exported from Grafana UI omits defaults and stores heights as numbers of pixels
+	var exported Dashboard
+	if err := json.Unmarshal([]byte(`{
+		"id": 3,
+		"title": "API",
+		"editable": true,
+		"style": "dark",
+		"time": {"from": "now-6h", "to": "now"},
+		"schemaVersion": 14,
+		"tags": ["prod", "api"],
+		"rows": [{
+			"title": "Overview",
+			"editable": true,
+			"height": "250",
+			"panels": [{
+				"id": 1,
+				"type": "text",
+				"title": "Readme",
+				"span": 12,
+				"mode": "markdown",
+				"content": "Hello"
+			}]
+		}]
+	}`), &exported); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err)
+	}
+
+	// The same dashboard generated in Go
+	text := panel.NewText(panel.TextPanelMarkdownMode)
+	text.GeneralOptions().Title = "Readme"
+	text.GeneralOptions().Span = 12
+	text.Content = "Hello"
+	row := NewRow()
+	row.Title = "Overview"
+	row.Height = "250px"
+	row.Panels = []Panel{text}
+	generated := NewDashboard("API")
+	generated.Tags.Add("api", "prod")
+	generated.Rows = []*Row{row}
+
+	a, err := Hash(&exported)
+	if err != nil {
+		t.Fatalf("Hash returned error %s", err)
+	}
+	b, err := Hash(generated)
+	if err != nil {
+		t.Fatalf("Hash returned error %s", err)
+	}
+	if a != b {
+		ca, _ := Normalize(&exported)
+		cb, _ := Normalize(generated)
+		t.Errorf("Hash: expected the same hashes of dashboards, canonical JSON:\n%s\n%s", ca, cb)
+	}
+
+	generated.Title = "Web"
+	if c, _ := Hash(generated); c == a {
+		t.Errorf("Hash: expected different hashes of dashboards with different titles")
+	}
+}
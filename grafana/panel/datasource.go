@@ -0,0 +1,206 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package panel
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// Names of special datasources in dashboards before schema version 33.
+const (
+	MixedDatasourceName     = "-- Mixed --"     // queries of the panel use their own datasources
+	DashboardDatasourceName = "-- Dashboard --" // queries reuse results of another panel
+	GrafanaDatasourceName   = "-- Grafana --"   // built-in datasource with test data and annotations
+)
+
+// DatasourceRefSchemaVersion is the first schema version which references datasources by {type, uid} objects
+// instead of names.
+const DatasourceRefSchemaVersion = 33
+
+// typeOfSpecialDatasources is the type of mixed, dashboard and grafana datasources in {type, uid} references.
+const typeOfSpecialDatasources = "datasource"
+
+// uids of special datasources
+var specialDatasourceUIDs = map[string]string{
+	MixedDatasourceName:     MixedDatasourceName,
+	DashboardDatasourceName: DashboardDatasourceName,
+	GrafanaDatasourceName:   "grafana",
+}
+
+// DatasourceRef references datasource of panel, query or variable. Datasource is referenced by name in dashboards
+// before schema version 33 and by {type, uid} object since it. Zero value references the default datasource.
+type DatasourceRef struct {
+	Name string // name of datasource or variable, ie. "Prometheus" or "$ds"
+	UID  string // uid of datasource or variable, ie. "P1809F7CD0C75ACF3" or "${ds}"
+	Type string // plugin id of datasource, ie. "prometheus"
+}
+
+// NewDatasourceRef creates reference to datasource with given name. Empty name references the default datasource.
+func NewDatasourceRef(name string) DatasourceRef {
+	return DatasourceRef{Name: name}
+}
+
+// NewDatasourceUIDRef creates reference to datasource of given type with given uid.
+func NewDatasourceUIDRef(datasourceType, uid string) DatasourceRef {
+	return DatasourceRef{UID: uid, Type: datasourceType}
+}
+
+// NewDatasourceVariableRef creates reference to datasource selected in datasource variable with given name.
+func NewDatasourceVariableRef(variable string) DatasourceRef {
+	return DatasourceRef{Name: "$" + variable, UID: "${" + variable + "}"}
+}
+
+// MixedDatasourceRef returns reference to mixed datasource of panels whose queries use their own datasources.
+func MixedDatasourceRef() DatasourceRef {
+	return specialDatasourceRef(MixedDatasourceName)
+}
+
+// DashboardDatasourceRef returns reference to dashboard datasource of panels reusing results of other panels.
+func DashboardDatasourceRef() DatasourceRef {
+	return specialDatasourceRef(DashboardDatasourceName)
+}
+
+// GrafanaDatasourceRef returns reference to built-in Grafana datasource.
+func GrafanaDatasourceRef() DatasourceRef {
+	return specialDatasourceRef(GrafanaDatasourceName)
+}
+
+func specialDatasourceRef(name string) DatasourceRef {
+	return DatasourceRef{Name: name, UID: specialDatasourceUIDs[name], Type: typeOfSpecialDatasources}
+}
+
+// IsDefault reports whether the reference is to the default datasource.
+func (r DatasourceRef) IsDefault() bool {
+	return r.UID == "" && (r.Name == "" || r.Name == "default")
+}
+
+// IsMixed reports whether the reference is to mixed datasource.
+func (r DatasourceRef) IsMixed() bool {
+	return r.Name == MixedDatasourceName || r.UID == MixedDatasourceName
+}
+
+// IsDashboard reports whether the reference is to dashboard datasource.
+func (r DatasourceRef) IsDashboard() bool {
+	return r.Name == DashboardDatasourceName || r.UID == DashboardDatasourceName
+}
+
+var datasourceVariableRe = regexp.MustCompile(`^(?:\$(\w+)|\$\{(\w+)\}|\[\[(\w+)\]\])$`)
+
+// Variable returns name of datasource variable the reference is to.
+func (r DatasourceRef) Variable() (string, bool) {
+	for _, s := range []string{r.UID, r.Name} {
+		if m := datasourceVariableRe.FindStringSubmatch(s); m != nil {
+			return m[1] + m[2] + m[3], true
+		}
+	}
+	return "", false
+}
+
+// Equal reports whether both references are to the same datasource. References by uid and by name are equal if
+// both of them are known.
+func (r DatasourceRef) Equal(other DatasourceRef) bool {
+	if r.IsDefault() || other.IsDefault() {
+		return r.IsDefault() && other.IsDefault()
+	}
+	if r.UID != "" && other.UID != "" {
+		return r.UID == other.UID
+	}
+	if r.Name != "" && other.Name != "" {
+		return r.Name == other.Name
+	}
+	return false
+}
+
+// ForSchema returns the reference in the form used by dashboards of given schema version: name before version 33
+// and {type, uid} object since it. Uid is used as name and vice versa if the reference lacks them, the same way as
+// Grafana migrates dashboards. Zero version keeps the form as is.
+func (r DatasourceRef) ForSchema(schemaVersion int) DatasourceRef {
+	if schemaVersion == 0 {
+		return r
+	}
+	if r.IsDefault() {
+		return DatasourceRef{}
+	}
+
+	if schemaVersion < DatasourceRefSchemaVersion {
+		name := r.Name
+		if name == "" {
+			name = r.UID
+			for n, uid := range specialDatasourceUIDs {
+				if uid == r.UID && r.Type == typeOfSpecialDatasources {
+					name = n
+				}
+			}
+		}
+		return DatasourceRef{Name: name}
+	}
+
+	if r.UID != "" {
+		return DatasourceRef{UID: r.UID, Type: r.Type}
+	}
+	if uid, ok := specialDatasourceUIDs[r.Name]; ok {
+		return DatasourceRef{UID: uid, Type: typeOfSpecialDatasources}
+	}
+	uid := r.Name
+	if v, ok := r.Variable(); ok {
+		uid = "${" + v + "}"
+	}
+	return DatasourceRef{UID: uid, Type: r.Type}
+}
+
+// MarshalJSON implements json.Marshaler interface. Reference with uid is marshaled as {type, uid} object, reference
+// with name only as the name and the default datasource as null. Use ForSchema to choose the form explicitly.
+func (r DatasourceRef) MarshalJSON() ([]byte, error) {
+	if r.UID == "" && r.Name != "" {
+		return json.Marshal(r.Name)
+	}
+	if r.UID == "" && r.Type == "" {
+		return []byte("null"), nil
+	}
+
+	jr := struct {
+		Type string `json:"type,omitempty"`
+		UID  string `json:"uid,omitempty"`
+	}{
+		Type: r.Type,
+		UID:  r.UID,
+	}
+	return json.Marshal(jr)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (r *DatasourceRef) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	switch vv := v.(type) {
+	case nil:
+		*r = DatasourceRef{}
+	case string:
+		*r = DatasourceRef{Name: vv}
+	default:
+		var jr struct {
+			Type string `json:"type"`
+			UID  string `json:"uid"`
+		}
+		if err := json.Unmarshal(data, &jr); err != nil {
+			return err
+		}
+		*r = DatasourceRef{UID: jr.UID, Type: jr.Type}
+	}
+	return nil
+}
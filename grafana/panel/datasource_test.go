@@ -0,0 +1,101 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package panel_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/spoof/go-grafana/grafana/panel"
+)
+
+func TestDatasourceRef_JSON(t *testing.T) {
+	ts := []struct {
+		data string
+		ref  panel.DatasourceRef
+	}{
+		{`null`, panel.DatasourceRef{}},
+		{`"Prometheus"`, panel.NewDatasourceRef("Prometheus")},
+		{`{"type":"prometheus","uid":"P1809F7CD0C75ACF3"}`, panel.NewDatasourceUIDRef("prometheus", "P1809F7CD0C75ACF3")},
+	}
+
+	for _, tt := range ts {
+		var got panel.DatasourceRef
+		if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+			t.Fatalf("DatasourceRef.UnmarshalJSON returned error %s", err)
+		}
+		if !reflect.DeepEqual(got, tt.ref) {
+			t.Errorf("DatasourceRef.UnmarshalJSON(%s): %s", tt.data, pretty.Diff(got, tt.ref))
+		}
+
+		data, err := json.Marshal(tt.ref)
+		if err != nil {
+			t.Fatalf("DatasourceRef.MarshalJSON returned error %s", err)
+		}
+		if string(data) != tt.data {
+			t.Errorf("DatasourceRef.MarshalJSON: expected %s, got %s", tt.data, data)
+		}
+	}
+}
+
+func TestDatasourceRef_ForSchema(t *testing.T) {
+	prometheus := panel.DatasourceRef{Name: "Prometheus", UID: "P1809F7CD0C75ACF3", Type: "prometheus"}
+
+	ts := []struct {
+		ref      panel.DatasourceRef
+		version  int
+		expected string
+	}{
+		{panel.DatasourceRef{}, 33, `null`},
+		{panel.NewDatasourceRef("default"), 33, `null`},
+		{prometheus, 14, `"Prometheus"`},
+		{prometheus, 33, `{"type":"prometheus","uid":"P1809F7CD0C75ACF3"}`},
+		{prometheus, 0, `{"type":"prometheus","uid":"P1809F7CD0C75ACF3"}`},
+		{panel.NewDatasourceRef("Prometheus"), 33, `{"uid":"Prometheus"}`},
+		{panel.NewDatasourceUIDRef("prometheus", "P1809F7CD0C75ACF3"), 14, `"P1809F7CD0C75ACF3"`},
+		{panel.NewDatasourceRef("$ds"), 33, `{"uid":"${ds}"}`},
+		{panel.NewDatasourceVariableRef("ds"), 14, `"$ds"`},
+		{panel.NewDatasourceRef(panel.MixedDatasourceName), 33, `{"type":"datasource","uid":"-- Mixed --"}`},
+		{panel.MixedDatasourceRef(), 14, `"-- Mixed --"`},
+		{panel.DashboardDatasourceRef(), 33, `{"type":"datasource","uid":"-- Dashboard --"}`},
+		{panel.NewDatasourceUIDRef("datasource", "grafana"), 14, `"-- Grafana --"`},
+	}
+
+	for _, tt := range ts {
+		data, err := json.Marshal(tt.ref.ForSchema(tt.version))
+		if err != nil {
+			t.Fatalf("DatasourceRef.MarshalJSON returned error %s", err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("DatasourceRef.ForSchema(%d) of %+v: expected %s, got %s", tt.version, tt.ref, tt.expected, data)
+		}
+	}
+}
+
+func TestDatasourceRef_Variable(t *testing.T) {
+	for _, ref := range []panel.DatasourceRef{
+		panel.NewDatasourceRef("$ds"),
+		panel.NewDatasourceRef("[[ds]]"),
+		panel.NewDatasourceUIDRef("prometheus", "${ds}"),
+	} {
+		if name, ok := ref.Variable(); !ok || name != "ds" {
+			t.Errorf("DatasourceRef.Variable of %+v: expected ds, got %q", ref, name)
+		}
+	}
+	if _, ok := panel.NewDatasourceRef("Prometheus").Variable(); ok {
+		t.Errorf("DatasourceRef.Variable: datasource name is not a variable")
+	}
+}
@@ -75,7 +75,7 @@ type Graph struct {
 	YAxes GraphYaxesOptions `json:"yaxes"`
 	XAxis struct {
 		Buckets null.Int       `json:"buckets,omitempty"`
-		Mode    graphXAxisMode `json:"mode"`           // TODO: add validaition here: histogram/series/time
+		Mode    graphXAxisMode `json:"mode"`           // histogram/series/time
 		Name    *string        `json:"name,omitempty"` // it's seems that it's not used anymore
 		Show    bool           `json:"show"`
 		Values  []string       `json:"values"` // TODO: actually it's only single value here. Need custom type
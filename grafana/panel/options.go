@@ -20,14 +20,23 @@ import (
 
 type GeneralOptions struct {
 	Description string            `json:"description"`
+	GridPos     *GridPos          `json:"gridPos,omitempty"` // position on dashboard's grid (schema version 16+)
 	Height      field.ForceString `json:"height"`
 	Links       []PanelLink       `json:"links"`
-	MinSpan     uint              `json:"minSpan"` // TODO: valid values: 1-12
-	Span        uint              `json:"span"`    // TODO: valid values: 1-12
+	MinSpan     uint              `json:"minSpan"` // 1-12
+	Span        uint              `json:"span"`    // 1-12
 	Title       string            `json:"title"`
 	Transparent bool              `json:"transparent"`
 }
 
+// GridPos is a position and size of panel on dashboard's grid. Grid is 24 units wide.
+type GridPos struct {
+	H uint `json:"h"`
+	W uint `json:"w"`
+	X uint `json:"x"`
+	Y uint `json:"y"`
+}
+
 type panelLinkType string
 
 const (
@@ -61,7 +70,31 @@ func NewPanelLink(linkType panelLinkType) *PanelLink {
 
 // Query is interface describes behaviour that we want from panel's queries
 type Query interface {
-	Datasource() string
+	Datasource() DatasourceRef
+}
+
+// QueryOptions are options common for queries of all datasources. Queries embedding QueryOptions keep their refIds
+// and datasources when dashboard is saved and loaded, refIds of other queries are generated.
+type QueryOptions struct {
+	RefID string `json:"refId,omitempty"` // ie. "A". It's referenced by alert conditions and other queries.
+	Hide  bool   `json:"hide,omitempty"`  // disables the query
+
+	datasource DatasourceRef
+}
+
+// Options returns common options of the query. It's promoted to queries embedding QueryOptions.
+func (o *QueryOptions) Options() *QueryOptions {
+	return o
+}
+
+// Datasource implements Query interface
+func (o *QueryOptions) Datasource() DatasourceRef {
+	return o.datasource
+}
+
+// SetDatasource sets datasource of the query.
+func (o *QueryOptions) SetDatasource(ref DatasourceRef) {
+	o.datasource = ref
 }
 
 type TimeRangeOptions struct {
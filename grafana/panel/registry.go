@@ -0,0 +1,90 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package panel
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Panel is a panel of dashboard. It's the same as grafana.Panel interface.
+type Panel interface {
+	GeneralOptions() *GeneralOptions
+}
+
+// Factory creates new empty panel of registered type.
+type Factory func() Panel
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+	types     map[reflect.Type]string
+}{
+	factories: make(map[string]Factory),
+	types:     make(map[reflect.Type]string),
+}
+
+// Types of built-in panels
+const (
+	TextType       = "text"
+	SinglestatType = "singlestat"
+	GraphType      = "graph"
+)
+
+func init() {
+	Register(TextType, func() Panel { return new(Text) })
+	Register(SinglestatType, func() Panel { return new(Singlestat) })
+	Register(GraphType, func() Panel { return new(Graph) })
+}
+
+// Register registers factory of panels of given type, ie. id of panel plugin. Dashboards decode panels of registered
+// types only, panels of other types are skipped. Factory of already registered type is replaced and panels of the
+// replaced factory are no longer recognized by TypeOf. Register panics if factory is nil.
+func Register(panelType string, factory Factory) {
+	if factory == nil {
+		panic(fmt.Sprintf("panel: nil factory of %q panel type", panelType))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if old, ok := registry.factories[panelType]; ok {
+		if t := reflect.TypeOf(old()); registry.types[t] == panelType {
+			delete(registry.types, t)
+		}
+	}
+	registry.factories[panelType] = factory
+	registry.types[reflect.TypeOf(factory())] = panelType
+}
+
+// New creates new empty panel of given type using registered factory. It returns false if the type is not
+// registered.
+func New(panelType string) (Panel, bool) {
+	registry.RLock()
+	factory, ok := registry.factories[panelType]
+	registry.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// TypeOf returns type of given panel which it's registered with. It returns false if the panel's type is not
+// registered.
+func TypeOf(p Panel) (string, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	t, ok := registry.types[reflect.TypeOf(p)]
+	return t, ok
+}
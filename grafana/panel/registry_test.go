@@ -0,0 +1,66 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package panel_test
+
+import (
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+)
+
+type worldmap struct {
+	generalOptions panel.GeneralOptions
+}
+
+func (p *worldmap) GeneralOptions() *panel.GeneralOptions {
+	return &p.generalOptions
+}
+
+type worldmapV2 struct {
+	worldmap
+}
+
+func TestRegister(t *testing.T) {
+	p, ok := panel.New(panel.GraphType)
+	if !ok {
+		t.Fatalf("panel.New: built-in %q type is not registered", panel.GraphType)
+	}
+	if _, isGraph := p.(*panel.Graph); !isGraph {
+		t.Errorf("panel.New: expected *panel.Graph, got %T", p)
+	}
+	if typ, _ := panel.TypeOf(panel.NewText(panel.TextPanelTextMode)); typ != panel.TextType {
+		t.Errorf("panel.TypeOf: expected %q, got %q", panel.TextType, typ)
+	}
+
+	if _, ok := panel.New("unknown-panel"); ok {
+		t.Errorf("panel.New: unregistered type should not be created")
+	}
+	panel.Register("worldmap-panel", func() panel.Panel { return new(worldmap) })
+	if p, ok := panel.New("worldmap-panel"); !ok {
+		t.Errorf("panel.New: registered type is not created")
+	} else if typ, _ := panel.TypeOf(p); typ != "worldmap-panel" {
+		t.Errorf("panel.TypeOf: expected %q, got %q", "worldmap-panel", typ)
+	}
+
+	panel.Register("worldmap-panel", func() panel.Panel { return new(worldmapV2) })
+	if _, ok := panel.New("worldmap-panel"); !ok {
+		t.Errorf("panel.New: re-registered type is not created")
+	}
+	if typ, ok := panel.TypeOf(new(worldmap)); ok {
+		t.Errorf("panel.TypeOf: replaced factory's type should not be registered, got %q", typ)
+	}
+	if typ, _ := panel.TypeOf(new(worldmapV2)); typ != "worldmap-panel" {
+		t.Errorf("panel.TypeOf: expected %q, got %q", "worldmap-panel", typ)
+	}
+}
@@ -28,11 +28,11 @@ type Singlestat struct {
 
 	// Options. Value.
 	ValueName       string `json:"valueName"`     // Stat: min/max/avg/current/total/name/first/delta/diff/range
-	ValueFontSize   string `json:"valueFontSize"` // 0%-100% TODO: validation
+	ValueFontSize   string `json:"valueFontSize"` // 0%-100%
 	Postfix         string `json:"postfix"`
-	PostfixFontSize string `json:"postfixFontSize"` // 0%-100% TODO: validation
+	PostfixFontSize string `json:"postfixFontSize"` // 0%-100%
 	Prefix          string `json:"prefix"`
-	PrefixFontSize  string `json:"prefixFontSize"` // 0%-100% TODO: validation
+	PrefixFontSize  string `json:"prefixFontSize"` // 0%-100%
 	Format          string `json:"format"`         // Unit option. TODO: make a custom type with constants
 
 	// Options. Coloring.
@@ -40,7 +40,7 @@ type Singlestat struct {
 	ColorBackground bool `json:"colorBackground"`
 	// Colorize value or not
 	ColorValue bool     `json:"colorValue"`
-	Thresholds string   `json:"thresholds"` // comma separated values "x,x". TODO: validation
+	Thresholds string   `json:"thresholds"` // comma separated values "x,x"
 	Colors     []string `json:"colors"`     // array of 3 colors, ie. rgba(50, 172, 45, 0.97)
 
 	// Options. Spark lines.
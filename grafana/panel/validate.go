@@ -0,0 +1,185 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package panel
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spoof/go-grafana/pkg/grid"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// Validate checks general options of the panel. Errors are reported with paths relative to the panel.
+func (o *GeneralOptions) Validate() error {
+	var errs validate.Errors
+	validateSpan(&errs, "span", o.Span)
+	validateSpan(&errs, "minSpan", o.MinSpan)
+
+	if pos := o.GridPos; pos != nil {
+		if pos.W == 0 || pos.W > grid.Columns {
+			errs.Addf("gridPos.w", "should be between 1 and %d, got %d", grid.Columns, pos.W)
+		} else if pos.X+pos.W > grid.Columns {
+			errs.Addf("gridPos.x", "panel of width %d doesn't fit into grid at %d", pos.W, pos.X)
+		}
+		if pos.H == 0 {
+			errs.Addf("gridPos.h", "should be positive")
+		}
+	}
+
+	for i, l := range o.Links {
+		path := validate.Index("links", i)
+		switch l.Type {
+		case PanelLinkAbsolute:
+			if l.URL == "" {
+				errs.Addf(validate.Join(path, "url"), "absolute link should have URL")
+			}
+		case PanelLinkDashboard:
+			if l.DashboardURI == "" && l.Dashboard == "" {
+				errs.Addf(validate.Join(path, "dashUri"), "dashboard link should refer to dashboard")
+			}
+		default:
+			errs.Addf(validate.Join(path, "type"), "unknown link type %q", l.Type)
+		}
+	}
+	return errs.Err()
+}
+
+func validateSpan(errs *validate.Errors, path string, span uint) {
+	// Zero span is not set, Grafana uses default span then
+	if span > 12 {
+		errs.Addf(path, "should be between 1 and 12, got %d", span)
+	}
+}
+
+// Validate checks options of Text panel.
+func (p *Text) Validate() error {
+	var errs validate.Errors
+	errs.Add("", p.generalOptions.Validate())
+
+	switch p.Mode {
+	case "", TextPanelHTMLMode, TextPanelMarkdownMode, TextPanelTextMode:
+	default:
+		errs.Addf("mode", "unknown mode %q", p.Mode)
+	}
+	return errs.Err()
+}
+
+var singlestatValueNames = []string{"min", "max", "avg", "current", "total", "name", "first", "delta", "diff", "range",
+	"last_time"}
+
+// Validate checks options of Singlestat panel: font sizes are percents between 0% and 100%, thresholds are two
+// comma separated numbers "x,y" and there are three colors for values below, between and above them.
+func (p *Singlestat) Validate() error {
+	var errs validate.Errors
+	errs.Add("", p.generalOptions.Validate())
+
+	if p.ValueName != "" && !contains(singlestatValueNames, p.ValueName) {
+		errs.Addf("valueName", "unknown stat %q", p.ValueName)
+	}
+	validateFontSize(&errs, "valueFontSize", p.ValueFontSize)
+	validateFontSize(&errs, "prefixFontSize", p.PrefixFontSize)
+	validateFontSize(&errs, "postfixFontSize", p.PostfixFontSize)
+
+	if p.Thresholds != "" {
+		values := strings.Split(p.Thresholds, ",")
+		if len(values) != 2 {
+			errs.Addf("thresholds", "should be two comma separated values \"x,y\", got %q", p.Thresholds)
+		} else {
+			for _, v := range values {
+				if _, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+					errs.Addf("thresholds", "%q is not a number", v)
+				}
+			}
+		}
+	}
+	if len(p.Colors) != 0 && len(p.Colors) != 3 {
+		errs.Addf("colors", "should be 3 colors, got %d", len(p.Colors))
+	}
+	return errs.Err()
+}
+
+var fontSizeRe = regexp.MustCompile(`^(\d+)%$`)
+
+func validateFontSize(errs *validate.Errors, path, size string) {
+	if size == "" {
+		return
+	}
+	m := fontSizeRe.FindStringSubmatch(size)
+	if m == nil {
+		errs.Addf(path, "should be percents, ie. 80%%, got %q", size)
+		return
+	}
+	if n, _ := strconv.Atoi(m[1]); n > 100 {
+		errs.Addf(path, "should be between 0%% and 100%%, got %q", size)
+	}
+}
+
+var graphLogBases = []int{0, 1, 2, 10, 32, 1024}
+
+// Validate checks options of Graph panel.
+func (p *Graph) Validate() error {
+	var errs validate.Errors
+	errs.Add("", p.generalOptions.Validate())
+
+	switch p.XAxis.Mode {
+	case "", graphXAxisTime, graphXAxisHistogram:
+	case graphXAxisSeries:
+		if len(p.XAxis.Values) > 1 {
+			errs.Addf("xaxis.values", "series mode should have single value, got %d", len(p.XAxis.Values))
+		}
+	default:
+		errs.Addf("xaxis.mode", "unknown mode %q", p.XAxis.Mode)
+	}
+
+	for i, axis := range []GraphYAxis{p.YAxes.Left, p.YAxes.Right} {
+		if !containsInt(graphLogBases, axis.LogBase) {
+			errs.Addf(validate.Join(validate.Index("yaxes", i), "logBase"), "unsupported log base %d", axis.LogBase)
+		}
+	}
+
+	for i, t := range p.Thresholds {
+		path := validate.Index("thresholds", i)
+		switch t.Op {
+		case GreaterOp, LessOp:
+		default:
+			errs.Addf(validate.Join(path, "op"), "should be %q or %q, got %q", GreaterOp, LessOp, t.Op)
+		}
+		switch t.Mode {
+		case CustomThresholdMode, CriticalThresholdMode, WarningThresholdMode, OKThresholdMode:
+		default:
+			errs.Addf(validate.Join(path, "colorMode"), "unknown mode %q", t.Mode)
+		}
+	}
+	return errs.Err()
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, v int) bool {
+	for _, n := range values {
+		if n == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package panel_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// errorPaths returns paths of validation errors
+func errorPaths(err error) []string {
+	if err == nil {
+		return nil
+	}
+	var paths []string
+	for _, e := range err.(validate.Errors) {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
+
+func TestSinglestat_Validate(t *testing.T) {
+	valid := panel.NewSinglestat()
+	valid.ValueName = "avg"
+	valid.ValueFontSize = "80%"
+	valid.Thresholds = "20, 80.5"
+	valid.Colors = []string{"#299c46", "rgba(237, 129, 40, 0.89)", "#d44a3a"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Singlestat.Validate returned error %s", err)
+	}
+
+	p := panel.NewSinglestat()
+	p.GeneralOptions().Span = 13
+	p.ValueName = "median"
+	p.ValueFontSize = "120%"
+	p.PrefixFontSize = "12px"
+	p.Thresholds = "20"
+	p.Colors = []string{"#299c46"}
+	expected := []string{"span", "valueName", "valueFontSize", "prefixFontSize", "thresholds", "colors"}
+	if paths := errorPaths(p.Validate()); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Singlestat.Validate: expected errors of %v, got %v", expected, paths)
+	}
+}
+
+func TestGraph_Validate(t *testing.T) {
+	if err := panel.NewGraph().Validate(); err != nil {
+		t.Errorf("Graph.Validate returned error %s for new panel", err)
+	}
+
+	p := panel.NewGraph()
+	p.GeneralOptions().GridPos = &panel.GridPos{X: 12, W: 16, H: 8}
+	p.GeneralOptions().Links = []panel.PanelLink{*panel.NewPanelLink(panel.PanelLinkAbsolute)}
+	p.XAxis.Mode = "category"
+	p.YAxes.Right.LogBase = 3
+	p.Thresholds = []panel.Threshold{{Mode: panel.CriticalThresholdMode, Op: panel.EqualSignOp}}
+	expected := []string{"gridPos.x", "links[0].url", "xaxis.mode", "yaxes[1].logBase", "thresholds[0].op"}
+	if paths := errorPaths(p.Validate()); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Graph.Validate: expected errors of %v, got %v", expected, paths)
+	}
+}
+
+func TestText_Validate(t *testing.T) {
+	p := panel.NewText("latex")
+	expected := []string{"mode"}
+	if paths := errorPaths(p.Validate()); !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Text.Validate: expected errors of %v, got %v", expected, paths)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+// permissionLevel is level of access to a dashboard or a folder
+type permissionLevel uint8
+
+// Levels of permissions
+const (
+	ViewPermission  permissionLevel = 1
+	EditPermission  permissionLevel = 2
+	AdminPermission permissionLevel = 4
+)
+
+// Permission is an item of access control list of a dashboard or a folder. It grants the permission level either to
+// a role, a user or a team.
+type Permission struct {
+	Role       string          `json:"role,omitempty"` // Viewer or Editor
+	UserID     uint            `json:"userId,omitempty"`
+	UserLogin  string          `json:"userLogin,omitempty"`
+	TeamID     uint            `json:"teamId,omitempty"`
+	Team       string          `json:"team,omitempty"`
+	Permission permissionLevel `json:"permission"`
+
+	// Inherited is set for permissions of dashboards inherited from their folders.
+	Inherited bool `json:"inherited,omitempty"`
+}
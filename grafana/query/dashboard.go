@@ -0,0 +1,31 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import "github.com/spoof/go-grafana/grafana/panel"
+
+// Dashboard is query of dashboard datasource which reuses results of another panel of the same dashboard. Panels
+// get sequential ids when dashboard is saved, so PanelID must be the id the source panel gets.
+type Dashboard struct {
+	PanelID uint `json:"panelId"`
+
+	panel.QueryOptions
+}
+
+// NewDashboard creates new query reusing results of panel with given id.
+func NewDashboard(panelID uint) *Dashboard {
+	q := &Dashboard{PanelID: panelID}
+	q.SetDatasource(panel.DashboardDatasourceRef())
+	return q
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"strconv"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/field"
+)
+
+type esMetricType string
+
+// Types of Elasticsearch metrics
+const (
+	ESCount         esMetricType = "count"
+	ESAvg           esMetricType = "avg"
+	ESSum           esMetricType = "sum"
+	ESMax           esMetricType = "max"
+	ESMin           esMetricType = "min"
+	ESExtendedStats esMetricType = "extended_stats"
+	ESPercentiles   esMetricType = "percentiles"
+	ESCardinality   esMetricType = "cardinality"
+	ESDerivative    esMetricType = "derivative"
+	ESMovingAvg     esMetricType = "moving_avg"
+	ESRawDocument   esMetricType = "raw_document"
+)
+
+type esBucketAggType string
+
+// Types of Elasticsearch bucket aggregations
+const (
+	ESDateHistogram esBucketAggType = "date_histogram"
+	ESTerms         esBucketAggType = "terms"
+	ESFilters       esBucketAggType = "filters"
+	ESGeohashGrid   esBucketAggType = "geohash_grid"
+	ESHistogram     esBucketAggType = "histogram"
+)
+
+// Elasticsearch is query specific options for Elasticsearch datasource.
+type Elasticsearch struct {
+	Query      string                    `json:"query"` // Lucene query
+	Alias      string                    `json:"alias,omitempty"`
+	TimeField  string                    `json:"timeField"`
+	Metrics    []*ElasticsearchMetric    `json:"metrics"`
+	BucketAggs []*ElasticsearchBucketAgg `json:"bucketAggs"`
+
+	panel.QueryOptions
+}
+
+// NewElasticsearch creates new instance of Elasticsearch query with Grafana's defaults: count of documents grouped
+// by @timestamp date histogram.
+func NewElasticsearch(datasourceName string) *Elasticsearch {
+	q := &Elasticsearch{
+		TimeField:    "@timestamp",
+		Metrics:      []*ElasticsearchMetric{},
+		BucketAggs:   []*ElasticsearchBucketAgg{},
+		QueryOptions: newQueryOptions(datasourceName),
+	}
+	q.AddMetric(ESCount, "")
+	agg := q.AddBucketAgg(ESDateHistogram, q.TimeField)
+	agg.Settings.Interval = "auto"
+	agg.Settings.MinDocCount = "0"
+	agg.Settings.TrimEdges = "0"
+	return q
+}
+
+// AddMetric adds metric of given type and returns it for further setup. Metric gets the next free id.
+func (q *Elasticsearch) AddMetric(metricType esMetricType, field string) *ElasticsearchMetric {
+	m := &ElasticsearchMetric{
+		ID:    q.nextID(),
+		Type:  metricType,
+		Field: field,
+	}
+	q.Metrics = append(q.Metrics, m)
+	return m
+}
+
+// AddBucketAgg adds bucket aggregation of given type and returns it for further setup. Aggregation gets the next
+// free id.
+func (q *Elasticsearch) AddBucketAgg(aggType esBucketAggType, field string) *ElasticsearchBucketAgg {
+	agg := &ElasticsearchBucketAgg{
+		ID:    q.nextID(),
+		Type:  aggType,
+		Field: field,
+	}
+	q.BucketAggs = append(q.BucketAggs, agg)
+	return agg
+}
+
+// nextID returns id for new metric or aggregation. Metrics and aggregations share ids.
+func (q *Elasticsearch) nextID() string {
+	max := 0
+	for _, m := range q.Metrics {
+		if id, err := strconv.Atoi(m.ID); err == nil && id > max {
+			max = id
+		}
+	}
+	for _, a := range q.BucketAggs {
+		if id, err := strconv.Atoi(a.ID); err == nil && id > max {
+			max = id
+		}
+	}
+	return strconv.Itoa(max + 1)
+}
+
+// ElasticsearchMetric is a metric aggregation of Elasticsearch query.
+type ElasticsearchMetric struct {
+	ID          string                      `json:"id"`
+	Type        esMetricType                `json:"type"`
+	Field       string                      `json:"field,omitempty"`
+	PipelineAgg string                      `json:"pipelineAgg,omitempty"` // id of metric for derivative and moving_avg
+	Hide        bool                        `json:"hide,omitempty"`
+	Meta        map[string]bool             `json:"meta,omitempty"` // extended_stats to show, ie. "std_deviation"
+	Settings    ElasticsearchMetricSettings `json:"settings"`
+}
+
+// ElasticsearchMetricSettings are settings of Elasticsearch metric. Each type of metric uses its own settings.
+type ElasticsearchMetricSettings struct {
+	Script  string `json:"script,omitempty"`
+	Missing string `json:"missing,omitempty"`
+
+	// percentiles
+	Percents []string `json:"percents,omitempty"` // ie. "25", "99.9"
+
+	// cardinality
+	PrecisionThreshold field.ForceString `json:"precision_threshold,omitempty"`
+
+	// derivative
+	Unit string `json:"unit,omitempty"` // ie. "1s"
+
+	// moving_avg
+	Model       string              `json:"model,omitempty"` // simple, linear, ewma, holt, holt_winters
+	Window      uint                `json:"window,omitempty"`
+	Predict     uint                `json:"predict,omitempty"`
+	Minimize    bool                `json:"minimize,omitempty"`
+	ModelParams *MovingAvgModelOpts `json:"settings,omitempty"`
+}
+
+// MovingAvgModelOpts are parameters of moving average models.
+type MovingAvgModelOpts struct {
+	Alpha  *float64 `json:"alpha,omitempty"`
+	Beta   *float64 `json:"beta,omitempty"`
+	Gamma  *float64 `json:"gamma,omitempty"`
+	Period *uint    `json:"period,omitempty"`
+	Pad    bool     `json:"pad,omitempty"`
+}
+
+// ElasticsearchBucketAgg is a bucket aggregation (group by) of Elasticsearch query.
+type ElasticsearchBucketAgg struct {
+	ID       string                         `json:"id"`
+	Type     esBucketAggType                `json:"type"`
+	Field    string                         `json:"field,omitempty"`
+	Settings ElasticsearchBucketAggSettings `json:"settings"`
+}
+
+// ElasticsearchBucketAggSettings are settings of Elasticsearch bucket aggregation. Each type of aggregation uses its
+// own settings.
+type ElasticsearchBucketAggSettings struct {
+	MinDocCount field.ForceString `json:"min_doc_count,omitempty"`
+
+	// date_histogram and histogram
+	Interval  string            `json:"interval,omitempty"` // ie. "auto", "1m"
+	TrimEdges field.ForceString `json:"trimEdges,omitempty"`
+	Offset    string            `json:"offset,omitempty"`
+
+	// terms
+	Size    field.ForceString `json:"size,omitempty"`
+	Order   string            `json:"order,omitempty"`   // asc or desc
+	OrderBy string            `json:"orderBy,omitempty"` // _term, _count or id of the metric
+
+	// filters
+	Filters []ElasticsearchFilter `json:"filters,omitempty"`
+
+	// geohash_grid
+	Precision field.ForceString `json:"precision,omitempty"`
+}
+
+// ElasticsearchFilter is a single filter of "filters" bucket aggregation.
+type ElasticsearchFilter struct {
+	Query string `json:"query"`
+	Label string `json:"label"`
+}
@@ -0,0 +1,115 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/spoof/go-grafana/grafana/query"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+func TestElasticsearch_MarshalJSON(t *testing.T) {
+	q := query.NewElasticsearch("Elasticsearch")
+	q.Query = "status:500"
+	q.Alias = "{{term host}}"
+	// Metrics and aggregations are set up after others are added
+	p := q.AddMetric(query.ESPercentiles, "duration")
+	d := q.AddMetric(query.ESDerivative, "")
+	terms := q.AddBucketAgg(query.ESTerms, "host")
+	p.Settings.Percents = []string{"50", "99.9"}
+	d.PipelineAgg = "1"
+	d.Settings.Unit = "1s"
+	terms.Settings.Size = "10"
+	terms.Settings.Order = "desc"
+	terms.Settings.OrderBy = "_count"
+
+	got, err := json.MarshalIndent(q, "", "\t\t")
+	if err != nil {
+		t.Fatalf("Elasticsearch.MarshalJSON returned error %s", err)
+	}
+	expected := []byte(`{
+		"query": "status:500",
+		"alias": "{{term host}}",
+		"timeField": "@timestamp",
+		"metrics": [
+			{"id": "1", "type": "count", "settings": {}},
+			{"id": "3", "type": "percentiles", "field": "duration", "settings": {"percents": ["50", "99.9"]}},
+			{"id": "4", "type": "derivative", "pipelineAgg": "1", "settings": {"unit": "1s"}}
+		],
+		"bucketAggs": [
+			{"id": "2", "type": "date_histogram", "field": "@timestamp",
+				"settings": {"interval": "auto", "min_doc_count": "0", "trimEdges": "0"}},
+			{"id": "5", "type": "terms", "field": "host", "settings": {"size": "10", "order": "desc", "orderBy": "_count"}}
+		]
+	}`)
+	if eq, err := jsontools.BytesEqual(expected, got); err != nil {
+		t.Fatalf("Elasticsearch.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("Elasticsearch.MarshalJSON: got %s, want %s\n", got, expected)
+	}
+}
+
+func TestElasticsearch_UnmarshalJSON(t *testing.T) {
+	data := []byte(`{
+		"query": "*",
+		"timeField": "timestamp",
+		"metrics": [
+			{"id": "1", "type": "moving_avg", "pipelineAgg": "3",
+				"settings": {"model": "holt", "window": 5, "predict": 2, "minimize": true, "settings": {"alpha": 0.5}}},
+			{"id": "3", "type": "cardinality", "field": "user", "settings": {"precision_threshold": 100}}
+		],
+		"bucketAggs": [
+			{"id": "2", "type": "filters", "settings": {"filters": [{"query": "status:200", "label": "ok"}]}},
+			{"id": "4", "type": "geohash_grid", "field": "location", "settings": {"precision": 3}},
+			{"id": "5", "type": "date_histogram", "field": "timestamp", "settings": {"interval": "1m", "min_doc_count": 0}}
+		]
+	}`)
+	var got query.Elasticsearch
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Elasticsearch.UnmarshalJSON returned error %s", err)
+	}
+
+	alpha := 0.5
+	expected := query.Elasticsearch{
+		Query:     "*",
+		TimeField: "timestamp",
+		Metrics: []*query.ElasticsearchMetric{
+			{ID: "1", Type: query.ESMovingAvg, PipelineAgg: "3", Settings: query.ElasticsearchMetricSettings{
+				Model: "holt", Window: 5, Predict: 2, Minimize: true,
+				ModelParams: &query.MovingAvgModelOpts{Alpha: &alpha},
+			}},
+			{ID: "3", Type: query.ESCardinality, Field: "user", Settings: query.ElasticsearchMetricSettings{
+				PrecisionThreshold: "100",
+			}},
+		},
+		BucketAggs: []*query.ElasticsearchBucketAgg{
+			{ID: "2", Type: query.ESFilters, Settings: query.ElasticsearchBucketAggSettings{
+				Filters: []query.ElasticsearchFilter{{Query: "status:200", Label: "ok"}},
+			}},
+			{ID: "4", Type: query.ESGeohashGrid, Field: "location", Settings: query.ElasticsearchBucketAggSettings{
+				Precision: "3",
+			}},
+			{ID: "5", Type: query.ESDateHistogram, Field: "timestamp", Settings: query.ElasticsearchBucketAggSettings{
+				Interval: "1m", MinDocCount: "0",
+			}},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Elasticsearch.UnmarshalJSON: %s", pretty.Diff(got, expected))
+	}
+}
@@ -13,22 +13,19 @@
 
 package query
 
+import "github.com/spoof/go-grafana/grafana/panel"
+
 // Graphite is query specific options for Graphite datasource.
 type Graphite struct {
 	Target     string `json:"target"`
 	TargetFull string `json:"targetFull,omitempty"`
 
-	datasource string
+	panel.QueryOptions
 }
 
 // NewGraphite creates new instance of Graphite query
 func NewGraphite(datasourceName string) *Graphite {
 	return &Graphite{
-		datasource: datasourceName,
+		QueryOptions: newQueryOptions(datasourceName),
 	}
 }
-
-// Datasource implements panel.Query interface
-func (q *Graphite) Datasource() string {
-	return q.datasource
-}
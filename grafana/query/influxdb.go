@@ -0,0 +1,266 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/field"
+)
+
+type influxResultFormat string
+
+// Result formats of InfluxDB query
+const (
+	InfluxTimeSeries influxResultFormat = "time_series"
+	InfluxTable      influxResultFormat = "table"
+	InfluxLogs       influxResultFormat = "logs"
+)
+
+// Types of InfluxDB query parts which are rendered specially. Other types of select parts are rendered as
+// functions, ie. mean(), percentile(95) or derivative(10s).
+const (
+	InfluxField = "field"
+	InfluxAlias = "alias"
+	InfluxMath  = "math"
+	InfluxTime  = "time"
+	InfluxTag   = "tag"
+	InfluxFill  = "fill"
+)
+
+// InfluxDB is query specific options for InfluxDB datasource. The query is either raw InfluxQL query (RawQuery is
+// true) or structured query built in Grafana's query editor.
+type InfluxDB struct {
+	Alias        string                `json:"alias,omitempty"`
+	GroupBy      []InfluxDBQueryPart   `json:"groupBy"`
+	Limit        field.ForceString     `json:"limit,omitempty"`
+	Measurement  string                `json:"measurement,omitempty"`
+	OrderByTime  string                `json:"orderByTime,omitempty"` // ASC or DESC
+	Policy       string                `json:"policy,omitempty"`      // retention policy
+	Query        string                `json:"query,omitempty"`       // raw InfluxQL query
+	RawQuery     bool                  `json:"rawQuery"`
+	ResultFormat influxResultFormat    `json:"resultFormat"`
+	Select       [][]InfluxDBQueryPart `json:"select"`
+	SLimit       field.ForceString     `json:"slimit,omitempty"`
+	Tags         []InfluxDBTag         `json:"tags"`
+	Tz           string                `json:"tz,omitempty"`
+
+	panel.QueryOptions
+}
+
+// NewInfluxDB creates new instance of InfluxDB query with Grafana's defaults: mean of "value" field grouped by
+// $__interval with null fill.
+func NewInfluxDB(datasourceName string) *InfluxDB {
+	return &InfluxDB{
+		GroupBy: []InfluxDBQueryPart{
+			NewInfluxDBQueryPart(InfluxTime, "$__interval"),
+			NewInfluxDBQueryPart(InfluxFill, "null"),
+		},
+		OrderByTime:  "ASC",
+		Policy:       "default",
+		ResultFormat: InfluxTimeSeries,
+		Select: [][]InfluxDBQueryPart{{
+			NewInfluxDBQueryPart(InfluxField, "value"),
+			NewInfluxDBQueryPart("mean"),
+		}},
+		Tags:         []InfluxDBTag{},
+		QueryOptions: newQueryOptions(datasourceName),
+	}
+}
+
+// NewRawInfluxDB creates new instance of InfluxDB query with given raw InfluxQL query.
+func NewRawInfluxDB(datasourceName, query string) *InfluxDB {
+	q := NewInfluxDB(datasourceName)
+	q.RawQuery = true
+	q.Query = query
+	return q
+}
+
+// InfluxDBQueryPart is a part of select or group by clause of InfluxDB query.
+type InfluxDBQueryPart struct {
+	Type   string   `json:"type"`
+	Params []string `json:"params"`
+}
+
+// NewInfluxDBQueryPart creates query part of given type with given params.
+func NewInfluxDBQueryPart(partType string, params ...string) InfluxDBQueryPart {
+	if params == nil {
+		params = []string{}
+	}
+	return InfluxDBQueryPart{Type: partType, Params: params}
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (p *InfluxDBQueryPart) UnmarshalJSON(data []byte) error {
+	// Numeric params are stored as numbers sometimes
+	var jp struct {
+		Type   string              `json:"type"`
+		Params []field.ForceString `json:"params"`
+	}
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+
+	p.Type = jp.Type
+	p.Params = make([]string, len(jp.Params))
+	for i, param := range jp.Params {
+		p.Params[i] = string(param)
+	}
+	return nil
+}
+
+// InfluxDBTag is a condition on tag value in where clause of InfluxDB query.
+type InfluxDBTag struct {
+	Condition string `json:"condition,omitempty"` // AND or OR. Ignored for the first tag.
+	Key       string `json:"key"`
+	Operator  string `json:"operator,omitempty"` // =, !=, <, >, =~, !~. Guessed from value if empty.
+	Value     string `json:"value"`
+}
+
+var influxRegexRe = regexp.MustCompile(`^/.*/$`)
+
+// Render renders InfluxQL query. Raw query is returned as is, structured query is rendered the same way as
+// Grafana's query editor does it. Variables are not interpolated.
+func (q *InfluxDB) Render() string {
+	if q.RawQuery {
+		return q.Query
+	}
+
+	selects := make([]string, len(q.Select))
+	for i, parts := range q.Select {
+		var expr string
+		for _, p := range parts {
+			expr = p.render(expr)
+		}
+		selects[i] = expr
+	}
+
+	query := "SELECT " + strings.Join(selects, ", ") + " FROM " + q.renderMeasurement() + " WHERE "
+
+	conditions := make([]string, len(q.Tags))
+	for i, t := range q.Tags {
+		conditions[i] = t.render(i)
+	}
+	if len(conditions) > 0 {
+		query += "(" + strings.Join(conditions, " ") + ") AND "
+	}
+	query += "$timeFilter"
+
+	var groupBy string
+	for i, p := range q.GroupBy {
+		if i > 0 {
+			if p.Type == InfluxFill {
+				groupBy += " "
+			} else {
+				groupBy += ", "
+			}
+		}
+		groupBy += p.render("")
+	}
+	if groupBy != "" {
+		query += " GROUP BY " + groupBy
+	}
+
+	if q.OrderByTime == "DESC" {
+		query += " ORDER BY time DESC"
+	}
+	if q.Limit != "" {
+		query += " LIMIT " + string(q.Limit)
+	}
+	if q.SLimit != "" {
+		query += " SLIMIT " + string(q.SLimit)
+	}
+	if q.Tz != "" {
+		query += " tz('" + q.Tz + "')"
+	}
+	return query
+}
+
+func (q *InfluxDB) renderMeasurement() string {
+	measurement := q.Measurement
+	if measurement == "" {
+		measurement = "measurement"
+	}
+	if !influxRegexRe.MatchString(measurement) {
+		measurement = `"` + measurement + `"`
+	}
+
+	if q.Policy == "" || q.Policy == "default" {
+		return measurement
+	}
+	return `"` + q.Policy + `".` + measurement
+}
+
+func (p InfluxDBQueryPart) render(inner string) string {
+	switch p.Type {
+	case InfluxField, InfluxTag:
+		if len(p.Params) == 0 {
+			return inner
+		}
+		if p.Params[0] == "*" {
+			return "*"
+		}
+		return `"` + p.Params[0] + `"`
+	case InfluxAlias:
+		if len(p.Params) == 0 {
+			return inner
+		}
+		return inner + ` AS "` + p.Params[0] + `"`
+	case InfluxMath:
+		if len(p.Params) == 0 {
+			return inner
+		}
+		return inner + " " + p.Params[0]
+	}
+
+	var params []string
+	if inner != "" {
+		params = append(params, inner)
+	}
+	for _, param := range p.Params {
+		if param == "auto" && p.Type == InfluxTime {
+			param = "$__interval"
+		}
+		params = append(params, param)
+	}
+	return p.Type + "(" + strings.Join(params, ", ") + ")"
+}
+
+func (t InfluxDBTag) render(index int) string {
+	var str string
+	if index > 0 {
+		condition := t.Condition
+		if condition == "" {
+			condition = "AND"
+		}
+		str = condition + " "
+	}
+
+	operator := t.Operator
+	if operator == "" {
+		operator = "="
+		if influxRegexRe.MatchString(t.Value) {
+			operator = "=~"
+		}
+	}
+
+	value := t.Value
+	if operator != "=~" && operator != "!~" && operator != ">" && operator != "<" {
+		value = "'" + strings.Replace(strings.Replace(value, `\`, `\\`, -1), "'", `\'`, -1) + "'"
+	}
+	return str + `"` + t.Key + `" ` + operator + " " + value
+}
@@ -0,0 +1,129 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kr/pretty"
+	"github.com/spoof/go-grafana/grafana/query"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+func TestInfluxDB_Render(t *testing.T) {
+	q := query.NewInfluxDB("InfluxDB")
+	q.Measurement = "cpu"
+
+	regexMeasurement := query.NewInfluxDB("InfluxDB")
+	regexMeasurement.Measurement = "/^cpu.*/"
+	regexMeasurement.Policy = "autogen"
+	regexMeasurement.Select = [][]query.InfluxDBQueryPart{
+		{
+			query.NewInfluxDBQueryPart(query.InfluxField, "usage"),
+			query.NewInfluxDBQueryPart("percentile", "95"),
+			query.NewInfluxDBQueryPart(query.InfluxMath, "/ 100"),
+			query.NewInfluxDBQueryPart(query.InfluxAlias, "p95"),
+		},
+		{
+			query.NewInfluxDBQueryPart(query.InfluxField, "usage"),
+			query.NewInfluxDBQueryPart("max"),
+		},
+	}
+	regexMeasurement.Tags = []query.InfluxDBTag{
+		{Key: "host", Value: "/^$host$/"},
+		{Key: "region", Operator: "!=", Value: "o'hare", Condition: "OR"},
+		{Key: "cores", Operator: ">", Value: "4"},
+	}
+	regexMeasurement.GroupBy = []query.InfluxDBQueryPart{
+		query.NewInfluxDBQueryPart(query.InfluxTime, "auto"),
+		query.NewInfluxDBQueryPart(query.InfluxTag, "host"),
+		query.NewInfluxDBQueryPart(query.InfluxFill, "0"),
+	}
+	regexMeasurement.OrderByTime = "DESC"
+	regexMeasurement.Limit = "10"
+	regexMeasurement.SLimit = "5"
+	regexMeasurement.Tz = "Europe/Moscow"
+
+	ts := []struct {
+		query    *query.InfluxDB
+		expected string
+	}{
+		{
+			query:    q,
+			expected: `SELECT mean("value") FROM "cpu" WHERE $timeFilter GROUP BY time($__interval) fill(null)`,
+		},
+		{
+			query: regexMeasurement,
+			expected: `SELECT percentile("usage", 95) / 100 AS "p95", max("usage") FROM "autogen"./^cpu.*/ ` +
+				`WHERE ("host" =~ /^$host$/ OR "region" != 'o\'hare' AND "cores" > 4) AND $timeFilter ` +
+				`GROUP BY time($__interval), "host" fill(0) ORDER BY time DESC LIMIT 10 SLIMIT 5 tz('Europe/Moscow')`,
+		},
+		{
+			query:    query.NewRawInfluxDB("InfluxDB", `SELECT * FROM "cpu"`),
+			expected: `SELECT * FROM "cpu"`,
+		},
+	}
+
+	for _, tt := range ts {
+		if got := tt.query.Render(); got != tt.expected {
+			t.Errorf("InfluxDB.Render:\nexpected %s\ngot      %s", tt.expected, got)
+		}
+	}
+}
+
+func TestInfluxDB_MarshalJSON(t *testing.T) {
+	q := query.NewInfluxDB("InfluxDB")
+	q.Measurement = "cpu"
+	q.Alias = "$tag_host"
+	q.Tags = []query.InfluxDBTag{{Key: "host", Operator: "=", Value: "server1"}}
+
+	got, err := json.Marshal(q)
+	if err != nil {
+		t.Fatalf("InfluxDB.MarshalJSON returned error %s", err)
+	}
+	expected := []byte(`{
+		"alias": "$tag_host",
+		"groupBy": [{"type": "time", "params": ["$__interval"]}, {"type": "fill", "params": ["null"]}],
+		"measurement": "cpu",
+		"orderByTime": "ASC",
+		"policy": "default",
+		"rawQuery": false,
+		"resultFormat": "time_series",
+		"select": [[{"type": "field", "params": ["value"]}, {"type": "mean", "params": []}]],
+		"tags": [{"key": "host", "operator": "=", "value": "server1"}]
+	}`)
+	if eq, err := jsontools.BytesEqual(expected, got); err != nil {
+		t.Fatalf("InfluxDB.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("InfluxDB.MarshalJSON: got %s, want %s\n", got, expected)
+	}
+}
+
+func TestInfluxDBQueryPart_UnmarshalJSON(t *testing.T) {
+	data := []byte(`[{"type": "percentile", "params": [99.9]}, {"type": "top", "params": ["host", 3]}]`)
+	var got []query.InfluxDBQueryPart
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("InfluxDBQueryPart.UnmarshalJSON returned error %s", err)
+	}
+
+	expected := []query.InfluxDBQueryPart{
+		query.NewInfluxDBQueryPart("percentile", "99.9"),
+		query.NewInfluxDBQueryPart("top", "host", "3"),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("InfluxDBQueryPart.UnmarshalJSON: %s", pretty.Diff(got, expected))
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import "github.com/spoof/go-grafana/grafana/panel"
+
+// Loki is query specific options for Loki datasource.
+type Loki struct {
+	Expression   string `json:"expr"` // LogQL query
+	LegendFormat string `json:"legendFormat,omitempty"`
+	MaxLines     uint   `json:"maxLines,omitempty"`   // datasource's limit if zero
+	Resolution   uint   `json:"resolution,omitempty"` // 1/N of data points, from 1 to 5
+
+	panel.QueryOptions
+}
+
+// NewLoki creates new instance of Loki query with given LogQL expression.
+func NewLoki(datasourceName, expr string) *Loki {
+	return &Loki{
+		Expression:   expr,
+		QueryOptions: newQueryOptions(datasourceName),
+	}
+}
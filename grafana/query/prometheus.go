@@ -13,6 +13,8 @@
 
 package query
 
+import "github.com/spoof/go-grafana/grafana/panel"
+
 // Prometheus is query specific options for Prometheus datasource.
 type Prometheus struct {
 	IntervalFactor uint `json:"intervalFactor"`
@@ -24,17 +26,12 @@ type Prometheus struct {
 	LegendFormat string `json:"legendFormat,omitempty"`
 	Step         uint   `json:"step,omitempty"`
 
-	datasource string
+	panel.QueryOptions
 }
 
 // NewPrometheus creates new instance of Prometheus query.
 func NewPrometheus(datasourceName string) *Prometheus {
 	return &Prometheus{
-		datasource: datasourceName,
+		QueryOptions: newQueryOptions(datasourceName),
 	}
 }
-
-// Datasource implements panel.Query interface
-func (q *Prometheus) Datasource() string {
-	return q.datasource
-}
@@ -0,0 +1,125 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+)
+
+// Factory creates new empty query of registered datasource type.
+type Factory func() panel.Query
+
+type registration struct {
+	factory Factory
+	fields  []string
+}
+
+var registry = struct {
+	sync.RWMutex
+	queries map[string]registration
+	order   []string // datasource types with fields in order of registration
+}{
+	queries: make(map[string]registration),
+}
+
+func init() {
+	Register("prometheus", func() panel.Query { return new(Prometheus) })
+	Register("graphite", func() panel.Query { return new(Graphite) })
+	Register("elasticsearch", func() panel.Query { return new(Elasticsearch) })
+	Register("influxdb", func() panel.Query { return new(InfluxDB) })
+	Register(string(MySQL), func() panel.Query { return &SQL{Dialect: MySQL} })
+	Register(string(PostgreSQL), func() panel.Query { return &SQL{Dialect: PostgreSQL} })
+	Register(string(MSSQL), func() panel.Query { return &SQL{Dialect: MSSQL} })
+	Register("loki", func() panel.Query { return new(Loki) })
+	Register("testdata", func() panel.Query { return new(TestData) })
+	Register("grafana-testdata-datasource", func() panel.Query { return new(TestData) })
+	Register("dashboard", func() panel.Query { return new(Dashboard) }, "panelId")
+}
+
+// Register registers factory of queries of datasources of given type, ie. id of datasource plugin. Dashboards decode
+// queries into registered types by types of their datasources.
+//
+// Fields are names of JSON fields specific for the query. They are used to guess type of query if type of its
+// datasource is unknown: query is decoded into the registered type if it has all of the fields. Registered queries
+// are guessed before built-in ones. Factory of already registered type is replaced. Register panics if factory is
+// nil.
+func Register(datasourceType string, factory Factory, fields ...string) {
+	if factory == nil {
+		panic(fmt.Sprintf("query: nil factory of %q datasource type", datasourceType))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if prev, ok := registry.queries[datasourceType]; ok && len(prev.fields) > 0 {
+		for i, t := range registry.order {
+			if t == datasourceType {
+				registry.order = append(registry.order[:i], registry.order[i+1:]...)
+				break
+			}
+		}
+	}
+	registry.queries[datasourceType] = registration{factory: factory, fields: fields}
+	if len(fields) > 0 {
+		registry.order = append(registry.order, datasourceType)
+	}
+}
+
+// New creates new empty query for datasource of given type using registered factory. It returns false if the type
+// is not registered.
+func New(datasourceType string) (panel.Query, bool) {
+	registry.RLock()
+	r, ok := registry.queries[datasourceType]
+	registry.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return r.factory(), true
+}
+
+// Guess creates new empty query of registered type whose fields are all present in given JSON object. It returns
+// false if there is no such type.
+func Guess(data []byte) (panel.Query, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+	for _, t := range registry.order {
+		r := registry.queries[t]
+		matched := true
+		for _, f := range r.fields {
+			if _, ok := fields[f]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return r.factory(), true
+		}
+	}
+	return nil, false
+}
+
+// newQueryOptions returns common options of query of datasource with given name.
+func newQueryOptions(datasourceName string) panel.QueryOptions {
+	var o panel.QueryOptions
+	o.SetDatasource(panel.NewDatasourceRef(datasourceName))
+	return o
+}
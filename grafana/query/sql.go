@@ -0,0 +1,352 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/field"
+	"github.com/spoof/go-grafana/pkg/interval"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// SQLDialect is a dialect of SQL datasource. Values are the same as Grafana's datasource types.
+type SQLDialect string
+
+// SQL dialects supported by Grafana
+const (
+	MySQL      SQLDialect = "mysql"
+	PostgreSQL SQLDialect = "postgres"
+	MSSQL      SQLDialect = "mssql"
+)
+
+type sqlFormat string
+
+// Result formats of SQL query
+const (
+	SQLTimeSeries sqlFormat = "time_series"
+	SQLTable      sqlFormat = "table"
+)
+
+// Types of SQL query parts of Grafana's query builder
+const (
+	SQLColumn     = "column"
+	SQLAggregate  = "aggregate"
+	SQLPercentile = "percentile"
+	SQLWindow     = "window"
+	SQLAlias      = "alias"
+	SQLExpression = "expression"
+	SQLMacro      = "macro"
+	SQLTime       = "time"
+)
+
+// SQL is query specific options for MySQL, PostgreSQL and MSSQL datasources. Grafana's query builder always keeps
+// RawSQL rendered, so structured fields are informational only and RawSQL is the query which is executed.
+type SQL struct {
+	Format         sqlFormat        `json:"format"`
+	RawQuery       bool             `json:"rawQuery"`
+	RawSQL         string           `json:"rawSql"`
+	Table          string           `json:"table,omitempty"`
+	TimeColumn     string           `json:"timeColumn,omitempty"`
+	TimeColumnType string           `json:"timeColumnType,omitempty"`
+	MetricColumn   string           `json:"metricColumn,omitempty"` // "none" if not set in query builder
+	Select         [][]SQLQueryPart `json:"select,omitempty"`
+	Where          []SQLQueryPart   `json:"where,omitempty"`
+	Group          []SQLQueryPart   `json:"group,omitempty"`
+
+	// Dialect is not stored in Grafana's query. It's set by constructors and used to expand macros.
+	Dialect SQLDialect `json:"-"`
+
+	panel.QueryOptions
+}
+
+// NewMySQL creates new instance of raw MySQL query returning time series.
+func NewMySQL(datasourceName, rawSQL string) *SQL {
+	return newSQL(MySQL, datasourceName, rawSQL)
+}
+
+// NewPostgreSQL creates new instance of raw PostgreSQL query returning time series.
+func NewPostgreSQL(datasourceName, rawSQL string) *SQL {
+	return newSQL(PostgreSQL, datasourceName, rawSQL)
+}
+
+// NewMSSQL creates new instance of raw MSSQL query returning time series.
+func NewMSSQL(datasourceName, rawSQL string) *SQL {
+	return newSQL(MSSQL, datasourceName, rawSQL)
+}
+
+func newSQL(dialect SQLDialect, datasourceName, rawSQL string) *SQL {
+	return &SQL{
+		Format:       SQLTimeSeries,
+		RawQuery:     true,
+		RawSQL:       rawSQL,
+		Dialect:      dialect,
+		QueryOptions: newQueryOptions(datasourceName),
+	}
+}
+
+// SQLQueryPart is a part of select, where or group by clause of SQL query built in Grafana's query builder.
+type SQLQueryPart struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name,omitempty"` // macro name or operator
+	Params   []string `json:"params"`
+	Datatype string   `json:"datatype,omitempty"`
+}
+
+// NewSQLQueryPart creates query part of given type with given params.
+func NewSQLQueryPart(partType string, params ...string) SQLQueryPart {
+	if params == nil {
+		params = []string{}
+	}
+	return SQLQueryPart{Type: partType, Params: params}
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (p *SQLQueryPart) UnmarshalJSON(data []byte) error {
+	// Numeric params are stored as numbers sometimes
+	var jp struct {
+		Type     string              `json:"type"`
+		Name     string              `json:"name"`
+		Params   []field.ForceString `json:"params"`
+		Datatype string              `json:"datatype"`
+	}
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return err
+	}
+
+	p.Type = jp.Type
+	p.Name = jp.Name
+	p.Datatype = jp.Datatype
+	p.Params = make([]string, len(jp.Params))
+	for i, param := range jp.Params {
+		p.Params[i] = string(param)
+	}
+	return nil
+}
+
+// sqlMacros are numbers of arguments of macros expanded by Grafana's SQL datasources. Optional arguments are
+// counted in max.
+var sqlMacros = map[string]struct{ min, max int }{
+	"__time":                {1, 1},
+	"__timeEpoch":           {1, 1},
+	"__timeFilter":          {1, 1},
+	"__timeFrom":            {0, 0},
+	"__timeTo":              {0, 0},
+	"__timeGroup":           {2, 3},
+	"__timeGroupAlias":      {2, 3},
+	"__unixEpochFilter":     {1, 1},
+	"__unixEpochFrom":       {0, 0},
+	"__unixEpochTo":         {0, 0},
+	"__unixEpochNanoFilter": {1, 1},
+	"__unixEpochNanoFrom":   {0, 0},
+	"__unixEpochNanoTo":     {0, 0},
+	"__unixEpochGroup":      {2, 3},
+	"__unixEpochGroupAlias": {2, 3},
+}
+
+var sqlMacroRe = regexp.MustCompile(`\$(__\w+)\(([^)]*)\)`)
+
+// Validate checks that all macros of RawSQL are known and have valid arguments.
+func (q *SQL) Validate() error {
+	var errs validate.Errors
+	for _, m := range sqlMacroRe.FindAllStringSubmatch(q.RawSQL, -1) {
+		if _, err := parseSQLMacro(m[1], m[2]); err != nil {
+			errs.Add("rawSql", err)
+		}
+	}
+	return errs.Err()
+}
+
+// Expand returns RawSQL with macros expanded for given time range and interval the same way as Grafana's
+// datasource does. Variables must be interpolated beforehand, except for $__interval used as macro argument.
+func (q *SQL) Expand(from, to time.Time, step time.Duration) (string, error) {
+	switch q.Dialect {
+	case MySQL, PostgreSQL, MSSQL:
+	default:
+		return "", fmt.Errorf("unsupported SQL dialect %q", q.Dialect)
+	}
+
+	var err error
+	sql := sqlMacroRe.ReplaceAllStringFunc(q.RawSQL, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+
+		m := sqlMacroRe.FindStringSubmatch(ref)
+		var args []string
+		args, err = parseSQLMacro(m[1], m[2])
+		if err != nil {
+			return ref
+		}
+
+		var expanded string
+		expanded, err = q.expandMacro(m[1], args, from, to, step)
+		return expanded
+	})
+	if err != nil {
+		return "", err
+	}
+	return sql, nil
+}
+
+// parseSQLMacro validates macro with given name and returns its arguments.
+func parseSQLMacro(name, arguments string) ([]string, error) {
+	n, ok := sqlMacros[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown macro $%s", name)
+	}
+
+	var args []string
+	if strings.TrimSpace(arguments) != "" {
+		args = strings.Split(arguments, ",")
+		for i, arg := range args {
+			args[i] = strings.Trim(strings.TrimSpace(arg), `'"`)
+		}
+	}
+	if len(args) < n.min || len(args) > n.max {
+		return nil, fmt.Errorf("macro $%s needs %s", name, sqlMacroArgsCount(n.min, n.max))
+	}
+
+	if strings.Contains(name, "Group") {
+		// Intervals referencing variables are checked after interpolation
+		if !strings.ContainsAny(args[1], "$[") {
+			if _, err := interval.Parse(args[1]); err != nil {
+				return nil, fmt.Errorf("macro $%s has invalid interval %q", name, args[1])
+			}
+		}
+		if len(args) > 2 && !isSQLFill(args[2]) {
+			return nil, fmt.Errorf("macro $%s has invalid fill mode %q", name, args[2])
+		}
+	}
+	return args, nil
+}
+
+func sqlMacroArgsCount(min, max int) string {
+	switch {
+	case max == 0:
+		return "no arguments"
+	case min == max && min == 1:
+		return "1 argument"
+	case min == max:
+		return strconv.Itoa(min) + " arguments"
+	}
+	return fmt.Sprintf("%d to %d arguments", min, max)
+}
+
+func isSQLFill(s string) bool {
+	switch strings.ToLower(s) {
+	case "null", "previous", "none":
+		return true
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func (q *SQL) expandMacro(name string, args []string, from, to time.Time, step time.Duration) (string, error) {
+	var groupSeconds string
+	if strings.Contains(name, "Group") {
+		d := step
+		if args[1] != "$__interval" {
+			var err error
+			if d, err = interval.Parse(args[1]); err != nil {
+				return "", fmt.Errorf("macro $%s has invalid interval %q", name, args[1])
+			}
+		}
+		if d < time.Second {
+			return "", fmt.Errorf("macro $%s needs interval of at least 1s", name)
+		}
+		groupSeconds = strconv.FormatInt(int64(d/time.Second), 10)
+	}
+
+	var alias string
+	if strings.HasSuffix(name, "Alias") {
+		switch q.Dialect {
+		case MSSQL:
+			alias = " AS [time]"
+		default:
+			alias = ` AS "time"`
+		}
+	}
+
+	sec := func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }
+	nsec := func(t time.Time) string { return strconv.FormatInt(t.UnixNano(), 10) }
+
+	switch name {
+	case "__time":
+		switch q.Dialect {
+		case MySQL:
+			return "UNIX_TIMESTAMP(" + args[0] + ") as time_sec", nil
+		case MSSQL:
+			return args[0] + " AS time", nil
+		}
+		return args[0] + ` AS "time"`, nil
+	case "__timeEpoch":
+		switch q.Dialect {
+		case MySQL:
+			return "UNIX_TIMESTAMP(" + args[0] + ") as time_sec", nil
+		case MSSQL:
+			return "DATEDIFF(second, '1970-01-01', " + args[0] + ") AS time", nil
+		}
+		return "extract(epoch from " + args[0] + `) as "time"`, nil
+	case "__timeFilter":
+		return args[0] + " BETWEEN " + q.sqlTime(from) + " AND " + q.sqlTime(to), nil
+	case "__timeFrom":
+		return q.sqlTime(from), nil
+	case "__timeTo":
+		return q.sqlTime(to), nil
+	case "__timeGroup", "__timeGroupAlias":
+		switch q.Dialect {
+		case MySQL:
+			return "UNIX_TIMESTAMP(" + args[0] + ") DIV " + groupSeconds + " * " + groupSeconds + alias, nil
+		case MSSQL:
+			return "FLOOR(DATEDIFF(second, '1970-01-01', " + args[0] + ")/" + groupSeconds + ")*" + groupSeconds + alias, nil
+		}
+		return "floor(extract(epoch from " + args[0] + ")/" + groupSeconds + ")*" + groupSeconds + alias, nil
+	case "__unixEpochFilter":
+		return args[0] + " >= " + sec(from) + " AND " + args[0] + " <= " + sec(to), nil
+	case "__unixEpochFrom":
+		return sec(from), nil
+	case "__unixEpochTo":
+		return sec(to), nil
+	case "__unixEpochNanoFilter":
+		return args[0] + " >= " + nsec(from) + " AND " + args[0] + " <= " + nsec(to), nil
+	case "__unixEpochNanoFrom":
+		return nsec(from), nil
+	case "__unixEpochNanoTo":
+		return nsec(to), nil
+	case "__unixEpochGroup", "__unixEpochGroupAlias":
+		switch q.Dialect {
+		case MySQL:
+			return args[0] + " DIV " + groupSeconds + " * " + groupSeconds + alias, nil
+		case MSSQL:
+			return "FLOOR(" + args[0] + "/" + groupSeconds + ")*" + groupSeconds + alias, nil
+		}
+		return "floor(" + args[0] + "/" + groupSeconds + ")*" + groupSeconds + alias, nil
+	}
+	return "", fmt.Errorf("unknown macro $%s", name)
+}
+
+// sqlTime formats time as SQL literal of the dialect.
+func (q *SQL) sqlTime(t time.Time) string {
+	if q.Dialect == MySQL {
+		return "FROM_UNIXTIME(" + strconv.FormatInt(t.Unix(), 10) + ")"
+	}
+	return "'" + t.UTC().Format(time.RFC3339) + "'"
+}
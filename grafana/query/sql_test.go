@@ -0,0 +1,133 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kr/pretty"
+	"github.com/spoof/go-grafana/grafana/query"
+)
+
+func TestSQL_Validate(t *testing.T) {
+	ts := []struct {
+		sql   string
+		valid bool
+	}{
+		{"SELECT $__time(ts), value FROM metrics WHERE $__timeFilter(ts)", true},
+		{"SELECT $__timeGroup(ts, '5m', previous), avg(value) FROM metrics GROUP BY 1", true},
+		{"SELECT $__timeGroup(ts, $__interval, 0) FROM metrics", true},
+		{"SELECT $__unixEpochGroupAlias(ts, $step) FROM metrics WHERE ts > $__unixEpochFrom()", true},
+		{"SELECT * FROM metrics WHERE $__timeFilter()", false},
+		{"SELECT $__timeGroup(ts, 5 minutes) FROM metrics", false},
+		{"SELECT $__timeGroup(ts, 5m, last) FROM metrics", false},
+		{"SELECT * FROM metrics WHERE ts > $__timeFrom(ts)", false},
+		{"SELECT * FROM metrics WHERE $__timeRange(ts)", false},
+	}
+
+	for _, tt := range ts {
+		q := query.NewMySQL("MySQL", tt.sql)
+		if err := q.Validate(); (err == nil) != tt.valid {
+			t.Errorf("SQL.Validate(%q): expected valid %v, got error %v", tt.sql, tt.valid, err)
+		}
+	}
+}
+
+func TestSQL_Expand(t *testing.T) {
+	from := time.Date(2017, 7, 18, 11, 15, 52, 0, time.UTC)
+	to := time.Date(2017, 7, 18, 11, 25, 52, 0, time.UTC)
+	sql := "SELECT $__timeGroupAlias(ts, 5m), $__timeEpoch(ts), sum(v) FROM t " +
+		"WHERE $__timeFilter(ts) AND $__unixEpochFilter(epoch) GROUP BY $__unixEpochGroup(epoch, $__interval)"
+
+	ts := []struct {
+		query    *query.SQL
+		expected string
+	}{
+		{
+			query: query.NewMySQL("MySQL", sql),
+			expected: "SELECT UNIX_TIMESTAMP(ts) DIV 300 * 300 AS \"time\", UNIX_TIMESTAMP(ts) as time_sec, sum(v) FROM t " +
+				"WHERE ts BETWEEN FROM_UNIXTIME(1500376552) AND FROM_UNIXTIME(1500377152) " +
+				"AND epoch >= 1500376552 AND epoch <= 1500377152 GROUP BY epoch DIV 10 * 10",
+		},
+		{
+			query: query.NewPostgreSQL("PostgreSQL", sql),
+			expected: "SELECT floor(extract(epoch from ts)/300)*300 AS \"time\", extract(epoch from ts) as \"time\", sum(v) FROM t " +
+				"WHERE ts BETWEEN '2017-07-18T11:15:52Z' AND '2017-07-18T11:25:52Z' " +
+				"AND epoch >= 1500376552 AND epoch <= 1500377152 GROUP BY floor(epoch/10)*10",
+		},
+		{
+			query: query.NewMSSQL("MSSQL", sql),
+			expected: "SELECT FLOOR(DATEDIFF(second, '1970-01-01', ts)/300)*300 AS [time], " +
+				"DATEDIFF(second, '1970-01-01', ts) AS time, sum(v) FROM t " +
+				"WHERE ts BETWEEN '2017-07-18T11:15:52Z' AND '2017-07-18T11:25:52Z' " +
+				"AND epoch >= 1500376552 AND epoch <= 1500377152 GROUP BY FLOOR(epoch/10)*10",
+		},
+	}
+
+	for _, tt := range ts {
+		got, err := tt.query.Expand(from, to, 10*time.Second)
+		if err != nil {
+			t.Fatalf("SQL.Expand(%s) returned error %s", tt.query.Dialect, err)
+		}
+		if got != tt.expected {
+			t.Errorf("SQL.Expand(%s):\nexpected %s\ngot      %s", tt.query.Dialect, tt.expected, got)
+		}
+	}
+
+	var unknown query.SQL
+	unknown.RawSQL = sql
+	if _, err := unknown.Expand(from, to, time.Second); err == nil {
+		t.Errorf("SQL.Expand: expected error for unknown dialect")
+	}
+}
+
+func TestSQL_UnmarshalJSON(t *testing.T) {
+	data := []byte(`{
+		"format": "table",
+		"rawQuery": false,
+		"rawSql": "SELECT 1",
+		"table": "metrics",
+		"timeColumn": "ts",
+		"metricColumn": "none",
+		"select": [[{"type": "column", "params": ["value"]}, {"type": "percentile", "params": ["percentile_cont", 0.95]}]],
+		"where": [{"type": "macro", "name": "$__timeFilter", "params": []}],
+		"group": [{"type": "time", "params": ["$__interval", "none"]}]
+	}`)
+	var got query.SQL
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("SQL.UnmarshalJSON returned error %s", err)
+	}
+
+	where := query.NewSQLQueryPart(query.SQLMacro)
+	where.Name = "$__timeFilter"
+	expected := query.SQL{
+		Format:       query.SQLTable,
+		RawSQL:       "SELECT 1",
+		Table:        "metrics",
+		TimeColumn:   "ts",
+		MetricColumn: "none",
+		Select: [][]query.SQLQueryPart{{
+			query.NewSQLQueryPart(query.SQLColumn, "value"),
+			query.NewSQLQueryPart(query.SQLPercentile, "percentile_cont", "0.95"),
+		}},
+		Where: []query.SQLQueryPart{where},
+		Group: []query.SQLQueryPart{query.NewSQLQueryPart(query.SQLTime, "$__interval", "none")},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("SQL.UnmarshalJSON: %s", pretty.Diff(got, expected))
+	}
+}
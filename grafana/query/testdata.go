@@ -0,0 +1,86 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+)
+
+type testDataScenario string
+
+// Scenarios of TestData datasource. CSV metric values, manual entry and predictable pulse render the same data on
+// each refresh.
+const (
+	TestDataRandomWalk       testDataScenario = "random_walk"
+	TestDataCSVMetricValues  testDataScenario = "csv_metric_values"
+	TestDataManualEntry      testDataScenario = "manual_entry"
+	TestDataPredictablePulse testDataScenario = "predictable_pulse"
+	TestDataNoDataPoints     testDataScenario = "no_data_points"
+	TestDataLogs             testDataScenario = "logs"
+)
+
+// TestData is query specific options for TestData datasource which generates synthetic data.
+type TestData struct {
+	ScenarioID  testDataScenario `json:"scenarioId"`
+	StringInput string           `json:"stringInput,omitempty"` // ie. comma separated values of CSV metric values
+	SeriesCount uint             `json:"seriesCount,omitempty"`
+	Points      []TestDataPoint  `json:"points,omitempty"` // points of manual entry scenario
+	Alias       string           `json:"alias,omitempty"`
+
+	panel.QueryOptions
+}
+
+// NewTestData creates new instance of TestData query of given scenario.
+func NewTestData(datasourceName string, scenario testDataScenario) *TestData {
+	return &TestData{
+		ScenarioID:   scenario,
+		QueryOptions: newQueryOptions(datasourceName),
+	}
+}
+
+// AddPoint adds point to manual entry scenario.
+func (q *TestData) AddPoint(value float64, t time.Time) {
+	q.Points = append(q.Points, TestDataPoint{Value: value, Time: t})
+}
+
+// TestDataPoint is a point of manual entry scenario. It's stored as [value, unix time in milliseconds] pair.
+type TestDataPoint struct {
+	Value float64
+	Time  time.Time
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (p TestDataPoint) MarshalJSON() ([]byte, error) {
+	ms := p.Time.UnixNano() / int64(time.Millisecond)
+	return json.Marshal([]interface{}{p.Value, ms})
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (p *TestDataPoint) UnmarshalJSON(data []byte) error {
+	var pair []float64
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return err
+	}
+	if len(pair) != 2 {
+		return errors.New("point must be [value, time] pair")
+	}
+
+	p.Value = pair[0]
+	p.Time = time.Unix(0, int64(pair[1])*int64(time.Millisecond))
+	return nil
+}
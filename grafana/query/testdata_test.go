@@ -0,0 +1,59 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/spoof/go-grafana/grafana/query"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+)
+
+func TestTestData_MarshalJSON(t *testing.T) {
+	q := query.NewTestData("TestData", query.TestDataManualEntry)
+	q.Alias = "fixture"
+	q.AddPoint(1.5, time.Unix(1500376552, 0))
+	q.AddPoint(-2, time.Unix(1500376612, 0))
+
+	got, err := json.Marshal(q)
+	if err != nil {
+		t.Fatalf("TestData.MarshalJSON returned error %s", err)
+	}
+	expected := []byte(`{
+		"scenarioId": "manual_entry",
+		"points": [[1.5, 1500376552000], [-2, 1500376612000]],
+		"alias": "fixture"
+	}`)
+	if eq, err := jsontools.BytesEqual(expected, got); err != nil {
+		t.Fatalf("TestData.MarshalJSON returned error %s", err)
+	} else if !eq {
+		t.Errorf("TestData.MarshalJSON: got %s, want %s\n", got, expected)
+	}
+}
+
+func TestTestDataPoint_UnmarshalJSON(t *testing.T) {
+	var p query.TestDataPoint
+	if err := json.Unmarshal([]byte(`[42, 1500376552000]`), &p); err != nil {
+		t.Fatalf("TestDataPoint.UnmarshalJSON returned error %s", err)
+	}
+	if p.Value != 42 || !p.Time.Equal(time.Unix(1500376552, 0)) {
+		t.Errorf("TestDataPoint.UnmarshalJSON: got %v at %v", p.Value, p.Time)
+	}
+
+	if err := json.Unmarshal([]byte(`[42]`), &p); err == nil {
+		t.Errorf("TestDataPoint.UnmarshalJSON: expected error for invalid point")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"regexp"
+
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// Validate checks the dashboard before it's saved: its options, rows, panels, queries and variables. All found
+// violations are returned at once as validate.Errors, every error has JSON-like path of invalid field, ie.
+// "rows[2].panels[0].span".
+func (d *Dashboard) Validate() error {
+	var errs validate.Errors
+	if d.Title == "" {
+		errs.Addf("title", "should not be empty")
+	}
+	if d.FiscalYearStartMonth > 11 {
+		errs.Addf("fiscalYearStartMonth", "should be between 0 and 11, got %d", d.FiscalYearStartMonth)
+	}
+	if d.GraphTooltip > 2 {
+		errs.Addf("graphTooltip", "should be between 0 and 2, got %d", d.GraphTooltip)
+	}
+	switch d.WeekStart {
+	case WeekStartDefault, WeekStartMonday, WeekStartSaturday, WeekStartSunday:
+	default:
+		errs.Addf("weekStart", "unknown day %q", d.WeekStart)
+	}
+	if d.Time != nil {
+		errs.Add("time", d.Time.Validate())
+	}
+
+	if len(d.Rows) > 0 && len(d.Panels) > 0 {
+		errs.Addf("rows", "dashboard should have either rows or panels")
+	}
+	for i, r := range d.Rows {
+		errs.Add(validate.Index("rows", i), r.Validate())
+	}
+	errs.Add("", validatePanels(d.Panels))
+
+	errs.Add("templating", d.Variables.Validate())
+	return errs.Err()
+}
+
+// Validate checks the row and its panels.
+func (r *Row) Validate() error {
+	var errs validate.Errors
+	switch r.TitleSize {
+	case "", "h1", "h2", "h3", "h4", "h5", "h6":
+	default:
+		errs.Addf("titleSize", "should be h1-h6, got %q", r.TitleSize)
+	}
+	errs.Add("", validatePanels(r.Panels))
+	return errs.Err()
+}
+
+// validator is implemented by panels and queries which are able to validate their options.
+type validator interface {
+	Validate() error
+}
+
+// validatePanels checks given panels, their queries and panels of collapsed rows. Only general options of panels
+// which don't implement validator interface are checked.
+func validatePanels(panels []Panel) error {
+	var errs validate.Errors
+	for i, p := range panels {
+		path := validate.Index("panels", i)
+		if v, ok := p.(validator); ok {
+			errs.Add(path, v.Validate())
+		} else {
+			errs.Add(path, p.GeneralOptions().Validate())
+		}
+
+		if rp, ok := p.(*RowPanel); ok {
+			errs.Add(path, validatePanels(rp.Panels))
+		}
+
+		if qp, ok := p.(QueryablePanel); ok {
+			for j, q := range *qp.Queries() {
+				if v, ok := q.(validator); ok {
+					errs.Add(validate.Join(path, validate.Index("targets", j)), v.Validate())
+				}
+			}
+		}
+	}
+	return errs.Err()
+}
+
+var variableNameRe = regexp.MustCompile(`^\w+$`)
+
+// Validate checks names of variables and operators of ad hoc filters. Errors are reported with paths of variables
+// in dashboard's "templating" object, ie. "list[0].name".
+func (v Variables) Validate() error {
+	var errs validate.Errors
+	names := make(map[string]bool, len(v))
+	for i, variable := range v {
+		path := validate.Index("list", i)
+		name := variable.commonOptions().Name
+		switch {
+		case name == "":
+			errs.Addf(validate.Join(path, "name"), "should not be empty")
+		case !variableNameRe.MatchString(name):
+			errs.Addf(validate.Join(path, "name"), "should contain only letters, digits and underscores, got %q", name)
+		case name == "__interval" || name == "__interval_ms" || name == "timeFilter":
+			errs.Addf(validate.Join(path, "name"), "%q is a built-in variable", name)
+		case names[name]:
+			errs.Addf(validate.Join(path, "name"), "duplicate variable %q", name)
+		}
+		names[name] = true
+
+		if adhoc, ok := variable.(*AdHocVariable); ok {
+			for j, f := range adhoc.Filters {
+				switch f.Operator {
+				case "=", "!=", "<", ">", "=~", "!~":
+				default:
+					errs.Addf(validate.Join(path, validate.Index("filters", j), "operator"), "unknown operator %q",
+						f.Operator)
+				}
+			}
+		}
+	}
+	return errs.Err()
+}
@@ -0,0 +1,87 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grafana
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana/panel"
+	panelQuery "github.com/spoof/go-grafana/grafana/query"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+func TestDashboard_Validate(t *testing.T) {
+	d := NewDashboard("Valid")
+	row := NewRow()
+	row.TitleSize = "h6"
+	row.Panels = []Panel{panel.NewGraph(), panel.NewSinglestat()}
+	d.Rows = []*Row{row}
+	d.Variables = Variables{NewQueryVar("host"), NewConstantVariable("env")}
+	if err := d.Validate(); err != nil {
+		t.Errorf("Dashboard.Validate returned error %s", err)
+	}
+
+	graph := panel.NewGraph()
+	graph.GeneralOptions().Span = 14
+	sql := panelQuery.NewMySQL("", "SELECT $__timeGroup(time_sec) FROM t")
+	*graph.Queries() = []panel.Query{panelQuery.NewPrometheus(""), sql}
+	singlestat := panel.NewSinglestat()
+	singlestat.Colors = []string{"green", "red"}
+
+	d = NewDashboard("")
+	d.Time = NewTimeRange("now-1x", "now")
+	d.Rows = []*Row{
+		NewRow(),
+		{TitleSize: "h7", Panels: []Panel{graph, panel.NewText(panel.TextPanelHTMLMode), singlestat}},
+	}
+	d.Variables = Variables{NewQueryVar("host"), NewTextboxVariable("host", ""), NewConstantVariable("app-name")}
+
+	err := d.Validate()
+	var paths []string
+	for _, e := range err.(validate.Errors) {
+		paths = append(paths, e.Path)
+	}
+	expected := []string{
+		"title",
+		"time",
+		"rows[1].titleSize",
+		"rows[1].panels[0].span",
+		"rows[1].panels[0].targets[1].rawSql",
+		"rows[1].panels[2].colors",
+		"templating.list[1].name",
+		"templating.list[2].name",
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Dashboard.Validate: expected errors of %v, got %v", expected, paths)
+	}
+}
+
+func TestDashboard_Validate_Grid(t *testing.T) {
+	graph := panel.NewGraph()
+	graph.GeneralOptions().GridPos = &panel.GridPos{W: 25, H: 8}
+	collapsed := NewRowPanel("Collapsed")
+	collapsed.Collapsed = true
+	collapsed.Panels = []Panel{panel.NewText(panel.TextPanelMarkdownMode), graph}
+
+	d := NewDashboard("Grid")
+	d.SchemaVersion = gridLayoutSchemaVersion
+	d.Panels = []Panel{NewRowPanel("Row"), collapsed}
+
+	err := d.Validate()
+	expected := "panels[1].panels[1].gridPos.w: should be between 1 and 24, got 25"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Dashboard.Validate: expected error %q, got %v", expected, err)
+	}
+}
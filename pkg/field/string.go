@@ -15,7 +15,7 @@ package field
 
 import (
 	"encoding/json"
-	"fmt"
+	"strconv"
 )
 
 // ForceString is type that forces conversion to string
@@ -30,7 +30,7 @@ func (s *ForceString) UnmarshalJSON(data []byte) error {
 
 	switch v := val.(type) {
 	case float64:
-		*s = ForceString(fmt.Sprintf("%d", int(v)))
+		*s = ForceString(strconv.FormatFloat(v, 'f', -1, 64))
 	case string:
 		*s = ForceString(v)
 	default:
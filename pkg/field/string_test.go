@@ -29,6 +29,7 @@ func TestForceString_UnmarshalJSON(t *testing.T) {
 		{got: `{"height": ""}`, expected: ""},
 		{got: `{"height": null}`, expected: ""},
 		{got: `{"height": 200}`, expected: "200"},
+		{got: `{"height": 99.9}`, expected: "99.9"},
 		{got: `{"height": "200px"}`, expected: "200px"},
 	}
 
@@ -0,0 +1,98 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Strings is a list of strings represented in JSON either by a single string or by an array of strings.
+type Strings struct {
+	Values []string
+	IsList bool // marshal as an array even if there is only one value
+}
+
+// NewStrings creates Strings with given values. Single value is represented by a string, others by an array.
+func NewStrings(values ...string) Strings {
+	return Strings{
+		Values: values,
+		IsList: len(values) != 1,
+	}
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (s Strings) MarshalJSON() ([]byte, error) {
+	if s.IsList {
+		values := s.Values
+		if values == nil {
+			values = []string{}
+		}
+		return json.Marshal(values)
+	}
+
+	var value string
+	if len(s.Values) > 0 {
+		value = s.Values[0]
+	}
+	return json.Marshal(value)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface. Numbers and booleans are converted into strings.
+func (s *Strings) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return err
+	}
+
+	switch v := val.(type) {
+	case nil:
+		*s = Strings{}
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			str, err := scalarString(item)
+			if err != nil {
+				return err
+			}
+			values[i] = str
+		}
+		*s = Strings{Values: values, IsList: true}
+	default:
+		str, err := scalarString(v)
+		if err != nil {
+			return err
+		}
+		*s = Strings{Values: []string{str}}
+	}
+
+	return nil
+}
+
+// scalarString converts JSON string, number or boolean into string.
+func scalarString(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("expected string, number or boolean, got %T", v)
+}
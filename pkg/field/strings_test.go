@@ -0,0 +1,82 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package field
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStrings_UnmarshalJSON(t *testing.T) {
+	tt := []struct {
+		got      string
+		expected Strings
+	}{
+		{got: `{"value": "a"}`, expected: Strings{Values: []string{"a"}}},
+		{got: `{"value": ["a"]}`, expected: Strings{Values: []string{"a"}, IsList: true}},
+		{got: `{"value": ["a", "b"]}`, expected: Strings{Values: []string{"a", "b"}, IsList: true}},
+		{got: `{"value": null}`, expected: Strings{}},
+		{got: `{"value": [1, 2.5, true]}`, expected: Strings{Values: []string{"1", "2.5", "true"}, IsList: true}},
+		{got: `{"value": 10}`, expected: Strings{Values: []string{"10"}}},
+	}
+
+	for _, ts := range tt {
+		data := struct {
+			Value Strings `json:"value"`
+		}{}
+
+		if err := json.Unmarshal([]byte(ts.got), &data); err != nil {
+			t.Fatalf("Strings.UnmarshalJSON returned error %s", err)
+		}
+
+		if !reflect.DeepEqual(ts.expected, data.Value) {
+			t.Errorf("Strings.UnmarshalJSON:\nexpected: %#v\ngot: %#v", ts.expected, data.Value)
+		}
+	}
+}
+
+func TestStrings_UnmarshalJSON_Error(t *testing.T) {
+	for _, data := range []string{`{"value": [{"a": 1}]}`, `{"value": [null]}`, `{"value": {}}`} {
+		var v struct {
+			Value Strings `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(data), &v); err == nil {
+			t.Errorf("Strings.UnmarshalJSON(%s): expected error, got %#v", data, v.Value)
+		}
+	}
+}
+
+func TestStrings_MarshalJSON(t *testing.T) {
+	tt := []struct {
+		value    Strings
+		expected string
+	}{
+		{value: NewStrings("a"), expected: `"a"`},
+		{value: NewStrings("a", "b"), expected: `["a","b"]`},
+		{value: NewStrings(), expected: `[]`},
+		{value: Strings{Values: []string{"a"}, IsList: true}, expected: `["a"]`},
+		{value: Strings{}, expected: `""`},
+	}
+
+	for _, ts := range tt {
+		got, err := json.Marshal(ts.value)
+		if err != nil {
+			t.Fatalf("Strings.MarshalJSON returned error %s", err)
+		}
+		if string(got) != ts.expected {
+			t.Errorf("Strings.MarshalJSON: got %s, want %s", got, ts.expected)
+		}
+	}
+}
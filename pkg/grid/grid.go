@@ -0,0 +1,198 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package grid contains helpers to convert legacy rows/span layout of Grafana dashboards into grid positions used
+// since dashboard schema version 16.
+package grid
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Grafana's grid parameters.
+const (
+	Columns       = 24
+	CellHeight    = 30
+	CellVMargin   = 8
+	MinHeight     = CellHeight * 3
+	DefaultSpan   = 4
+	DefaultHeight = 250
+	spanFactor    = Columns / 12
+)
+
+// Width converts panel's span (1-12) into width in grid units.
+func Width(span uint) uint {
+	if span == 0 {
+		span = DefaultSpan
+	}
+	w := span * spanFactor
+	if w > Columns {
+		w = Columns
+	}
+	return w
+}
+
+// Height converts panel's or row's height in pixels (ie. "250px" or "250") into height in grid units. Empty or
+// invalid height is treated as DefaultHeight.
+func Height(height string) uint {
+	px, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(height), "px"))
+	if err != nil || px <= 0 {
+		px = DefaultHeight
+	}
+	if px < MinHeight {
+		px = MinHeight
+	}
+	return uint((px + CellHeight + CellVMargin - 1) / (CellHeight + CellVMargin))
+}
+
+// RowArea places panels of a single legacy row on the grid. It mimics Grafana's own migration, so converted
+// dashboards look the same as if they were upgraded by Grafana.
+type RowArea struct {
+	area   [Columns]uint
+	y      uint
+	height uint
+}
+
+// NewRowArea creates RowArea of given height which starts at y.
+func NewRowArea(height, y uint) *RowArea {
+	return &RowArea{height: height, y: y}
+}
+
+// Y returns current vertical position of the area. It grows when panels don't fit and wrap to the next line.
+func (a *RowArea) Y() uint {
+	return a.y
+}
+
+// Place finds a place for panel of given width and height and returns its absolute grid position.
+func (a *RowArea) Place(w, h uint) (x, y uint) {
+	if w > Columns {
+		w = Columns
+	}
+
+	x, y, ok := a.find(w)
+	if !ok {
+		// Wrap to the next line
+		a.y += a.height
+		a.area = [Columns]uint{}
+		x, y, _ = a.find(w)
+	}
+
+	for i := x; i < x+w; i++ {
+		if y+h-a.y > a.area[i] {
+			a.area[i] = y + h - a.y
+		}
+	}
+	return x, y
+}
+
+// find searches free space from the right side of the area.
+func (a *RowArea) find(w uint) (x, y uint, ok bool) {
+	start, end := -1, -1
+	for i := Columns - 1; i >= 0; i-- {
+		if a.area[i] >= a.height {
+			break
+		}
+		if end == -1 {
+			end = i
+			continue
+		}
+		if i < Columns-1 && a.area[i] <= a.area[i+1] {
+			start = i
+		} else {
+			break
+		}
+	}
+
+	if start == -1 || end == -1 || uint(end-start) < w-1 {
+		return 0, a.y, false
+	}
+
+	var max uint
+	for _, v := range a.area[start:] {
+		if v > max {
+			max = v
+		}
+	}
+	return uint(start), a.y + max, true
+}
+
+// Row is a row of legacy layout.
+type Row struct {
+	Height    string // ie. "250px"
+	Collapsed bool
+	ShowTitle bool
+	Repeat    string // variable the row is repeated for
+	Panels    []Panel
+}
+
+// Panel is a panel of legacy row.
+type Panel struct {
+	Span   uint   // 1-12, DefaultSpan if zero
+	Height string // overrides height of the row if not empty
+}
+
+// Pos is a position and size of panel on the grid.
+type Pos struct {
+	X, Y, W, H uint
+}
+
+// Placement is a panel of grid layout converted from legacy row.
+type Placement struct {
+	Row       int  // index of the row
+	Panel     int  // index of the panel in the row, -1 for panel of the row itself
+	Collapsed bool // panel is placed inside panel of collapsed row
+	Pos       Pos
+}
+
+// Convert converts legacy rows into grid layout the same way as Grafana does it. Placements are returned in the
+// order of panels of the grid layout. Rows get their own panels if any of them has visible title, is collapsed or
+// repeated; panels of collapsed rows are placed inside panels of their rows then.
+func Convert(rows []Row) []Placement {
+	showRows := false
+	for _, r := range rows {
+		if r.Collapsed || r.ShowTitle || r.Repeat != "" {
+			showRows = true
+			break
+		}
+	}
+
+	var placements []Placement
+	var y uint
+	for i, r := range rows {
+		rowHeight := Height(r.Height)
+		collapsed := showRows && r.Collapsed
+		if showRows {
+			placements = append(placements, Placement{Row: i, Panel: -1, Pos: Pos{X: 0, Y: y, W: Columns, H: 1}})
+			y++
+		}
+
+		area := NewRowArea(rowHeight, y)
+		for j, p := range r.Panels {
+			w := Width(p.Span)
+			h := rowHeight
+			if p.Height != "" {
+				h = Height(p.Height)
+			}
+
+			px, py := area.Place(w, h)
+			y = area.Y()
+			placements = append(placements, Placement{Row: i, Panel: j, Collapsed: collapsed, Pos: Pos{X: px, Y: py, W: w, H: h}})
+		}
+
+		if !collapsed {
+			y += rowHeight
+		}
+	}
+	return placements
+}
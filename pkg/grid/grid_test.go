@@ -0,0 +1,108 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grid_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spoof/go-grafana/pkg/grid"
+)
+
+func TestHeight(t *testing.T) {
+	ts := []struct {
+		height   string
+		expected uint
+	}{
+		{"", 7},
+		{"250px", 7},
+		{"250", 7},
+		{"50px", 3},
+		{"500", 14},
+		{"invalid", 7},
+	}
+
+	for _, tt := range ts {
+		if got := grid.Height(tt.height); got != tt.expected {
+			t.Errorf("Height(%q): expected %d, got %d", tt.height, tt.expected, got)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	ts := []struct {
+		span     uint
+		expected uint
+	}{
+		{0, 8},
+		{1, 2},
+		{12, 24},
+		{20, 24},
+	}
+
+	for _, tt := range ts {
+		if got := grid.Width(tt.span); got != tt.expected {
+			t.Errorf("Width(%d): expected %d, got %d", tt.span, tt.expected, got)
+		}
+	}
+}
+
+func TestRowArea_Place(t *testing.T) {
+	type pos struct{ x, y uint }
+	ts := []struct {
+		sizes    [][2]uint
+		expected []pos
+	}{
+		{
+			sizes:    [][2]uint{{12, 7}, {12, 7}, {24, 7}},
+			expected: []pos{{0, 10}, {12, 10}, {0, 17}},
+		},
+		{
+			// Short panels are stacked in the free space of the row
+			sizes:    [][2]uint{{12, 7}, {12, 3}, {12, 3}},
+			expected: []pos{{0, 10}, {12, 10}, {12, 13}},
+		},
+	}
+
+	for _, tt := range ts {
+		area := grid.NewRowArea(7, 10)
+		for i, s := range tt.sizes {
+			x, y := area.Place(s[0], s[1])
+			if got := (pos{x, y}); got != tt.expected[i] {
+				t.Errorf("RowArea.Place(%v) #%d: expected %v, got %v", tt.sizes, i, tt.expected[i], got)
+			}
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	rows := []grid.Row{
+		{Panels: []grid.Panel{{Span: 6}, {Span: 6, Height: "100px"}}},
+		{ShowTitle: true, Collapsed: true, Height: "100px", Panels: []grid.Panel{{Span: 12}}},
+		{Panels: []grid.Panel{{}}},
+	}
+	expected := []grid.Placement{
+		{Row: 0, Panel: -1, Pos: grid.Pos{X: 0, Y: 0, W: 24, H: 1}},
+		{Row: 0, Panel: 0, Pos: grid.Pos{X: 0, Y: 1, W: 12, H: 7}},
+		{Row: 0, Panel: 1, Pos: grid.Pos{X: 12, Y: 1, W: 12, H: 3}},
+		{Row: 1, Panel: -1, Pos: grid.Pos{X: 0, Y: 8, W: 24, H: 1}},
+		{Row: 1, Panel: 0, Collapsed: true, Pos: grid.Pos{X: 0, Y: 9, W: 24, H: 3}},
+		{Row: 2, Panel: -1, Pos: grid.Pos{X: 0, Y: 9, W: 24, H: 1}},
+		{Row: 2, Panel: 0, Pos: grid.Pos{X: 0, Y: 10, W: 8, H: 7}},
+	}
+
+	if got := grid.Convert(rows); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Convert: expected %+v, got %+v", expected, got)
+	}
+}
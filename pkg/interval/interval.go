@@ -0,0 +1,46 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package interval parses Grafana's intervals like "10s" or "1d" used by variables, queries and macros.
+package interval
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var intervalRe = regexp.MustCompile(`^(\d+)(ms|s|m|h|d|w|y)$`)
+
+var units = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+// Parse parses interval like "10s" or "1d". Surrounding spaces are ignored.
+func Parse(s string) (time.Duration, error) {
+	m := intervalRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid interval %q", s)
+	}
+
+	n, _ := strconv.ParseInt(m[1], 10, 64)
+	return time.Duration(n) * units[m[2]], nil
+}
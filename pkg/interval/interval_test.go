@@ -0,0 +1,48 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package interval_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spoof/go-grafana/pkg/interval"
+)
+
+func TestParse(t *testing.T) {
+	ts := []struct {
+		s        string
+		expected time.Duration
+	}{
+		{"100ms", 100 * time.Millisecond},
+		{"10s", 10 * time.Second},
+		{" 5m ", 5 * time.Minute},
+		{"1d", 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+	}
+	for _, tt := range ts {
+		got, err := interval.Parse(tt.s)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error %s", tt.s, err)
+		} else if got != tt.expected {
+			t.Errorf("Parse(%q): expected %s, got %s", tt.s, tt.expected, got)
+		}
+	}
+
+	for _, s := range []string{"", "10", "1.5s", "-1s", "1M"} {
+		if _, err := interval.Parse(s); err == nil {
+			t.Errorf("Parse(%q): expected error", s)
+		}
+	}
+}
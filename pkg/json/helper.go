@@ -29,3 +29,75 @@ func BytesEqual(a, b []byte) (bool, error) {
 	}
 	return reflect.DeepEqual(j2, j), nil
 }
+
+// MergeObjects marshals given values into JSON objects and merges their fields into single object. Fields of latter
+// values override fields of former ones. Nil values are skipped.
+func MergeObjects(values ...interface{}) ([]byte, error) {
+	merged := make(map[string]gojson.RawMessage)
+	for _, v := range values {
+		if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+			continue
+		}
+
+		data, err := gojson.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		var fields map[string]gojson.RawMessage
+		if err := gojson.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		for k, f := range fields {
+			merged[k] = f
+		}
+	}
+	return gojson.Marshal(merged)
+}
+
+// SetFields sets given fields of JSON object keeping its other fields as is. Fields with nil values are removed.
+func SetFields(data []byte, fields map[string]interface{}) ([]byte, error) {
+	var object map[string]gojson.RawMessage
+	if err := gojson.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		if v == nil {
+			delete(object, k)
+			continue
+		}
+
+		value, err := gojson.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		object[k] = value
+	}
+	return gojson.Marshal(object)
+}
+
+// ExtraFields returns fields of JSON object which are missing in JSON of given value, ie. fields unknown to the type
+// the object was decoded into. Merge them with the value by MergeObjects to marshal the object without losses. Nil
+// is returned if there are no extra fields.
+func ExtraFields(data []byte, v interface{}) (map[string]gojson.RawMessage, error) {
+	var object map[string]gojson.RawMessage
+	if err := gojson.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	known, err := gojson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var knownFields map[string]gojson.RawMessage
+	if err := gojson.Unmarshal(known, &knownFields); err != nil {
+		return nil, err
+	}
+
+	for k := range knownFields {
+		delete(object, k)
+	}
+	if len(object) == 0 {
+		return nil, nil
+	}
+	return object, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package json
+
+import "testing"
+
+func TestMergeObjects(t *testing.T) {
+	a := struct {
+		A string `json:"a"`
+		B string `json:"b"`
+	}{"a", "b"}
+	b := &struct {
+		B string `json:"b"`
+		C int    `json:"c"`
+	}{"overridden", 1}
+	var c *struct{}
+
+	got, err := MergeObjects(a, b, c, nil)
+	if err != nil {
+		t.Fatalf("MergeObjects returned error %s", err)
+	}
+
+	expected := []byte(`{"a": "a", "b": "overridden", "c": 1}`)
+	if eq, err := BytesEqual(expected, got); err != nil {
+		t.Fatalf("MergeObjects returned error %s", err)
+	} else if !eq {
+		t.Errorf("MergeObjects: got %s, want %s\n", got, expected)
+	}
+}
+
+func TestSetFields(t *testing.T) {
+	data := []byte(`{"id": 1, "title": "old", "panels": [{"type": "worldmap-panel"}]}`)
+
+	got, err := SetFields(data, map[string]interface{}{"id": nil, "title": "new", "uid": "abc"})
+	if err != nil {
+		t.Fatalf("SetFields returned error %s", err)
+	}
+
+	expected := []byte(`{"title": "new", "uid": "abc", "panels": [{"type": "worldmap-panel"}]}`)
+	if eq, err := BytesEqual(expected, got); err != nil {
+		t.Fatalf("SetFields returned error %s", err)
+	} else if !eq {
+		t.Errorf("SetFields: got %s, want %s\n", got, expected)
+	}
+
+	if _, err := SetFields([]byte(`[]`), nil); err == nil {
+		t.Errorf("SetFields: expected error for non-object JSON")
+	}
+}
+
+func TestExtraFields(t *testing.T) {
+	data := []byte(`{"hidden": true, "nowDelay": "", "collapse": false, "status": "Stable"}`)
+	v := struct {
+		Hidden   bool   `json:"hidden"`
+		NowDelay string `json:"nowDelay,omitempty"`
+	}{Hidden: true}
+
+	extra, err := ExtraFields(data, v)
+	if err != nil {
+		t.Fatalf("ExtraFields returned error %s", err)
+	}
+	got, err := MergeObjects(extra, v)
+	if err != nil {
+		t.Fatalf("MergeObjects returned error %s", err)
+	}
+	if eq, err := BytesEqual(data, got); err != nil {
+		t.Fatalf("ExtraFields returned error %s", err)
+	} else if !eq {
+		t.Errorf("ExtraFields: merged %s, want %s\n", got, data)
+	}
+
+	if extra, err := ExtraFields([]byte(`{"hidden": false}`), v); err != nil || extra != nil {
+		t.Errorf("ExtraFields: expected no extra fields, got %s (%v)", extra, err)
+	}
+}
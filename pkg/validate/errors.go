@@ -0,0 +1,95 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package validate contains helpers to collect validation errors of nested structures. Every error is reported with
+// JSON-pointer-like path of the invalid field, ie. "rows[2].panels[0].span".
+package validate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Error is a violation of a single field.
+type Error struct {
+	Path string // ie. "rows[2].panels[0].span". Empty for the validated value itself.
+	Err  error
+}
+
+// Error implements error interface
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Errors is a list of all violations found during validation.
+type Errors []*Error
+
+// Error implements error interface
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Add adds error of field with given path. Nested errors returned by other validations are added with their paths
+// prefixed by the path. Nil error is ignored.
+func (e *Errors) Add(path string, err error) {
+	switch err := err.(type) {
+	case nil:
+	case Errors:
+		for _, nested := range err {
+			e.Add(Join(path, nested.Path), nested.Err)
+		}
+	case *Error:
+		e.Add(Join(path, err.Path), err.Err)
+	default:
+		*e = append(*e, &Error{Path: path, Err: err})
+	}
+}
+
+// Addf adds error of field with given path formatted according to format specifier.
+func (e *Errors) Addf(path, format string, a ...interface{}) {
+	e.Add(path, fmt.Errorf(format, a...))
+}
+
+// Err returns the errors as error or nil if there are no errors.
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Join joins given paths, ie. "rows[2]", "panels[0]" and "span" are joined into "rows[2].panels[0].span". Paths
+// may start with index, ie. "[0].span".
+func Join(paths ...string) string {
+	var joined string
+	for _, p := range paths {
+		if joined != "" && p != "" && !strings.HasPrefix(p, "[") {
+			joined += "."
+		}
+		joined += p
+	}
+	return joined
+}
+
+// Index returns path of element of the list with given path, ie. "panels[0]".
+func Index(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+func TestErrors_Add(t *testing.T) {
+	var panelErrs validate.Errors
+	panelErrs.Add("span", errors.New("should be 1-12"))
+	panelErrs.Add("title", nil)
+
+	var errs validate.Errors
+	errs.Add(validate.Index("rows", 2), validate.Errors{{Path: validate.Index("panels", 0), Err: panelErrs}}.Err())
+	errs.Add(validate.Join(validate.Index("rows", 2), "panels[1]"), panelErrs.Err())
+	errs.Addf("time", "invalid from time: %s", "now+")
+	errs.Add("templating", &validate.Error{Path: "list[0].name", Err: errors.New("empty name")})
+
+	expected := "rows[2].panels[0].span: should be 1-12; rows[2].panels[1].span: should be 1-12; " +
+		"time: invalid from time: now+; templating.list[0].name: empty name"
+	if errs.Error() != expected {
+		t.Errorf("Errors.Error: expected %q, got %q", expected, errs.Error())
+	}
+}
+
+func TestErrors_Err(t *testing.T) {
+	var errs validate.Errors
+	if errs.Err() != nil {
+		t.Errorf("Errors.Err: expected nil for empty errors, got %v", errs.Err())
+	}
+
+	errs.Add("", errors.New("invalid"))
+	if errs.Err() == nil || errs.Err().Error() != "invalid" {
+		t.Errorf("Errors.Err: expected \"invalid\", got %v", errs.Err())
+	}
+}
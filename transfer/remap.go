@@ -0,0 +1,221 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transfer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/grafana/panel"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// Remapper rewrites datasources of dashboards of source Grafana to datasources of target Grafana.
+type Remapper struct {
+	source     *grafana.DatasourceRegistry
+	target     map[string]*grafana.Datasource
+	types      map[string]bool // types of target datasources
+	hasDefault bool            // target has default datasource
+	mapping    map[string]string
+}
+
+// NewRemapper creates Remapper of given source and target datasources. Mapping maps names of source datasources to
+// names of target datasources, datasources missing in the mapping keep their names.
+func NewRemapper(source, target []*grafana.Datasource, mapping map[string]string) *Remapper {
+	r := &Remapper{
+		source:  grafana.NewDatasourceRegistry(source...),
+		target:  make(map[string]*grafana.Datasource, len(target)),
+		types:   make(map[string]bool),
+		mapping: mapping,
+	}
+	for _, d := range target {
+		r.target[d.Name] = d
+		r.types[string(d.Type)] = true
+		r.hasDefault = r.hasDefault || d.IsDefault
+	}
+	return r
+}
+
+// Remap returns JSON of the dashboard with rewritten datasources of panels, queries, query and ad hoc variables,
+// annotations and selection of datasource variables. References to the default datasource and to datasource
+// variables are kept, the target should have the default datasource if it's used. Regex of datasource variables is
+// not changed. Other fields are kept as is, including panels of unregistered types and uid of the dashboard.
+//
+// All datasources missing on the target are returned as validate.Errors with paths of their references, ie.
+// "panels[0].targets[1].datasource".
+func (r *Remapper) Remap(data []byte) ([]byte, error) {
+	var errs validate.Errors
+	data, err := r.remapDatasourceVariables(data)
+	if _, ok := err.(validate.Errors); err != nil && !ok {
+		return nil, err
+	}
+	errs.Add("", err)
+	remapped, err := grafana.WalkQueryDatasources(data, func(path string, ref panel.DatasourceRef) (panel.DatasourceRef, error) {
+		return r.remapRef(ref)
+	})
+	errs.Add("", err)
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return remapped, nil
+}
+
+// remapRef returns reference to target datasource of given reference to source datasource.
+func (r *Remapper) remapRef(ref panel.DatasourceRef) (panel.DatasourceRef, error) {
+	if ref.IsDefault() {
+		if !r.hasDefault {
+			return ref, errors.New("default datasource is missing on target")
+		}
+		return ref, nil
+	}
+	if _, ok := ref.Variable(); ok || ref.IsMixed() || ref.IsDashboard() || ref.Equal(panel.GrafanaDatasourceRef()) {
+		return ref, nil
+	}
+
+	name := ref.Name
+	if resolved, ok := r.source.Resolve(ref); ok {
+		name = resolved.Name
+	} else if name == "" {
+		name = ref.UID
+	}
+	d, err := r.targetDatasource(name)
+	if err != nil {
+		return ref, err
+	}
+	return panel.DatasourceRef{Name: d.Name, UID: d.UID, Type: string(d.Type)}, nil
+}
+
+// remapDatasourceVariables rewrites selected datasources of datasource variables of dashboard's JSON.
+func (r *Remapper) remapDatasourceVariables(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var root map[string]interface{}
+	if err := dec.Decode(&root); err != nil {
+		return data, err
+	}
+
+	var errs validate.Errors
+	templating, _ := root["templating"].(map[string]interface{})
+	variables, _ := templating["list"].([]interface{})
+	for i, v := range variables {
+		if v, ok := v.(map[string]interface{}); ok && v["type"] == "datasource" {
+			errs.Add(validate.Join("templating", validate.Index("list", i)), r.remapDatasourceVariable(v))
+		}
+	}
+	if err := errs.Err(); err != nil {
+		return data, err
+	}
+	return json.Marshal(root)
+}
+
+// remapDatasourceVariable rewrites selected datasources of decoded variable. Type of datasources listed by the
+// variable is changed to type of the selected datasource.
+func (r *Remapper) remapDatasourceVariable(v map[string]interface{}) error {
+	var errs validate.Errors
+	query, _ := v["query"].(string)
+	if current, ok := v["current"].(map[string]interface{}); ok {
+		values, valuesList := stringValues(current["value"])
+		texts, textsList := stringValues(current["text"])
+		for i, value := range values {
+			if value == "" || value == "default" || value == grafana.AllVariableValue {
+				continue
+			}
+
+			resolved, ok := r.source.Resolve(panel.DatasourceRef{Name: value, UID: value})
+			if !ok {
+				resolved.Name = value
+			}
+			d, err := r.targetDatasource(resolved.Name)
+			if err != nil {
+				errs.Add("current", err)
+				continue
+			}
+
+			mapped := d.Name
+			if resolved.UID == value && resolved.Name != value {
+				mapped = d.UID
+			}
+			values[i] = mapped
+			if i < len(texts) && texts[i] == value {
+				texts[i] = d.Name
+			}
+			query = string(d.Type)
+		}
+		if values != nil {
+			current["value"] = stringValuesJSON(values, valuesList)
+		}
+		if texts != nil {
+			current["text"] = stringValuesJSON(texts, textsList)
+		}
+	}
+	options, _ := v["options"].([]interface{})
+	for _, o := range options {
+		option, ok := o.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		value, _ := option["value"].(string)
+		if mapped, ok := r.mapping[value]; ok {
+			option["value"] = mapped
+			option["text"] = mapped
+		}
+	}
+	v["query"] = query
+
+	if !r.types[query] {
+		errs.Addf("query", "no datasources of type %q on target", query)
+	}
+	return errs.Err()
+}
+
+// stringValues returns decoded string or list of strings as list. It reports whether the value is a list.
+func stringValues(v interface{}) ([]string, bool) {
+	switch v := v.(type) {
+	case string:
+		return []string{v}, false
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			values[i], _ = item.(string)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// stringValuesJSON returns values in the form returned by stringValues.
+func stringValuesJSON(values []string, isList bool) interface{} {
+	if !isList && len(values) == 1 {
+		return values[0]
+	}
+	return values
+}
+
+// targetDatasource returns target datasource of source datasource with given name.
+func (r *Remapper) targetDatasource(name string) (*grafana.Datasource, error) {
+	mapped, isMapped := r.mapping[name]
+	if !isMapped {
+		mapped = name
+	}
+	if d, ok := r.target[mapped]; ok {
+		return d, nil
+	}
+	if isMapped {
+		return nil, fmt.Errorf("datasource %q mapped from %q is missing on target", mapped, name)
+	}
+	return nil, fmt.Errorf("datasource %q is missing on target", name)
+}
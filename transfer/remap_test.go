@@ -0,0 +1,128 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transfer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spoof/go-grafana/grafana"
+	jsontools "github.com/spoof/go-grafana/pkg/json"
+	"github.com/spoof/go-grafana/transfer"
+)
+
+const stagingDashboard = `{
+	"uid": "api",
+	"title": "API",
+	"schemaVersion": 16,
+	"annotations": {"list": [{"name": "Deploys", "datasource": "Loki-staging", "expr": "{job=\"deploy\"}"}]},
+	"templating": {"list": [
+		{"type": "query", "name": "job", "datasource": "Prometheus-staging", "query": "label_values(job)"},
+		{"type": "adhoc", "name": "filters", "datasource": "Loki-staging"},
+		{"type": "datasource", "name": "ds", "query": "prometheus",
+			"current": {"text": "Prometheus-staging", "value": "Prometheus-staging"}}
+	]},
+	"panels": [
+		{"id": 1, "type": "graph", "title": "Requests", "datasource": "Prometheus-staging",
+			"targets": [{"refId": "A", "expr": "sum(rate(requests_total[5m]))"}]},
+		{"id": 2, "type": "table", "title": "Mixed", "datasource": "-- Mixed --", "targets": [
+			{"refId": "A", "datasource": "$ds", "expr": "up"},
+			{"refId": "B", "datasource": "Loki-staging", "expr": "{job=\"api\"}"}
+		]},
+		{"id": 3, "type": "grafana-piechart-panel", "title": "Default", "pieType": "donut",
+			"targets": [{"refId": "A", "expr": "up"}]},
+		{"id": 4, "type": "row", "title": "Logs", "collapsed": true, "panels": [
+			{"id": 5, "type": "logs", "title": "Errors", "datasource": "Loki-staging",
+				"targets": [{"refId": "A", "expr": "{level=\"error\"}"}]}
+		]},
+		{"id": 6, "type": "text", "title": "Notes", "content": "Owned by API team"}
+	]
+}`
+
+var (
+	stagingDatasources = []*grafana.Datasource{
+		{Name: "Prometheus-staging", UID: "prom-stg", Type: grafana.PrometheusDatasource, IsDefault: true},
+		{Name: "Loki-staging", UID: "loki-stg", Type: grafana.LokiDatasource},
+		{Name: "Elastic", UID: "elastic", Type: grafana.ElasticsearchDatasource},
+	}
+	productionDatasources = []*grafana.Datasource{
+		{Name: "Prometheus", UID: "prom", Type: grafana.PrometheusDatasource, IsDefault: true},
+		{Name: "Loki", UID: "loki", Type: grafana.LokiDatasource},
+	}
+	stagingMapping = map[string]string{"Prometheus-staging": "Prometheus", "Loki-staging": "Loki"}
+)
+
+func TestRemapper_Remap(t *testing.T) {
+	r := transfer.NewRemapper(stagingDatasources, productionDatasources, stagingMapping)
+	got, err := r.Remap([]byte(stagingDashboard))
+	if err != nil {
+		t.Fatalf("Remap returned error: %v", err)
+	}
+
+	// Only datasources are changed, panels of unregistered types and uid are kept
+	want := `{
+		"uid": "api",
+		"title": "API",
+		"schemaVersion": 16,
+		"annotations": {"list": [{"name": "Deploys", "datasource": "Loki", "expr": "{job=\"deploy\"}"}]},
+		"templating": {"list": [
+			{"type": "query", "name": "job", "datasource": "Prometheus", "query": "label_values(job)"},
+			{"type": "adhoc", "name": "filters", "datasource": "Loki"},
+			{"type": "datasource", "name": "ds", "query": "prometheus",
+				"current": {"text": "Prometheus", "value": "Prometheus"}}
+		]},
+		"panels": [
+			{"id": 1, "type": "graph", "title": "Requests", "datasource": "Prometheus",
+				"targets": [{"refId": "A", "expr": "sum(rate(requests_total[5m]))"}]},
+			{"id": 2, "type": "table", "title": "Mixed", "datasource": "-- Mixed --", "targets": [
+				{"refId": "A", "datasource": "$ds", "expr": "up"},
+				{"refId": "B", "datasource": "Loki", "expr": "{job=\"api\"}"}
+			]},
+			{"id": 3, "type": "grafana-piechart-panel", "title": "Default", "pieType": "donut",
+				"targets": [{"refId": "A", "expr": "up"}]},
+			{"id": 4, "type": "row", "title": "Logs", "collapsed": true, "panels": [
+				{"id": 5, "type": "logs", "title": "Errors", "datasource": "Loki",
+					"targets": [{"refId": "A", "expr": "{level=\"error\"}"}]}
+			]},
+			{"id": 6, "type": "text", "title": "Notes", "content": "Owned by API team"}
+		]
+	}`
+	if eq, err := jsontools.BytesEqual(got, []byte(want)); err != nil || !eq {
+		t.Errorf("Remap returned\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRemapper_Remap_MissingDatasources(t *testing.T) {
+	data := strings.Replace(stagingDashboard, `"content": "Owned by API team"}`, `"content": "Owned by API team"},
+		{"id": 7, "type": "table", "title": "Logs", "datasource": "Elastic", "targets": [{"refId": "A", "query": "*"}]}`, 1)
+
+	target := []*grafana.Datasource{{Name: "Prometheus", UID: "prom", Type: grafana.PrometheusDatasource}}
+	mapping := map[string]string{"Prometheus-staging": "Prometheus", "Loki-staging": "Loki-prod"}
+	_, err := transfer.NewRemapper(stagingDatasources, target, mapping).Remap([]byte(data))
+	if err == nil {
+		t.Fatal("Remap: expected error for missing datasources")
+	}
+
+	expected := []string{
+		`templating.list[1].datasource: datasource "Loki-prod" mapped from "Loki-staging" is missing on target`,
+		`annotations.list[0].datasource: datasource "Loki-prod" mapped from "Loki-staging" is missing on target`,
+		`panels[1].targets[1].datasource: datasource "Loki-prod" mapped from "Loki-staging" is missing on target`,
+		`panels[2].datasource: default datasource is missing on target`,
+		`panels[3].panels[0].datasource: datasource "Loki-prod" mapped from "Loki-staging" is missing on target`,
+		`panels[5].datasource: datasource "Elastic" is missing on target`,
+	}
+	if got := err.Error(); got != strings.Join(expected, "; ") {
+		t.Errorf("Remap returned error\n%s\nwant\n%s", got, strings.Join(expected, "; "))
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package transfer copies dashboards between Grafana instances, ie. from staging to production. Names of datasources
+// often differ between instances, so datasources of queries and variables are rewritten with a mapping table.
+// Dashboards are copied only if all their datasources exist on the target instance.
+package transfer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spoof/go-grafana/client"
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/pkg/validate"
+)
+
+// searchLimit is a maximum number of copied dashboards. Grafana returns 1000 dashboards by default.
+const searchLimit = 5000
+
+// Transfer copies dashboards from source Grafana to target one.
+type Transfer struct {
+	source *client.Client
+	target *client.Client
+
+	Datasources map[string]string // names of source datasources to names of target datasources
+	Folders     bool              // copy folders of dashboards, otherwise dashboards are saved into General folder
+	Overwrite   bool              // overwrite dashboards existing on the target
+}
+
+// NewTransfer creates new Transfer of dashboards between Grafana instances of given clients.
+func NewTransfer(source, target *client.Client) *Transfer {
+	return &Transfer{
+		source: source,
+		target: target,
+	}
+}
+
+// Dashboard is a dashboard copied by Transfer.
+type Dashboard struct {
+	Title  string
+	JSON   json.RawMessage             // JSON of the dashboard with remapped datasources
+	Source *grafana.DashboardMeta      // meta of the dashboard in the source Grafana
+	Target *client.DashboardSaveResult // the dashboard saved into the target Grafana
+}
+
+// Run copies dashboards found in the source Grafana with given options, all dashboards are copied if options are
+// nil. Dashboards are copied as JSON with their uids, so fields which are not supported by grafana.Dashboard are
+// copied too. Datasources of all dashboards are remapped before any of them is saved, so nothing is copied if some
+// datasource is missing on the target. Errors of remapping are returned as validate.Errors with paths prefixed by
+// titles of dashboards. It returns the copied dashboards.
+func (t *Transfer) Run(ctx context.Context, opt *client.DashboardSearchOptions) ([]*Dashboard, error) {
+	sourceDatasources, err := t.source.Datasources.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching source datasources: %s", err)
+	}
+	targetDatasources, err := t.target.Datasources.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching target datasources: %s", err)
+	}
+	remapper := NewRemapper(sourceDatasources, targetDatasources, t.Datasources)
+
+	if opt == nil {
+		opt = &client.DashboardSearchOptions{Limit: searchLimit}
+	}
+	hits, err := t.source.Dashboards.Search(ctx, opt)
+	if err != nil {
+		return nil, fmt.Errorf("searching dashboards: %s", err)
+	}
+
+	var (
+		copied []*Dashboard
+		errs   validate.Errors
+	)
+	for _, hit := range hits {
+		if hit.Type == "dash-folder" || !strings.HasPrefix(hit.URI, "db/") {
+			continue
+		}
+		data, meta, err := t.source.Dashboards.GetJSON(ctx, strings.TrimPrefix(hit.URI, "db/"))
+		if err != nil {
+			return nil, fmt.Errorf("fetching dashboard %q: %s", hit.Title, err)
+		}
+		d := &Dashboard{Title: hit.Title, Source: meta}
+		if d.JSON, err = remapper.Remap(data); err != nil {
+			errs.Add(fmt.Sprintf("%q", d.Title), err)
+			continue
+		}
+		copied = append(copied, d)
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	folderIDs, err := t.copyFolders(ctx, copied)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range copied {
+		var folderID grafana.FolderID
+		if d.Source != nil {
+			folderID = folderIDs[d.Source.FolderID]
+		}
+		if d.Target, err = t.target.Dashboards.SaveJSON(ctx, d.JSON, folderID, t.Overwrite); err != nil {
+			return nil, fmt.Errorf("saving dashboard %q: %s", d.Title, err)
+		}
+	}
+	return copied, nil
+}
+
+// copyFolders creates folders of given source dashboards missing on the target. Folders are matched by their uids.
+// It returns ids of target folders by ids of source ones.
+func (t *Transfer) copyFolders(ctx context.Context, dashboards []*Dashboard) (map[grafana.FolderID]grafana.FolderID, error) {
+	folderIDs := make(map[grafana.FolderID]grafana.FolderID)
+	if !t.Folders {
+		return folderIDs, nil
+	}
+
+	used := make(map[grafana.FolderID]bool)
+	for _, d := range dashboards {
+		if d.Source != nil && d.Source.FolderID != 0 {
+			used[d.Source.FolderID] = true
+		}
+	}
+	if len(used) == 0 {
+		return folderIDs, nil
+	}
+
+	sourceFolders, err := t.source.Folders.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching source folders: %s", err)
+	}
+	targetFolders, err := t.target.Folders.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching target folders: %s", err)
+	}
+	byUID := make(map[string]grafana.FolderID, len(targetFolders))
+	for _, f := range targetFolders {
+		byUID[f.UID] = f.ID
+	}
+
+	for _, f := range sourceFolders {
+		if !used[f.ID] {
+			continue
+		}
+		if id, ok := byUID[f.UID]; ok {
+			folderIDs[f.ID] = id
+			continue
+		}
+		copied := &grafana.Folder{UID: f.UID, Title: f.Title}
+		if err := t.target.Folders.Create(ctx, copied); err != nil {
+			return nil, fmt.Errorf("creating folder %q: %s", f.Title, err)
+		}
+		folderIDs[f.ID] = copied.ID
+	}
+	return folderIDs, nil
+}
@@ -0,0 +1,137 @@
+// Copyright 2017 Sergey Safonov
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transfer_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/spoof/go-grafana/client"
+	"github.com/spoof/go-grafana/grafana"
+	"github.com/spoof/go-grafana/transfer"
+)
+
+func newServer(t *testing.T, datasources []*grafana.Datasource, mux *http.ServeMux) (*client.Client, func()) {
+	mux.HandleFunc("/api/datasources", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewEncoder(w).Encode(datasources); err != nil {
+			t.Fatal(err)
+		}
+	})
+	server := httptest.NewServer(mux)
+	baseURL, _ := url.Parse(server.URL + "/")
+	return client.NewClient(baseURL, "token", nil), server.Close
+}
+
+func newStaging(t *testing.T) (*client.Client, func()) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": 3, "type": "dash-folder", "title": "Services", "uri": "db/services"},
+			{"id": 5, "type": "dash-db", "title": "API", "uri": "db/api", "folderId": 3}]`)
+	})
+	mux.HandleFunc("/api/dashboards/db/api", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"dashboard": %s, "meta": {"slug": "api", "folderId": 3}}`, stagingDashboard)
+	})
+	mux.HandleFunc("/api/folders", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id": 3, "uid": "services", "title": "Services"}, {"id": 4, "uid": "other", "title": "Other"}]`)
+	})
+	return newServer(t, stagingDatasources, mux)
+}
+
+func TestTransfer_Run(t *testing.T) {
+	source, closeSource := newStaging(t)
+	defer closeSource()
+
+	var (
+		folders []string
+		saved   struct {
+			Dashboard json.RawMessage  `json:"dashboard"`
+			Overwrite bool             `json:"overwrite"`
+			FolderID  grafana.FolderID `json:"folderId"`
+		}
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/folders", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `[]`)
+			return
+		}
+		var f grafana.Folder
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			t.Errorf("folders: invalid body: %v", err)
+		}
+		folders = append(folders, f.UID)
+		fmt.Fprintf(w, `{"id": 8, "uid": %q, "title": %q}`, f.UID, f.Title)
+	})
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&saved); err != nil {
+			t.Errorf("dashboards: invalid body: %v", err)
+		}
+		fmt.Fprint(w, `{"id": 9, "uid": "api", "slug": "api", "status": "success", "version": 1}`)
+	})
+	target, closeTarget := newServer(t, productionDatasources, mux)
+	defer closeTarget()
+
+	tr := transfer.NewTransfer(source, target)
+	tr.Datasources = stagingMapping
+	tr.Folders = true
+	tr.Overwrite = true
+	copied, err := tr.Run(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	if len(copied) != 1 || copied[0].Title != "API" || copied[0].Target.ID != 9 {
+		t.Errorf("Run returned unexpected dashboards %+v", copied)
+	}
+	if len(folders) != 1 || folders[0] != "services" {
+		t.Errorf("Run created folders %v, want [services]", folders)
+	}
+	if saved.FolderID != 8 || !saved.Overwrite {
+		t.Errorf("Run saved dashboard into folder %d with overwrite %t", saved.FolderID, saved.Overwrite)
+	}
+	for _, s := range []string{`"datasource":"Prometheus"`, `"datasource":"Loki"`, `"datasource":"$ds"`,
+		`"uid":"api"`, `"type":"grafana-piechart-panel"`, `"type":"logs"`} {
+		if !strings.Contains(string(saved.Dashboard), s) {
+			t.Errorf("Run saved dashboard without %s: %s", s, saved.Dashboard)
+		}
+	}
+	if strings.Contains(string(saved.Dashboard), "staging") {
+		t.Errorf("Run saved dashboard with staging datasources: %s", saved.Dashboard)
+	}
+}
+
+func TestTransfer_Run_MissingDatasource(t *testing.T) {
+	source, closeSource := newStaging(t)
+	defer closeSource()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/dashboards/db", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("dashboard is saved despite missing datasource")
+	})
+	target, closeTarget := newServer(t, productionDatasources[:1], mux)
+	defer closeTarget()
+
+	tr := transfer.NewTransfer(source, target)
+	tr.Datasources = stagingMapping
+	_, err := tr.Run(context.Background(), nil)
+	if err == nil || !strings.HasPrefix(err.Error(), `"API".templating.list[1].datasource: datasource "Loki" mapped from`) {
+		t.Errorf("Run returned error %v, want error of missing Loki datasource", err)
+	}
+}